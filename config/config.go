@@ -4,13 +4,33 @@ import (
 	"github.com/joho/godotenv"
 	"ledger-app/logger"
 	"os"
+	"time"
 )
 
 type Config struct {
 	Port                 string
+	TrustedProxies       string
 	DBUrl                string
 	DefaultAdminUserName string
 	DefaultAdminPassword string
+	Currency             string
+	ChainKey             string
+	IdempotencyKeyTTL    time.Duration
+	IdempotencyPurge     time.Duration
+	HoldTTL              time.Duration
+	HoldExpiryInterval   time.Duration
+	SchedulerInterval    time.Duration
+	SnapshotInterval     time.Duration
+	InterestInterval     time.Duration
+	ReconcileInterval    time.Duration
+	AuditRetention       time.Duration
+	AuditPruneInterval   time.Duration
+	CreditApprovalLimit  string
+	ImportApprovalLimit  string
+	ApprovalTTL          time.Duration
+	ApprovalExpiry       time.Duration
+	RiskNewAccountAge    time.Duration
+	RiskNewAccountLimit  string
 }
 
 func LoadEnvironment() *Config {
@@ -21,9 +41,28 @@ func LoadEnvironment() *Config {
 
 	return &Config{
 		Port:                 getEnv("PORT", "80"),
+		TrustedProxies:       getEnv("TRUSTED_PROXIES", ""),
 		DBUrl:                getEnv("DB_URL", "root:12345@tcp(db:3306)/ledger_app"),
 		DefaultAdminUserName: getEnv("DEFAULT_ADMIN_USERNAME", "admin"),
 		DefaultAdminPassword: getEnv("DEFAULT_ADMIN_PASSWORD", "admin123"),
+		Currency:             getEnv("LEDGER_CURRENCY", "USD"),
+		ChainKey:             getEnv("LEDGER_CHAIN_KEY", ""),
+		IdempotencyKeyTTL:    getDuration("IDEMPOTENCY_KEY_TTL", 24*time.Hour),
+		IdempotencyPurge:     getDuration("IDEMPOTENCY_PURGE_INTERVAL", time.Hour),
+		HoldTTL:              getDuration("HOLD_TTL", 7*24*time.Hour),
+		HoldExpiryInterval:   getDuration("HOLD_EXPIRY_INTERVAL", time.Minute),
+		SchedulerInterval:    getDuration("SCHEDULER_INTERVAL", time.Minute),
+		SnapshotInterval:     getDuration("SNAPSHOT_INTERVAL", time.Hour),
+		InterestInterval:     getDuration("INTEREST_INTERVAL", time.Hour),
+		ReconcileInterval:    getDuration("RECONCILE_INTERVAL", 0),
+		AuditRetention:       getDuration("AUDIT_RETENTION", 365*24*time.Hour),
+		AuditPruneInterval:   getDuration("AUDIT_PRUNE_INTERVAL", 24*time.Hour),
+		CreditApprovalLimit:  getEnv("CREDIT_APPROVAL_THRESHOLD", ""),
+		ImportApprovalLimit:  getEnv("IMPORT_APPROVAL_THRESHOLD", ""),
+		ApprovalTTL:          getDuration("APPROVAL_TTL", 72*time.Hour),
+		ApprovalExpiry:       getDuration("APPROVAL_EXPIRY_INTERVAL", time.Minute),
+		RiskNewAccountAge:    getDuration("RISK_NEW_ACCOUNT_AGE", 24*time.Hour),
+		RiskNewAccountLimit:  getEnv("RISK_NEW_ACCOUNT_LIMIT", "100"),
 	}
 }
 
@@ -35,3 +74,18 @@ func getEnv(key, defaultValue string) string {
 
 	return value
 }
+
+func getDuration(key string, defaultValue time.Duration) time.Duration {
+	value, exists := os.LookupEnv(key)
+	if !exists {
+		return defaultValue
+	}
+
+	duration, err := time.ParseDuration(value)
+	if err != nil {
+		logger.Logger.Errorf("Invalid duration %q for %s, using %s", value, key, defaultValue)
+		return defaultValue
+	}
+
+	return duration
+}
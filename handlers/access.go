@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+)
+
+// canAccessUser reports whether the caller is an admin or the user itself,
+// the same rule GetUserBalance applies.
+func canAccessUser(c echo.Context, userID int) bool {
+	tokenUserID, ok := c.Get("userID").(float64)
+	if !ok {
+		return false
+	}
+
+	role, _ := c.Get("role").(string)
+	return role == "admin" || uint(tokenUserID) == uint(userID)
+}
+
+// refuseSender writes the refusal and returns true when the user's
+// status does not let money leave their account.
+func refuseSender(c echo.Context, user *models.User) bool {
+	if user.CanSend() {
+		return false
+	}
+
+	return refuseStatus(c, user, "send")
+}
+
+// refuseReceiver writes the refusal and returns true when the user's
+// status does not let money into their account.
+func refuseReceiver(c echo.Context, user *models.User) bool {
+	if user.CanReceive() {
+		return false
+	}
+
+	return refuseStatus(c, user, "receive")
+}
+
+func refuseStatus(c echo.Context, user *models.User, direction string) bool {
+	logger.Logger.Warnf("User ID %d is %s and cannot %s money", user.ID, user.Status, direction)
+	_ = c.JSON(http.StatusConflict, map[string]string{
+		"error":  fmt.Sprintf("User %d is %s and cannot %s money", user.ID, user.Status, direction),
+		"status": user.Status,
+	})
+	return true
+}
@@ -1,12 +1,22 @@
 package handlers
 
 import (
+	"errors"
 	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/internal/approvals"
+	"ledger-app/internal/audit"
 	"ledger-app/internal/connections/database"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
+	"ledger-app/internal/validation"
 	"ledger-app/logger"
 	"ledger-app/models"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type RoleUpdatePayload struct {
@@ -55,16 +65,342 @@ func UpdateUserRole(c echo.Context) error {
 		return c.JSON(http.StatusForbidden, map[string]string{"error": "Cannot change your own role"})
 	}
 
+	if approvals.PromotionNeedsApproval(&targetUser, payload.Role) {
+		request, err := approvals.SubmitRoleChange(database.Db, targetUser.ID, payload.Role, uint(adminUserId.(float64)), time.Now().UTC())
+		if err != nil {
+			if errors.Is(err, approvals.ErrAlreadyPending) {
+				return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+			}
+			logger.Logger.Error("Failed to submit role change for approval: ", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to submit role change for approval"})
+		}
+
+		return pendingApproval(c, request)
+	}
+
+	previousRole := targetUser.Role()
+
 	if payload.Role == "admin" {
 		targetUser.IsAdmin = true
 	} else {
 		targetUser.IsAdmin = false
 	}
 
-	if err := database.Db.Save(&targetUser).Error; err != nil {
+	tx := database.Db.Begin()
+
+	if err := tx.Save(&targetUser).Error; err != nil {
+		tx.Rollback()
 		logger.Logger.Error("Error updating user role: ", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error updating user role"})
 	}
 
+	if err := recordAudit(c, tx, http.StatusOK, &audit.Change{
+		Action:     "role_change",
+		TargetType: "user",
+		TargetID:   targetUser.ID,
+		Before:     map[string]string{"role": previousRole},
+		After:      map[string]string{"role": targetUser.Role()},
+	}); err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to record audit event: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record audit event"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
 	return c.JSON(http.StatusOK, map[string]string{"message": "Role updated successfully"})
 }
+
+func SetOverdraftLimit(c echo.Context) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	limitReq := new(models.OverdraftLimitRequest)
+	if err := c.Bind(limitReq); err != nil {
+		logger.Logger.Error("Invalid input: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+
+	if err := validation.ValidateStruct().Struct(limitReq); err != nil {
+		return validationFailed(c, err)
+	}
+
+	limit, err := limitReq.Limit.MinorUnits(money.Default)
+	if err != nil {
+		logger.Logger.Error("Invalid limit: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	var user models.User
+	if err := database.Db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Logger.Error("User not found with ID: ", strconv.Itoa(userID))
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+		}
+
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	if approvals.OverdraftNeedsApproval(limit) {
+		request, err := approvals.SubmitOverdraftLimit(database.Db, user.ID, limit, limitReq.Reason, adminUserID, time.Now().UTC())
+		if err != nil {
+			logger.Logger.Error("Failed to submit overdraft limit for approval: ", err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to submit overdraft limit for approval"})
+		}
+
+		return pendingApproval(c, request)
+	}
+
+	tx := database.Db.Begin()
+
+	change, err := ledger.SetOverdraftLimit(tx, user.ID, limit, adminUserID, limitReq.Reason)
+	if err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to set overdraft limit: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to set overdraft limit"})
+	}
+
+	if err := recordAudit(c, tx, http.StatusOK, &audit.Change{
+		Action:     "overdraft_limit_change",
+		TargetType: "user",
+		TargetID:   user.ID,
+		Before:     map[string]string{"limit": money.Format(change.OldLimit, money.Default)},
+		After:      map[string]string{"limit": money.Format(change.NewLimit, money.Default), "reason": change.Reason},
+	}); err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to record audit event: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record audit event"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"adminID":  adminUserID,
+		"userID":   user.ID,
+		"oldLimit": change.OldLimit,
+		"newLimit": change.NewLimit,
+		"reason":   change.Reason,
+	}).Info("Overdraft limit changed")
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":   "Overdraft limit updated successfully",
+		"user_id":   user.ID,
+		"old_limit": money.Format(change.OldLimit, money.Default),
+		"new_limit": money.Format(change.NewLimit, money.Default),
+	})
+}
+
+func GetOverdraftLimitHistory(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	var changes []models.OverdraftLimitChange
+	if err := database.Db.Where("user_id = ?", userID).Order("id DESC").Find(&changes).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, changes)
+}
+
+func SetUserStatus(c echo.Context) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	statusReq := new(models.UserStatusRequest)
+	if err := c.Bind(statusReq); err != nil {
+		logger.Logger.Error("Invalid input: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+
+	if err := validation.ValidateStruct().Struct(statusReq); err != nil {
+		return validationFailed(c, err)
+	}
+
+	if uint(userID) == adminUserID {
+		logger.Logger.Warn("Admin attempting to change own status")
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Cannot change your own status"})
+	}
+
+	var user models.User
+	if err := database.Db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Logger.Error("User not found with ID: ", strconv.Itoa(userID))
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+		}
+
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	switch {
+	case user.Status == models.UserStatusClosed:
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Closed accounts cannot be reopened"})
+	case user.Status == statusReq.Status:
+		return c.JSON(http.StatusConflict, map[string]string{"error": "User already has status " + user.Status})
+	case statusReq.SweepToUserID != 0 && statusReq.Status != models.UserStatusClosed:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "SweepToUserID only applies when closing an account"})
+	case statusReq.SweepToUserID == user.ID:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Cannot sweep an account into itself"})
+	}
+
+	var sweepUser models.User
+	if statusReq.SweepToUserID != 0 {
+		if err := database.Db.First(&sweepUser, statusReq.SweepToUserID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return c.JSON(http.StatusNotFound, map[string]string{"error": "Sweep user not found"})
+			}
+
+			logger.Logger.Error("Database error: ", err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		}
+
+		if refuseReceiver(c, &sweepUser) {
+			return nil
+		}
+	}
+
+	tx := database.Db.Begin()
+
+	change := models.UserStatusChange{
+		UserID:    user.ID,
+		OldStatus: user.Status,
+		NewStatus: statusReq.Status,
+		Reason:    statusReq.Reason,
+		ChangedBy: adminUserID,
+	}
+
+	// Lock the user so two admins cannot close the same account twice.
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, user.ID).Error; err != nil || user.Status != change.OldStatus {
+		tx.Rollback()
+		return c.JSON(http.StatusConflict, map[string]string{"error": "User status changed concurrently"})
+	}
+
+	if statusReq.Status == models.UserStatusClosed {
+		var sweepTo *models.Account
+		if sweepUser.ID != 0 {
+			// The sweep user may have been frozen or closed since it was read.
+			if err = ledger.CheckStatus(tx, 0, sweepUser.ID); err == nil {
+				sweepTo, err = ledger.UserAccount(tx, sweepUser.ID)
+			}
+		} else {
+			sweepTo, err = ledger.SystemAccount(tx, models.SystemAccountClosed)
+		}
+		if errors.Is(err, models.ErrUserInactive) {
+			tx.Rollback()
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+		if err != nil {
+			tx.Rollback()
+			logger.Logger.Error("Failed to load sweep account: ", err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to load sweep account"})
+		}
+
+		entry, err := ledger.CloseAccount(tx, user.ID, sweepTo, time.Now().UTC())
+		if err != nil {
+			tx.Rollback()
+			switch {
+			case errors.Is(err, ledger.ErrActiveHolds):
+				return c.JSON(http.StatusConflict, map[string]string{"error": "Release or capture the account's active holds before closing it"})
+			case errors.Is(err, ledger.ErrNegativeSweep), errors.Is(err, ledger.ErrAccountClosed):
+				return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+			}
+
+			logger.Logger.Error("Failed to close account: ", err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to close account"})
+		}
+		if entry != nil {
+			change.SweepEntryID = &entry.ID
+		}
+
+		if err := tx.Model(&models.ScheduledTransfer{}).
+			Where("(sender_id = ? OR receiver_id = ?) AND status IN ?", user.ID, user.ID,
+				[]string{models.ScheduleStatusActive, models.ScheduleStatusPaused}).
+			Update("status", models.ScheduleStatusCancelled).Error; err != nil {
+			tx.Rollback()
+			logger.Logger.Error("Failed to cancel schedules: ", err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to cancel schedules"})
+		}
+	}
+
+	if err := tx.Model(&user).Update("status", statusReq.Status).Error; err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to update user status: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update user status"})
+	}
+
+	if err := tx.Create(&change).Error; err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to record status change: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record status change"})
+	}
+
+	if err := recordAudit(c, tx, http.StatusOK, &audit.Change{
+		Action:     "status_change",
+		TargetType: "user",
+		TargetID:   user.ID,
+		Before:     map[string]string{"status": change.OldStatus},
+		After:      map[string]interface{}{"status": change.NewStatus, "reason": change.Reason, "sweep_entry_id": change.SweepEntryID},
+	}); err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to record audit event: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record audit event"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"adminID":   adminUserID,
+		"userID":    user.ID,
+		"oldStatus": change.OldStatus,
+		"newStatus": change.NewStatus,
+		"reason":    change.Reason,
+	}).Info("User status changed")
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":        "User status updated successfully",
+		"user_id":        user.ID,
+		"old_status":     change.OldStatus,
+		"new_status":     change.NewStatus,
+		"sweep_entry_id": change.SweepEntryID,
+	})
+}
+
+func GetUserStatusHistory(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	var changes []models.UserStatusChange
+	if err := database.Db.Where("user_id = ?", userID).Order("id DESC").Find(&changes).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, changes)
+}
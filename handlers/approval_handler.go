@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"ledger-app/internal/approvals"
+	"ledger-app/internal/audit"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/importer"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
+	"ledger-app/internal/validation"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func GetApprovalRequests(c echo.Context) error {
+	status := c.QueryParam("status")
+	if status == "" {
+		status = models.ApprovalStatusPending
+	}
+
+	var requests []models.ApprovalRequest
+	if err := database.Db.Where("status = ?", status).Order("id DESC").Limit(200).Find(&requests).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	response := make([]map[string]interface{}, 0, len(requests))
+	for i := range requests {
+		response = append(response, approvalResponse(&requests[i]))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func ApproveRequest(c echo.Context) error {
+	return decideApproval(c, models.ApprovalStatusApproved)
+}
+
+func RejectRequest(c echo.Context) error {
+	return decideApproval(c, models.ApprovalStatusRejected)
+}
+
+func decideApproval(c echo.Context, status string) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
+	requestID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert approval request ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid approval request ID format"})
+	}
+
+	decisionReq := new(models.ApprovalDecisionRequest)
+	if err := c.Bind(decisionReq); err != nil {
+		logger.Logger.Error("Invalid input: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+
+	if err := validation.ValidateStruct().Struct(decisionReq); err != nil {
+		return validationFailed(c, err)
+	}
+
+	if status == models.ApprovalStatusRejected && decisionReq.Reason == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "A reason is required to reject a request"})
+	}
+
+	now := time.Now().UTC()
+	tx := database.Db.Begin()
+
+	var request *models.ApprovalRequest
+	if status == models.ApprovalStatusApproved {
+		request, err = approvals.Approve(tx, uint(requestID), adminUserID, decisionReq.Reason, now)
+	} else {
+		request, err = approvals.Reject(tx, uint(requestID), adminUserID, decisionReq.Reason, now)
+	}
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, approvals.ErrNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Approval request not found"})
+		case errors.Is(err, approvals.ErrSelfApproval):
+			return c.JSON(http.StatusForbidden, map[string]string{"error": err.Error()})
+		case errors.Is(err, approvals.ErrNotPending), errors.Is(err, approvals.ErrExpired),
+			errors.Is(err, models.ErrUserInactive), errors.Is(err, ledger.ErrAccountClosed),
+			errors.Is(err, importer.ErrDuplicateReference), errors.Is(err, importer.ErrFutureDate),
+			errors.Is(err, approvals.ErrImportFailed):
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+
+		logger.Logger.Error("Failed to decide approval request: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to decide approval request"})
+	}
+
+	if err := recordAudit(c, tx, http.StatusOK, &audit.Change{
+		Action:     "approval_" + status,
+		TargetType: "approval_request",
+		TargetID:   request.ID,
+		Before:     map[string]string{"status": models.ApprovalStatusPending},
+		After:      approvalResponse(request),
+	}); err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to record audit event: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record audit event"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"adminID":     adminUserID,
+		"requestID":   request.ID,
+		"operation":   request.Operation,
+		"requestedBy": request.RequestedBy,
+		"status":      request.Status,
+	}).Info("Approval request decided")
+
+	return c.JSON(http.StatusOK, approvalResponse(request))
+}
+
+// pendingApproval answers an operation that was held back for approval.
+func pendingApproval(c echo.Context, request *models.ApprovalRequest) error {
+	change := &audit.Change{
+		Action:     request.Operation + "_requested",
+		TargetType: "user",
+		TargetID:   request.TargetUserID,
+		After:      approvalResponse(request),
+	}
+	// Imports may touch many users and are tracked by the request itself.
+	if request.TargetUserID == 0 {
+		change.TargetType = "approval_request"
+		change.TargetID = request.ID
+	}
+	setAuditChange(c, change)
+
+	logger.Logger.WithFields(logrus.Fields{
+		"adminID":   request.RequestedBy,
+		"requestID": request.ID,
+		"operation": request.Operation,
+		"userID":    request.TargetUserID,
+	}).Info("Operation pending approval")
+
+	response := approvalResponse(request)
+	response["message"] = "Operation requires approval by another admin"
+	return c.JSON(http.StatusAccepted, response)
+}
+
+func approvalResponse(request *models.ApprovalRequest) map[string]interface{} {
+	response := map[string]interface{}{
+		"id":              request.ID,
+		"operation":       request.Operation,
+		"target_user_id":  request.TargetUserID,
+		"status":          request.Status,
+		"requested_by":    request.RequestedBy,
+		"decided_by":      request.DecidedBy,
+		"decision_reason": request.DecisionReason,
+		"expires_at":      request.ExpiresAt,
+		"decided_at":      request.DecidedAt,
+		"created_at":      request.CreatedAt,
+	}
+
+	switch request.Operation {
+	case models.ApprovalOperationCredit:
+		response["amount"] = money.Format(request.Amount, money.Default)
+		response["metadata"] = json.RawMessage(request.Payload)
+		response["journal_entry_id"] = request.ResultEntryID
+	case models.ApprovalOperationImport:
+		row := approvals.ImportRowPayload(request)
+		response["amount"] = money.Format(request.Amount, money.Default)
+		response["row"] = map[string]interface{}{
+			"line":        row.Line,
+			"time":        row.Time,
+			"reference":   row.Reference,
+			"description": row.Description,
+			"category":    row.Category,
+		}
+		response["journal_entry_id"] = request.ResultEntryID
+	case models.ApprovalOperationImportFile:
+		rows := approvals.ImportFileRows(request)
+		response["volume"] = money.Format(request.Amount, money.Default)
+		response["rows"] = len(rows)
+	case models.ApprovalOperationJournal:
+		response["volume"] = money.Format(request.Amount, money.Default)
+		response["file"] = approvals.JournalFilename(request)
+	case models.ApprovalOperationOverdraft:
+		response["limit"] = money.Format(request.Amount, money.Default)
+		response["reason"] = approvals.OverdraftReason(request)
+	case models.ApprovalOperationRoleChange:
+		response["role"] = approvals.RolePayload(request)
+	}
+
+	return response
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"ledger-app/internal/audit"
+	"ledger-app/internal/connections/database"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const maxAuditEvents = 500
+
+// setAuditChange describes the operation to the audit middleware, for
+// operations that do not run in a transaction of their own.
+func setAuditChange(c echo.Context, change *audit.Change) {
+	c.Set("auditChange", change)
+}
+
+// recordAudit records the operation inside its transaction, so it cannot
+// commit without its audit event. status is the one the handler answers
+// with once tx commits.
+func recordAudit(c echo.Context, tx *gorm.DB, status int, change *audit.Change) error {
+	event := audit.NewEvent(c, status)
+	if err := audit.Apply(&event, change); err != nil {
+		return err
+	}
+
+	if err := audit.Record(tx, &event); err != nil {
+		return err
+	}
+
+	c.Set("auditEvent", &event)
+	return nil
+}
+
+// recordLogin audits a login attempt. user is nil when the username does
+// not exist.
+func recordLogin(c echo.Context, user *models.User, username string, status int) {
+	event := audit.NewEvent(c, status)
+	event.Action = models.AuditActionLogin
+	if status != http.StatusOK {
+		event.Action = models.AuditActionLoginFailed
+	}
+
+	change := &audit.Change{After: map[string]string{"username": username}}
+	if user != nil {
+		change.TargetType = "user"
+		change.TargetID = user.ID
+		if status == http.StatusOK {
+			event.ActorID = &user.ID
+			change.After = map[string]string{"username": username, "role": user.Role()}
+		}
+	}
+
+	if err := audit.Apply(&event, change); err != nil {
+		logger.Logger.Error("Failed to encode audit change: ", err.Error())
+	}
+
+	if err := audit.Record(database.Db, &event); err != nil {
+		logger.Logger.Error("Failed to record login audit event: ", err.Error())
+	}
+}
+
+func GetAuditEvents(c echo.Context) error {
+	query := database.Db.Model(&models.AuditEvent{})
+
+	for param, condition := range map[string]string{
+		"actor_id":  "actor_id = ?",
+		"target_id": "target_id = ?",
+		"before_id": "id < ?",
+	} {
+		value := c.QueryParam(param)
+		if value == "" {
+			continue
+		}
+
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Invalid %s", param)})
+		}
+
+		query = query.Where(condition, id)
+	}
+
+	for _, param := range []string{"action", "target_type", "request_id", "source_ip"} {
+		if value := c.QueryParam(param); value != "" {
+			query = query.Where(param+" = ?", value)
+		}
+	}
+
+	for param, operator := range map[string]string{"from": ">=", "to": "<"} {
+		value := c.QueryParam(param)
+		if value == "" {
+			continue
+		}
+
+		at, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Invalid %s, expected RFC 3339", param)})
+		}
+
+		query = query.Where("created_at "+operator+" ?", at.UTC())
+	}
+
+	limit := 100
+	if value := c.QueryParam("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > maxAuditEvents {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("limit must be between 1 and %d", maxAuditEvents)})
+		}
+		limit = parsed
+	}
+
+	var events []models.AuditEvent
+	if err := query.Order("id DESC").Limit(limit).Find(&events).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	response := make([]map[string]interface{}, 0, len(events))
+	for _, event := range events {
+		response = append(response, map[string]interface{}{
+			"id":          event.ID,
+			"seq":         event.Seq,
+			"actor_id":    event.ActorID,
+			"action":      event.Action,
+			"target_type": event.TargetType,
+			"target_id":   event.TargetID,
+			"before":      rawJSON(event.Before),
+			"after":       rawJSON(event.After),
+			"status":      event.Status,
+			"request_id":  event.RequestID,
+			"source_ip":   event.SourceIP,
+			"created_at":  event.CreatedAt,
+			"hash":        event.Hash,
+		})
+	}
+
+	result := map[string]interface{}{"events": response}
+	if len(events) == limit {
+		result["next_before_id"] = events[len(events)-1].ID
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
+
+func VerifyAuditLog(c echo.Context) error {
+	chainBreak, checked, err := audit.Verify(database.Db)
+	if err != nil {
+		logger.Logger.Error("Failed to verify audit log: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to verify audit log"})
+	}
+
+	if chainBreak != nil {
+		logger.Logger.Errorf("Audit log broken at event %d: %s", chainBreak.EventID, chainBreak.Reason)
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"valid":       false,
+			"checked":     checked,
+			"first_break": chainBreak,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"valid":   true,
+		"checked": checked,
+	})
+}
+
+func rawJSON(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+
+	return json.RawMessage(value)
+}
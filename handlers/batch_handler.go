@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/money"
+	"ledger-app/internal/risk"
+	"ledger-app/internal/transfers"
+	"ledger-app/internal/validation"
+	"ledger-app/internal/velocity"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"time"
+)
+
+func BatchTransfer(c echo.Context) error {
+	tokenUserID, ok := c.Get("userID").(float64)
+	if !ok {
+		logger.Logger.Error("Failed to retrieve user ID from token")
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+	}
+
+	batchReq := new(models.BatchTransferRequest)
+	if err := c.Bind(batchReq); err != nil {
+		logger.Logger.Error("Invalid input: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+
+	if err := validation.ValidateStruct().Struct(batchReq); err != nil {
+		return validationFailed(c, err)
+	}
+
+	userIDs := make([]uint, 0, 2*len(batchReq.Legs))
+	for _, leg := range batchReq.Legs {
+		userIDs = append(userIDs, leg.SenderID, leg.ReceiverID)
+	}
+
+	var found []models.User
+	if err := database.Db.Where("id IN ?", userIDs).Find(&found).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	users := make(map[uint]*models.User, len(found))
+	for i := range found {
+		users[found[i].ID] = &found[i]
+	}
+
+	legs := make([]transfers.Leg, len(batchReq.Legs))
+	results := make([]transfers.LegResult, len(batchReq.Legs))
+	rejected := false
+
+	for i, legReq := range batchReq.Legs {
+		legs[i] = transfers.Leg{SenderID: legReq.SenderID, ReceiverID: legReq.ReceiverID, Meta: legReq.EntryMetadata}
+
+		if !canAccessUser(c, int(legReq.SenderID)) {
+			logger.Logger.Warnf("User ID %v attempted a batch transfer from User ID %d", tokenUserID, legReq.SenderID)
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
+		}
+
+		var err error
+		switch {
+		case users[legReq.SenderID] == nil:
+			err = errors.New("sender not found")
+		case users[legReq.ReceiverID] == nil:
+			err = errors.New("receiver not found")
+		default:
+			legs[i].Amount, err = legReq.MinorUnits()
+		}
+
+		if err != nil {
+			results[i].Err = err
+			rejected = true
+		}
+	}
+
+	if rejected {
+		return c.JSON(http.StatusUnprocessableEntity, batchResponse("Batch transfer rejected", legs, results))
+	}
+
+	tx := database.Db.Begin()
+
+	results, err := transfers.Batch(tx, legs, time.Now().UTC())
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, transfers.ErrBatchRejected) {
+			recordBatchFailures(legs, results)
+			return c.JSON(http.StatusUnprocessableEntity, batchResponse("Batch transfer rejected", legs, results))
+		}
+
+		logger.Logger.Error("Failed to post batch transfer: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to post batch transfer"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"userID": uint(tokenUserID),
+		"legs":   len(legs),
+	}).Info("Batch transfer posted")
+
+	return c.JSON(http.StatusOK, batchResponse("Batch transfer posted successfully", legs, results))
+}
+
+// recordBatchFailures feeds the failed attempts risk rule with the legs
+// of a rejected batch that were denied or over a limit.
+func recordBatchFailures(legs []transfers.Leg, results []transfers.LegResult) {
+	for i, result := range results {
+		var riskErr *transfers.RiskError
+		var limitErr *velocity.LimitError
+		switch {
+		case errors.As(result.Err, &riskErr) && riskErr.Assessment.Decision == risk.Deny:
+			recordFailedAttempt(legs[i].SenderID, models.EntryTypeTransfer, "risk_denied")
+		case errors.As(result.Err, &limitErr):
+			recordFailedAttempt(legs[i].SenderID, models.EntryTypeTransfer, limitErr.Code)
+		}
+	}
+}
+
+func batchResponse(message string, legs []transfers.Leg, results []transfers.LegResult) map[string]interface{} {
+	legResponses := make([]map[string]interface{}, 0, len(legs))
+	for i, leg := range legs {
+		response := map[string]interface{}{
+			"index":       i,
+			"sender_id":   leg.SenderID,
+			"receiver_id": leg.ReceiverID,
+			"amount":      money.Format(leg.Amount, money.Default),
+			"fee":         money.Format(leg.Fee, money.Default),
+			"status":      "not_posted",
+		}
+
+		result := results[i]
+		switch {
+		case result.Err != nil:
+			response["status"] = "failed"
+			response["error"] = result.Err.Error()
+		case result.Entry != nil:
+			response["status"] = "posted"
+			response["journal_entry_id"] = result.Entry.ID
+			if result.FeeEntry != nil {
+				response["fee_entry_id"] = result.FeeEntry.ID
+			}
+		}
+
+		legResponses = append(legResponses, response)
+	}
+
+	return map[string]interface{}{
+		"message":  message,
+		"currency": money.Default.Code,
+		"legs":     legResponses,
+	}
+}
@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"github.com/labstack/echo/v4"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/ledger"
+	"ledger-app/logger"
+	"net/http"
+)
+
+func VerifyTransactionChain(c echo.Context) error {
+	chainBreak, checked, err := ledger.VerifyChain(database.Db)
+	if err != nil {
+		logger.Logger.Error("Failed to verify transaction chain: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to verify transaction chain"})
+	}
+
+	if chainBreak != nil {
+		logger.Logger.Errorf("Transaction chain broken in %s", chainBreak)
+		return c.JSON(http.StatusOK, map[string]interface{}{
+			"valid":       false,
+			"checked":     checked,
+			"first_break": chainBreak,
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"valid":   true,
+		"checked": checked,
+	})
+}
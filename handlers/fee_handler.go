@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/fees"
+	"ledger-app/internal/money"
+	"ledger-app/internal/validation"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+)
+
+// chargedResponse breaks a debit down into the amount moved and the fee
+// charged on top of it.
+func chargedResponse(message string, entry, feeEntry *models.JournalEntry, amount, fee int64) map[string]interface{} {
+	response := map[string]interface{}{
+		"message":          message,
+		"journal_entry_id": entry.ID,
+		"amount":           money.Format(amount, money.Default),
+		"fee":              money.Format(fee, money.Default),
+		"total_debited":    money.Format(amount+fee, money.Default),
+		"currency":         money.Default.Code,
+	}
+	if feeEntry != nil {
+		response["fee_entry_id"] = feeEntry.ID
+	}
+
+	return response
+}
+
+func SetFeeSchedule(c echo.Context) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
+	operation, role, ok := feeScheduleParams(c)
+	if !ok {
+		return nil
+	}
+
+	scheduleReq := new(models.FeeScheduleRequest)
+	if err := c.Bind(scheduleReq); err != nil {
+		logger.Logger.Error("Invalid input: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+
+	if err := validation.ValidateStruct().Struct(scheduleReq); err != nil {
+		return validationFailed(c, err)
+	}
+
+	schedule := &models.FeeSchedule{Operation: operation, Role: role, UpdatedBy: adminUserID}
+
+	var err error
+	if schedule.Min, err = optionalMinorUnits(scheduleReq.Min); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Min: " + err.Error()})
+	}
+	if schedule.Max, err = optionalMinorUnits(scheduleReq.Max); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Max: " + err.Error()})
+	}
+	if schedule.Max > 0 && schedule.Max < schedule.Min {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Max must not be below Min"})
+	}
+
+	for _, tierReq := range scheduleReq.Tiers {
+		var tier models.FeeTier
+		if tier.AmountFrom, err = optionalMinorUnits(tierReq.From); err == nil {
+			if tier.Flat, err = optionalMinorUnits(tierReq.Flat); err == nil && tierReq.Rate != "" {
+				tier.RatePPM, err = money.ParsePercent(tierReq.Rate)
+			}
+		}
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Tiers: " + err.Error()})
+		}
+
+		schedule.Tiers = append(schedule.Tiers, tier)
+	}
+
+	tx := database.Db.Begin()
+
+	if err := fees.SetSchedule(tx, schedule); err != nil {
+		tx.Rollback()
+		if errors.Is(err, fees.ErrDuplicateTier) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		logger.Logger.Error("Failed to set fee schedule: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to set fee schedule"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"adminID":   adminUserID,
+		"operation": operation,
+		"role":      role,
+		"tiers":     len(schedule.Tiers),
+	}).Info("Fee schedule set")
+
+	return c.JSON(http.StatusOK, feeScheduleResponse(schedule))
+}
+
+func GetFeeSchedules(c echo.Context) error {
+	var schedules []models.FeeSchedule
+	if err := database.Db.Preload("Tiers", func(db *gorm.DB) *gorm.DB {
+		return db.Order("fee_tiers.amount_from")
+	}).Order("operation").Order("role").Find(&schedules).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	response := make([]map[string]interface{}, 0, len(schedules))
+	for i := range schedules {
+		response = append(response, feeScheduleResponse(&schedules[i]))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func DeleteFeeSchedule(c echo.Context) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
+	operation, role, ok := feeScheduleParams(c)
+	if !ok {
+		return nil
+	}
+
+	if err := fees.DeleteSchedule(database.Db, operation, role); err != nil {
+		logger.Logger.Error("Failed to delete fee schedule: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete fee schedule"})
+	}
+
+	logger.Logger.Infof("Admin ID %d removed the %s fee schedule for role %s", adminUserID, operation, role)
+	return c.JSON(http.StatusOK, map[string]string{"message": "Fee schedule deleted successfully"})
+}
+
+// feeScheduleParams reads the operation and role from the path. When they
+// are invalid it writes the error response and returns false.
+func feeScheduleParams(c echo.Context) (string, string, bool) {
+	operation, role := c.Param("operation"), c.Param("role")
+
+	if operation != models.FeeOperationTransfer && operation != models.FeeOperationWithdrawal {
+		_ = c.JSON(http.StatusBadRequest, map[string]string{"error": "Operation must be transfer or withdrawal"})
+		return "", "", false
+	}
+
+	if role != "user" && role != "admin" && role != models.FeeRoleAny {
+		_ = c.JSON(http.StatusBadRequest, map[string]string{"error": "Role must be user, admin or any"})
+		return "", "", false
+	}
+
+	return operation, role, true
+}
+
+func optionalMinorUnits(value money.Decimal) (int64, error) {
+	if value == "" {
+		return 0, nil
+	}
+
+	return value.MinorUnits(money.Default)
+}
+
+func feeScheduleResponse(schedule *models.FeeSchedule) map[string]interface{} {
+	tiers := make([]map[string]interface{}, 0, len(schedule.Tiers))
+	for _, tier := range schedule.Tiers {
+		tiers = append(tiers, map[string]interface{}{
+			"from": money.Format(tier.AmountFrom, money.Default),
+			"flat": money.Format(tier.Flat, money.Default),
+			"rate": money.FormatPercent(tier.RatePPM),
+		})
+	}
+
+	return map[string]interface{}{
+		"operation":  schedule.Operation,
+		"role":       schedule.Role,
+		"min":        money.Format(schedule.Min, money.Default),
+		"max":        money.Format(schedule.Max, money.Default),
+		"tiers":      tiers,
+		"updated_by": schedule.UpdatedBy,
+		"updated_at": schedule.UpdatedAt,
+	}
+}
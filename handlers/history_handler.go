@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/history"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func GetUserTransactions(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	if !canAccessUser(c, userID) {
+		logger.Logger.Warnf("User ID %v attempted to access transactions of User ID %d", c.Get("userID"), userID)
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
+	}
+
+	filter, err := parseHistoryFilter(c)
+	if err != nil {
+		logger.Logger.Error("Invalid filter: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	var user models.User
+	if err := database.Db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Logger.Error("User not found with ID: ", strconv.Itoa(userID))
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+		}
+
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	account, err := ledger.UserAccount(database.Db, user.ID)
+	if err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	rows, next, err := history.List(database.Db, account.ID, filter)
+	if err != nil {
+		if errors.Is(err, history.ErrInvalidCursor) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid cursor"})
+		}
+
+		logger.Logger.Error("Failed to fetch transactions: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch transactions"})
+	}
+
+	transactions := make([]map[string]interface{}, 0, len(rows))
+	for i := range rows {
+		transactions = append(transactions, historyRowResponse(&rows[i]))
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"user_id":      user.ID,
+		"currency":     money.Default.Code,
+		"transactions": transactions,
+		"next_cursor":  next,
+	})
+}
+
+func parseHistoryFilter(c echo.Context) (history.Filter, error) {
+	filter := history.Filter{
+		Direction: c.QueryParam("direction"),
+		Type:      c.QueryParam("type"),
+		Category:  c.QueryParam("category"),
+		Tag:       c.QueryParam("tag"),
+		Reference: c.QueryParam("reference"),
+		Cursor:    c.QueryParam("cursor"),
+	}
+
+	if filter.Direction != "" && filter.Direction != history.DirectionIn && filter.Direction != history.DirectionOut {
+		return filter, errors.New("direction must be in or out")
+	}
+
+	for param, target := range map[string]**time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := c.QueryParam(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, fmt.Errorf("%s must be an RFC3339 time", param)
+			}
+			*target = &parsed
+		}
+	}
+
+	for param, target := range map[string]**int64{"min_amount": &filter.MinAmount, "max_amount": &filter.MaxAmount} {
+		if value := c.QueryParam(param); value != "" {
+			amount, err := money.Parse(value, money.Default)
+			if err != nil {
+				return filter, fmt.Errorf("%s: %w", param, err)
+			}
+			*target = &amount
+		}
+	}
+
+	if value := c.QueryParam("counterparty"); value != "" {
+		counterpartyID, err := strconv.Atoi(value)
+		if err != nil {
+			return filter, errors.New("counterparty must be a user ID")
+		}
+		id := uint(counterpartyID)
+		filter.CounterpartyID = &id
+	}
+
+	if value := c.QueryParam("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > history.MaxLimit {
+			return filter, fmt.Errorf("limit must be between 1 and %d", history.MaxLimit)
+		}
+		filter.Limit = limit
+	}
+
+	sort := c.QueryParam("sort")
+	if sort == "" {
+		sort = "-" + history.SortTime
+	}
+	filter.Descending = strings.HasPrefix(sort, "-")
+	filter.SortBy = strings.TrimPrefix(sort, "-")
+	if filter.SortBy != history.SortTime && filter.SortBy != history.SortAmount {
+		return filter, errors.New("sort must be time, -time, amount or -amount")
+	}
+
+	return filter, nil
+}
+
+func historyRowResponse(row *history.Row) map[string]interface{} {
+	direction := history.DirectionIn
+	if row.Amount < 0 {
+		direction = history.DirectionOut
+	}
+
+	return map[string]interface{}{
+		"transaction_id":   row.ID,
+		"journal_entry_id": row.JournalEntryID,
+		"type":             row.Type,
+		"direction":        direction,
+		"amount":           money.Format(row.Amount, money.Default),
+		"counterparty_id":  row.CounterpartyID(),
+		"description":      row.Description,
+		"reference":        row.Reference,
+		"category":         row.Category,
+		"tags":             row.Tags,
+		"fee_for_entry_id": row.FeeForID,
+		"transaction_time": row.TransactionTime,
+	}
+}
@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
+	"ledger-app/internal/risk"
+	"ledger-app/internal/transfers"
+	"ledger-app/internal/validation"
+	"ledger-app/internal/velocity"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func PlaceHold(ttl time.Duration) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		userID, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			logger.Logger.Error("Failed to convert user ID: ", err.Error())
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+		}
+
+		if !canAccessUser(c, userID) {
+			logger.Logger.Warnf("User ID %v attempted to place a hold on User ID %d", c.Get("userID"), userID)
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
+		}
+
+		creditReq := new(models.CreditRequest)
+		if err := c.Bind(creditReq); err != nil {
+			logger.Logger.Error("Invalid input: ", err.Error())
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+		}
+
+		if err := validation.ValidateStruct().Struct(creditReq); err != nil {
+			return validationFailed(c, err)
+		}
+
+		amount, err := creditReq.MinorUnits()
+		if err != nil {
+			logger.Logger.Error("Invalid amount: ", err.Error())
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		var user models.User
+		if err := database.Db.First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				logger.Logger.Error("User not found with ID: ", strconv.Itoa(userID))
+				return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+			}
+
+			logger.Logger.Error("Database error: ", err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		}
+
+		if refuseSender(c, &user) {
+			return nil
+		}
+
+		tx := database.Db.Begin()
+
+		// The status is checked again now that it cannot change before commit.
+		var hold *models.Hold
+		err = ledger.CheckStatus(tx, uint(userID), 0)
+		if err == nil {
+			hold, err = ledger.PlaceHold(tx, uint(userID), amount, ttl)
+		}
+		if err != nil {
+			tx.Rollback()
+			if errors.Is(err, models.ErrUserInactive) || errors.Is(err, ledger.ErrAccountClosed) {
+				return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+			}
+			if errors.Is(err, ledger.ErrInsufficientBalance) {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Insufficient balance"})
+			}
+
+			logger.Logger.Error("Failed to place hold: ", err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to place hold"})
+		}
+
+		if err := tx.Commit().Error; err != nil {
+			logger.Logger.Error("Failed to commit transaction: ", err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+		}
+
+		logger.Logger.Infof("Hold %d of %v placed on User ID %d", hold.ID, creditReq.Amount, userID)
+		return c.JSON(http.StatusCreated, holdResponse(hold))
+	}
+}
+
+func GetUserHolds(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	if !canAccessUser(c, userID) {
+		logger.Logger.Warnf("User ID %v attempted to list holds of User ID %d", c.Get("userID"), userID)
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
+	}
+
+	query := database.Db.Where("user_id = ?", userID)
+	if status := c.QueryParam("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var holds []models.Hold
+	if err := query.Order("id DESC").Find(&holds).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	response := make([]map[string]interface{}, 0, len(holds))
+	for i := range holds {
+		response = append(response, holdResponse(&holds[i]))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func CaptureHold(c echo.Context) error {
+	userID, holdID, ok := holdParams(c)
+	if !ok {
+		return nil
+	}
+
+	captureReq := new(models.CaptureRequest)
+	if err := c.Bind(captureReq); err != nil {
+		logger.Logger.Error("Invalid input: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+
+	if err := validation.ValidateStruct().Struct(captureReq); err != nil {
+		return validationFailed(c, err)
+	}
+
+	var amount int64
+	if captureReq.Amount != "" {
+		var err error
+		if amount, err = captureReq.Amount.MinorUnits(money.Default); err != nil {
+			logger.Logger.Error("Invalid amount: ", err.Error())
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+
+	var receiver models.User
+	if err := database.Db.First(&receiver, captureReq.ReceiverID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Logger.Error("Receiver not found with ID: ", captureReq.ReceiverID)
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Receiver not found"})
+		}
+
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	if refuseReceiver(c, &receiver) {
+		return nil
+	}
+
+	tx := database.Db.Begin()
+
+	if err := holdBelongsTo(tx, holdID, userID); err != nil {
+		tx.Rollback()
+		return holdError(c, err)
+	}
+
+	hold, result, err := transfers.CaptureHold(tx, holdID, captureReq.ReceiverID, amount, time.Now().UTC())
+	if err != nil {
+		tx.Rollback()
+
+		var riskErr *transfers.RiskError
+		var limitErr *velocity.LimitError
+		switch {
+		case errors.Is(err, ledger.ErrCaptureExceedsHold), errors.Is(err, transfers.ErrSelfTransfer):
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		case errors.As(err, &riskErr):
+			// A capture cannot be parked for review, so both refuse it.
+			message := "Capture requires risk review"
+			if riskErr.Assessment.Decision == risk.Deny {
+				recordFailedAttempt(userID, models.EntryTypeTransfer, "risk_denied")
+				message = "Capture denied by risk rules"
+			}
+			return c.JSON(http.StatusForbidden, map[string]interface{}{
+				"error":    message,
+				"findings": riskErr.Assessment.Findings,
+			})
+		case errors.Is(err, models.ErrUserInactive), errors.Is(err, ledger.ErrAccountClosed):
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		case errors.Is(err, ledger.ErrInsufficientBalance):
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Insufficient balance for the capture fee"})
+		case errors.As(err, &limitErr):
+			return c.JSON(http.StatusUnprocessableEntity, velocityRejected(limitErr))
+		}
+		return holdError(c, err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	logger.Logger.Infof("Hold %d captured into journal entry %d with a fee of %s",
+		hold.ID, result.Entry.ID, money.Format(result.Fee, money.Default))
+	return c.JSON(http.StatusOK, holdResponse(hold))
+}
+
+func ReleaseHold(c echo.Context) error {
+	userID, holdID, ok := holdParams(c)
+	if !ok {
+		return nil
+	}
+
+	tx := database.Db.Begin()
+
+	if err := holdBelongsTo(tx, holdID, userID); err != nil {
+		tx.Rollback()
+		return holdError(c, err)
+	}
+
+	hold, err := ledger.ReleaseHold(tx, holdID)
+	if err != nil {
+		tx.Rollback()
+		return holdError(c, err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	logger.Logger.Infof("Hold %d released", hold.ID)
+	return c.JSON(http.StatusOK, holdResponse(hold))
+}
+
+// holdParams parses the user and hold IDs and checks access. When it
+// reports false the error response has already been written.
+func holdParams(c echo.Context) (uint, uint, bool) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		_ = c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+		return 0, 0, false
+	}
+
+	if !canAccessUser(c, userID) {
+		logger.Logger.Warnf("User ID %v attempted to access holds of User ID %d", c.Get("userID"), userID)
+		_ = c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
+		return 0, 0, false
+	}
+
+	holdID, err := strconv.Atoi(c.Param("hold_id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert hold ID: ", err.Error())
+		_ = c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid hold ID format"})
+		return 0, 0, false
+	}
+
+	return uint(userID), uint(holdID), true
+}
+
+func holdBelongsTo(tx *gorm.DB, holdID, userID uint) error {
+	var count int64
+	if err := tx.Model(&models.Hold{}).Where("id = ? AND user_id = ?", holdID, userID).Count(&count).Error; err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return ledger.ErrHoldNotFound
+	}
+
+	return nil
+}
+
+func holdError(c echo.Context, err error) error {
+	switch {
+	case errors.Is(err, ledger.ErrHoldNotFound):
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Hold not found"})
+	case errors.Is(err, ledger.ErrHoldNotActive):
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Hold is no longer active"})
+	}
+
+	logger.Logger.Error("Failed to update hold: ", err.Error())
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update hold"})
+}
+
+func holdResponse(hold *models.Hold) map[string]interface{} {
+	return map[string]interface{}{
+		"hold_id":          hold.ID,
+		"user_id":          hold.UserID,
+		"amount":           money.Format(hold.Amount, money.Default),
+		"captured_amount":  money.Format(hold.CapturedAmount, money.Default),
+		"status":           hold.Status,
+		"expires_at":       hold.ExpiresAt,
+		"capture_entry_id": hold.CaptureEntryID,
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"ledger-app/internal/approvals"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/importer"
+	"ledger-app/logger"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func ImportTransactions(c echo.Context) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		logger.Logger.Error("Missing import file: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "An import file is required"})
+	}
+
+	format := c.FormValue("format")
+	if format == "" {
+		format = importer.FormatCSV
+	}
+
+	var mapping importer.Mapping
+	if value := c.FormValue("mapping"); value != "" {
+		if err := json.Unmarshal([]byte(value), &mapping); err != nil {
+			logger.Logger.Error("Invalid column mapping: ", err.Error())
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid column mapping"})
+		}
+	}
+
+	var userID uint
+	if value := c.FormValue("user_id"); value != "" {
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+		}
+		userID = uint(id)
+	}
+
+	dryRun := false
+	if value := c.FormValue("dry_run"); value != "" {
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid dry_run value"})
+		}
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		logger.Logger.Error("Failed to open import file: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read import file"})
+	}
+	defer src.Close()
+
+	rows, rowErrors, err := importer.Parse(format, src, mapping, userID)
+	if err != nil {
+		logger.Logger.Error("Failed to parse import file: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	now := time.Now().UTC()
+
+	// A file moving more than one admin may import alone waits for a
+	// second admin as a whole; it is checked first, so it is only
+	// submitted when it would import cleanly.
+	if !dryRun {
+		volume, err := importer.Volume(rows)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		if approvals.ImportVolumeNeedsApproval(volume) {
+			result, err := importer.Import(database.Db, rows, rowErrors, true, now, nil)
+			if err != nil {
+				logger.Logger.Error("Failed to check import: ", err.Error())
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to import transactions"})
+			}
+			if len(result.Errors) > 0 {
+				return c.JSON(http.StatusUnprocessableEntity, result)
+			}
+
+			request, err := approvals.SubmitImportFile(database.Db, rows, volume, adminUserID, now)
+			if err != nil {
+				logger.Logger.Error("Failed to submit import for approval: ", err.Error())
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to submit import for approval"})
+			}
+
+			return pendingApproval(c, request)
+		}
+	}
+
+	hold := func(tx *gorm.DB, row importer.Row) (uint, error) {
+		if !approvals.ImportNeedsApproval(row.Amount) {
+			return 0, nil
+		}
+
+		request, err := approvals.SubmitImportRow(tx, row, adminUserID, now)
+		if errors.Is(err, approvals.ErrAlreadyPending) {
+			return 0, errors.New("duplicate reference, already awaiting approval")
+		}
+		if err != nil {
+			return 0, err
+		}
+		return request.ID, nil
+	}
+
+	result, err := importer.Import(database.Db, rows, rowErrors, dryRun, now, hold)
+	if err != nil {
+		logger.Logger.Error("Failed to import transactions: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to import transactions"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"adminID":   adminUserID,
+		"file":      file.Filename,
+		"format":    format,
+		"rows":      result.Rows,
+		"held":      result.Held,
+		"errors":    len(result.Errors),
+		"dryRun":    result.DryRun,
+		"committed": result.Committed,
+	}).Info("Transaction import processed")
+
+	if len(result.Errors) > 0 {
+		return c.JSON(http.StatusUnprocessableEntity, result)
+	}
+
+	if result.Held > 0 {
+		return c.JSON(http.StatusAccepted, result)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"ledger-app/internal/audit"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/interest"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
+	"ledger-app/internal/validation"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func SetInterestRate(c echo.Context) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
+	class := c.Param("class")
+	if err := validation.ValidateStruct().Var(class, "max=32,slug"); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid account class"})
+	}
+
+	rateReq := new(models.InterestRateRequest)
+	if err := c.Bind(rateReq); err != nil {
+		logger.Logger.Error("Invalid input: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+
+	if err := validation.ValidateStruct().Struct(rateReq); err != nil {
+		return validationFailed(c, err)
+	}
+
+	ratePPM, err := money.ParsePercent(rateReq.Rate)
+	if err != nil {
+		logger.Logger.Error("Invalid rate: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	rate, err := interest.SetRate(database.Db, class, ratePPM, rateReq.Method, rateReq.DayCount, adminUserID)
+	if err != nil {
+		logger.Logger.Error("Failed to set interest rate: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to set interest rate"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"adminID":  adminUserID,
+		"class":    rate.Class,
+		"rate":     money.FormatPercent(rate.RatePPM),
+		"method":   rate.Method,
+		"dayCount": rate.DayCount,
+	}).Info("Interest rate set")
+
+	return c.JSON(http.StatusOK, interestRateResponse(rate))
+}
+
+func GetInterestRates(c echo.Context) error {
+	var rates []models.InterestRate
+	if err := database.Db.Order("class").Find(&rates).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	response := make([]map[string]interface{}, 0, len(rates))
+	for i := range rates {
+		response = append(response, interestRateResponse(&rates[i]))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func SetAccountClass(c echo.Context) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	classReq := new(models.AccountClassRequest)
+	if err := c.Bind(classReq); err != nil {
+		logger.Logger.Error("Invalid input: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+
+	if err := validation.ValidateStruct().Struct(classReq); err != nil {
+		return validationFailed(c, err)
+	}
+
+	var user models.User
+	if err := database.Db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Logger.Error("User not found with ID: ", strconv.Itoa(userID))
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+		}
+
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	tx := database.Db.Begin()
+
+	account, previous, err := interest.SetAccountClass(tx, user.ID, classReq.Class)
+	if err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to set account class: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to set account class"})
+	}
+
+	if err := recordAudit(c, tx, http.StatusOK, &audit.Change{
+		Action:     "account_class_change",
+		TargetType: "user",
+		TargetID:   user.ID,
+		Before:     map[string]string{"class": previous},
+		After:      map[string]string{"class": classReq.Class},
+	}); err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to record audit event: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record audit event"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"adminID":  adminUserID,
+		"userID":   user.ID,
+		"oldClass": previous,
+		"newClass": classReq.Class,
+	}).Info("Account class changed")
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":   "Account class updated successfully",
+		"user_id":   user.ID,
+		"account":   account.Code,
+		"old_class": previous,
+		"new_class": classReq.Class,
+	})
+}
+
+func GetUserInterest(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	if !canAccessUser(c, userID) {
+		logger.Logger.Warnf("User ID %v attempted to view the interest of User ID %d", c.Get("userID"), userID)
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
+	}
+
+	var user models.User
+	if err := database.Db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Logger.Error("User not found with ID: ", strconv.Itoa(userID))
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+		}
+
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	account, err := ledger.UserAccount(database.Db, user.ID)
+	if err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	preview, err := interest.PreviewAccount(database.Db, account, time.Now().UTC())
+	if err != nil {
+		logger.Logger.Error("Failed to preview interest: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to preview interest"})
+	}
+
+	response := map[string]interface{}{
+		"user_id":          user.ID,
+		"account_class":    account.Class,
+		"accrued_interest": money.Format(preview.WholeUnits(), money.Default),
+		"accrued_through":  preview.AccruedThrough,
+		"currency":         money.Default.Code,
+	}
+	if preview.Rate != nil {
+		response["rate"] = interestRateResponse(preview.Rate)
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func interestRateResponse(rate *models.InterestRate) map[string]interface{} {
+	return map[string]interface{}{
+		"class":      rate.Class,
+		"rate":       money.FormatPercent(rate.RatePPM),
+		"method":     rate.Method,
+		"day_count":  rate.DayCount,
+		"updated_by": rate.UpdatedBy,
+		"updated_at": rate.UpdatedAt,
+	}
+}
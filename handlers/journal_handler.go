@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"io"
+	"ledger-app/internal/approvals"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/journal"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func ExportJournal(c echo.Context) error {
+	format := c.QueryParam("format")
+	if format == "" {
+		format = journal.FormatHledger
+	}
+
+	extension, err := journal.Extension(format)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	var userID *uint
+	filename := fmt.Sprintf("ledger-%s.%s", time.Now().UTC().Format("20060102"), extension)
+
+	if value := c.QueryParam("user_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			logger.Logger.Error("Failed to convert user ID: ", err.Error())
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+		}
+
+		var user models.User
+		if err := database.Db.First(&user, id).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				logger.Logger.Error("User not found with ID: ", strconv.Itoa(id))
+				return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+			}
+
+			logger.Logger.Error("Database error: ", err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		}
+
+		userID = &user.ID
+		filename = fmt.Sprintf("ledger-user-%d-%s.%s", user.ID, time.Now().UTC().Format("20060102"), extension)
+	}
+
+	c.Response().Header().Set(echo.HeaderContentType, echo.MIMETextPlainCharsetUTF8)
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	// The status line is already sent, so failures from here on can only be logged.
+	count, err := journal.Export(database.Db, c.Response(), format, userID)
+	if err != nil {
+		logger.Logger.Error("Failed to export journal: ", err.Error())
+		return nil
+	}
+
+	logger.Logger.Infof("Exported %d journal entries as %s", count, format)
+	return nil
+}
+
+func ImportJournal(c echo.Context) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		logger.Logger.Error("Missing journal file: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "A journal file is required"})
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		logger.Logger.Error("Failed to open journal file: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read journal file"})
+	}
+	defer src.Close()
+
+	content, err := io.ReadAll(src)
+	if err != nil {
+		logger.Logger.Error("Failed to read journal file: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read journal file"})
+	}
+
+	volume, err := journal.Volume(bytes.NewReader(content))
+	if err != nil {
+		logger.Logger.Error("Failed to parse journal file: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	if approvals.ImportVolumeNeedsApproval(volume) {
+		request, err := approvals.SubmitJournalImport(database.Db, file.Filename, content, volume, adminUserID, time.Now().UTC())
+		if err != nil {
+			logger.Logger.Error("Failed to submit journal import for approval: ", err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to submit journal import for approval"})
+		}
+
+		return pendingApproval(c, request)
+	}
+
+	result, err := journal.Import(database.Db, bytes.NewReader(content))
+	if err != nil {
+		logger.Logger.Error("Failed to import journal: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to import journal"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"adminID":      adminUserID,
+		"file":         file.Filename,
+		"entries":      result.Entries,
+		"duplicates":   result.Duplicates,
+		"usersCreated": result.UsersCreated,
+		"errors":       len(result.Errors),
+		"committed":    result.Committed,
+	}).Info("Journal import processed")
+
+	if !result.Committed {
+		return c.JSON(http.StatusUnprocessableEntity, result)
+	}
+
+	return c.JSON(http.StatusOK, result)
+}
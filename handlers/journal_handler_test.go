@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"bytes"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"ledger-app/internal/journal"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/testdb"
+	"ledger-app/models"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// totalBalances returns the body GetAllUsersTotalBalance answers with.
+func totalBalances(t *testing.T) string {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/admin/balances", nil), rec)
+	if err := GetAllUsersTotalBalance(c); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("balances answered %d: %s", rec.Code, rec.Body.String())
+	}
+
+	return rec.Body.String()
+}
+
+func TestJournalRoundTripReproducesBalances(t *testing.T) {
+	db := testdb.Open(t)
+
+	alice := &models.User{Name: "alice", PasswordHash: "x"}
+	bob := &models.User{Name: "bob", PasswordHash: "x"}
+	for _, user := range []*models.User{alice, bob} {
+		if err := db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if _, err := ledger.Credit(tx, alice.ID, 10000, models.EntryMetadata{Reference: "opening"}); err != nil {
+			return err
+		}
+
+		transfer, err := ledger.Transfer(tx, alice.ID, bob.ID, 2500, models.EntryMetadata{Tags: []string{"rent"}})
+		if err != nil {
+			return err
+		}
+		if _, err := ledger.ChargeFee(tx, alice.ID, 25, transfer); err != nil {
+			return err
+		}
+		if _, _, _, err := ledger.Reverse(tx, transfer.Postings[0].ID, 1000, ledger.PostOptions{}); err != nil {
+			return err
+		}
+
+		_, err = ledger.Withdraw(tx, bob.ID, 500, models.EntryMetadata{})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := totalBalances(t)
+
+	var exported bytes.Buffer
+	if _, err := journal.Export(db, &exported, journal.FormatHledger, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	db = testdb.Open(t)
+
+	first, err := journal.Import(db, bytes.NewReader(exported.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !first.Committed || first.Duplicates != 0 {
+		t.Fatalf("import = %+v, want it committed without duplicates", first)
+	}
+	if got := totalBalances(t); got != want {
+		t.Errorf("balances after import = %s, want %s", got, want)
+	}
+
+	second, err := journal.Import(db, bytes.NewReader(exported.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !second.Committed || second.Entries != 0 || second.Duplicates != first.Entries {
+		t.Fatalf("re-import = %+v, want all %d entries skipped as duplicates", second, first.Entries)
+	}
+	if got := totalBalances(t); got != want {
+		t.Errorf("balances after re-import = %s, want %s", got, want)
+	}
+
+	chainBreak, _, err := ledger.VerifyChain(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chainBreak != nil {
+		t.Errorf("imported ledger does not verify: %s", chainBreak)
+	}
+}
+
+// exportLedger fills a fresh database with a credit and a transfer and
+// returns its export.
+func exportLedger(t *testing.T, names ...string) (*gorm.DB, []byte) {
+	t.Helper()
+	db := testdb.Open(t)
+
+	var users []*models.User
+	for _, name := range names {
+		user := &models.User{Name: name, PasswordHash: "x"}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+		users = append(users, user)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if _, err := ledger.Credit(tx, users[0].ID, 10000, models.EntryMetadata{}); err != nil {
+			return err
+		}
+		_, err := ledger.Transfer(tx, users[0].ID, users[1].ID, 2500, models.EntryMetadata{})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var exported bytes.Buffer
+	if _, err := journal.Export(db, &exported, journal.FormatBeancount, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	return db, exported.Bytes()
+}
+
+func TestJournalImportOfOwnExportSkipsEveryEntry(t *testing.T) {
+	db, exported := exportLedger(t, "alice", "bob")
+	want := totalBalances(t)
+
+	result, err := journal.Import(db, bytes.NewReader(exported))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Committed || result.Entries != 0 || result.Duplicates != 2 {
+		t.Fatalf("import = %+v, want both entries skipped as duplicates", result)
+	}
+	if got := totalBalances(t); got != want {
+		t.Errorf("balances after import = %s, want %s", got, want)
+	}
+}
+
+func TestJournalImportKeepsLedgersWithOverlappingIDsApart(t *testing.T) {
+	_, first := exportLedger(t, "alice", "bob")
+	_, second := exportLedger(t, "alice", "bob")
+
+	db := testdb.Open(t)
+	for i, exported := range [][]byte{first, second} {
+		result, err := journal.Import(db, bytes.NewReader(exported))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !result.Committed || result.Entries != 2 || result.Duplicates != 0 {
+			t.Fatalf("import of ledger %d = %+v, want both entries posted", i+1, result)
+		}
+	}
+
+	var alice models.Account
+	if err := db.Where("user_id = ?", 1).First(&alice).Error; err != nil {
+		t.Fatal(err)
+	}
+	if alice.Balance != 15000 {
+		t.Errorf("alice balance = %d, want 15000 from both ledgers", alice.Balance)
+	}
+}
+
+func TestJournalImportRefusesDebitsFromFrozenUsers(t *testing.T) {
+	_, exported := exportLedger(t, "alice", "bob")
+
+	db, _ := exportLedger(t, "alice", "bob")
+	if err := db.Model(&models.User{}).Where("id = ?", 1).Update("status", models.UserStatusFrozen).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := totalBalances(t)
+
+	result, err := journal.Import(db, bytes.NewReader(exported))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Committed || len(result.Errors) != 1 || !strings.Contains(result.Errors[0].Error, models.ErrUserInactive.Error()) {
+		t.Fatalf("import = %+v, want the transfer out of the frozen account refused", result)
+	}
+	if got := totalBalances(t); got != want {
+		t.Errorf("balances after refused import = %s, want %s", got, want)
+	}
+}
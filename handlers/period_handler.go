@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/validation"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"time"
+)
+
+func ClosePeriod(c echo.Context) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
+	closeReq := new(models.PeriodCloseRequest)
+	if err := c.Bind(closeReq); err != nil {
+		logger.Logger.Error("Invalid input: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+
+	if err := validation.ValidateStruct().Struct(closeReq); err != nil {
+		return validationFailed(c, err)
+	}
+
+	tx := database.Db.Begin()
+
+	period, snapshots, err := ledger.ClosePeriod(tx, closeReq.Through, adminUserID, closeReq.Reason, time.Now().UTC())
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, ledger.ErrPeriodNotEnded) || errors.Is(err, ledger.ErrPeriodAlreadyClosed) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+
+		logger.Logger.Error("Failed to close period: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to close period"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"adminID":       adminUserID,
+		"closedThrough": period.ClosedThrough,
+		"reason":        period.Reason,
+		"snapshots":     snapshots,
+	}).Info("Accounting period closed")
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":        "Period closed successfully",
+		"closed_through": period.ClosedThrough,
+		"snapshots":      snapshots,
+	})
+}
+
+func GetClosedPeriods(c echo.Context) error {
+	var periods []models.PeriodClose
+	if err := database.Db.Order("closed_through DESC").Find(&periods).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, periods)
+}
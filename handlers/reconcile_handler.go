@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/reconcile"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func RunReconciliation(c echo.Context) error {
+	report, err := reconcile.Run(database.Db, reconcile.TriggerManual, time.Now().UTC())
+	if err != nil {
+		logger.Logger.Error("Failed to reconcile ledger: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to reconcile ledger"})
+	}
+
+	if len(report.Discrepancies) > 0 {
+		logger.Logger.Errorf("Reconciliation found %d discrepancies", len(report.Discrepancies))
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
+
+func GetReconciliationRuns(c echo.Context) error {
+	var runs []models.ReconciliationRun
+	if err := database.Db.Omit("report").Order("id DESC").Limit(100).Find(&runs).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	response := make([]map[string]interface{}, 0, len(runs))
+	for _, run := range runs {
+		response = append(response, map[string]interface{}{
+			"id":            run.ID,
+			"trigger":       run.Trigger,
+			"discrepancies": run.Discrepancies,
+			"created_at":    run.CreatedAt,
+		})
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func GetReconciliationRun(c echo.Context) error {
+	runID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert run ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid run ID format"})
+	}
+
+	var run models.ReconciliationRun
+	if err := database.Db.First(&run, runID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Reconciliation run not found"})
+		}
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	var report reconcile.Report
+	if err := json.Unmarshal([]byte(run.Report), &report); err != nil {
+		logger.Logger.Error("Failed to decode reconciliation report: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to decode reconciliation report"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"id":         run.ID,
+		"trigger":    run.Trigger,
+		"created_at": run.CreatedAt,
+		"report":     report,
+	})
+}
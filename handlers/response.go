@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"ledger-app/logger"
+	"net/http"
+)
+
+func validationFailed(c echo.Context, err error) error {
+	var errorMessage []string
+	for _, err := range err.(validator.ValidationErrors) {
+		errorMessage = append(errorMessage, fmt.Sprintf("Field %s failed validation: %s parameter: %s", err.Field(), err.Tag(), err.Param()))
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"details": errorMessage,
+	}).Error("Validation failed")
+	return c.JSON(http.StatusBadRequest, map[string]interface{}{
+		"error":   "Validation failed",
+		"details": errorMessage,
+	})
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"ledger-app/internal/audit"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
+	"ledger-app/internal/validation"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"strconv"
+)
+
+func ReverseTransaction(c echo.Context) error {
+	adminUserID := c.Get("userID")
+
+	transactionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert transaction ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid transaction ID format"})
+	}
+
+	reversalReq := new(models.ReversalRequest)
+	if err := c.Bind(reversalReq); err != nil {
+		logger.Logger.Error("Invalid input: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+
+	if err := validation.ValidateStruct().Struct(reversalReq); err != nil {
+		return validationFailed(c, err)
+	}
+
+	var amount int64
+	if reversalReq.Amount != "" {
+		if amount, err = reversalReq.Amount.MinorUnits(money.Default); err != nil {
+			logger.Logger.Error("Invalid amount: ", err.Error())
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+
+	tx := database.Db.Begin()
+
+	reversal, feeReversal, original, err := ledger.Reverse(tx, uint(transactionID), amount, ledger.PostOptions{
+		AllowNegative:   reversalReq.AllowNegative,
+		ExceptionReason: fmt.Sprintf("Reversal approved by admin %v: %s", adminUserID, reversalReq.Reason),
+	})
+	if err != nil {
+		tx.Rollback()
+		switch {
+		case errors.Is(err, ledger.ErrEntryNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Transaction not found"})
+		case errors.Is(err, ledger.ErrInsufficientBalance):
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Counterparty has insufficient balance for the reversal"})
+		case errors.Is(err, ledger.ErrReversalOfReversal),
+			errors.Is(err, ledger.ErrAlreadyReversed),
+			errors.Is(err, ledger.ErrReversalExceedsEntry),
+			errors.Is(err, ledger.ErrPartialReversalUnsound),
+			errors.Is(err, ledger.ErrAccountClosed):
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+
+		logger.Logger.Error("Failed to reverse transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to reverse transaction"})
+	}
+
+	after := map[string]interface{}{
+		"reversal_entry_id": reversal.ID,
+		"reversed_amount":   money.Format(reversal.Amount(), money.Default),
+		"allow_negative":    reversalReq.AllowNegative,
+		"reason":            reversalReq.Reason,
+	}
+	response := map[string]interface{}{
+		"message":             "Transaction reversed successfully",
+		"reversal_entry_id":   reversal.ID,
+		"original_entry_id":   original.ID,
+		"reversed_amount":     money.Format(reversal.Amount(), money.Default),
+		"remaining_amount":    money.Format(original.Amount()-original.ReversedAmount, money.Default),
+		"negative_balance_ok": reversalReq.AllowNegative,
+	}
+	if feeReversal != nil {
+		after["fee_reversal_entry_id"] = feeReversal.ID
+		after["fee_refunded"] = money.Format(feeReversal.Amount(), money.Default)
+		response["fee_reversal_entry_id"] = feeReversal.ID
+		response["fee_refunded"] = money.Format(feeReversal.Amount(), money.Default)
+	}
+
+	if err := recordAudit(c, tx, http.StatusOK, &audit.Change{
+		Action:     "reversal",
+		TargetType: "journal_entry",
+		TargetID:   original.ID,
+		After:      after,
+	}); err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to record audit event: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record audit event"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	logger.Logger.Infof("Admin %v reversed %s of journal entry %d with entry %d: %s",
+		adminUserID, money.Format(reversal.Amount(), money.Default), original.ID, reversal.ID, reversalReq.Reason)
+	return c.JSON(http.StatusOK, response)
+}
@@ -0,0 +1,191 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"ledger-app/internal/audit"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
+	"ledger-app/internal/risk"
+	"ledger-app/internal/transfers"
+	"ledger-app/internal/validation"
+	"ledger-app/internal/velocity"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// riskRefused answers a transfer the risk rules did not allow. A denied
+// transfer counts as a failed attempt; one held for review is parked for
+// an admin to release or reject.
+func riskRefused(c echo.Context, riskErr *transfers.RiskError, req *transfers.Request) error {
+	assessment := riskErr.Assessment
+	if assessment.Decision == risk.Deny {
+		recordFailedAttempt(req.SenderID, models.EntryTypeTransfer, "risk_denied")
+		logger.Logger.WithFields(logrus.Fields{
+			"senderID":   req.SenderID,
+			"receiverID": req.ReceiverID,
+			"findings":   assessment.Findings,
+		}).Warn("Transfer denied by risk rules")
+		return c.JSON(http.StatusForbidden, map[string]interface{}{
+			"error":    "Transfer denied by risk rules",
+			"findings": assessment.Findings,
+		})
+	}
+
+	transfer := &risk.Transfer{SenderID: req.SenderID, ReceiverID: req.ReceiverID, Amount: req.Amount}
+	review, err := risk.Park(database.Db, transfer, req.Meta, assessment)
+	if err != nil {
+		logger.Logger.Error("Failed to park transfer for review: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to park transfer for review"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"reviewID":   review.ID,
+		"senderID":   req.SenderID,
+		"receiverID": req.ReceiverID,
+		"findings":   assessment.Findings,
+	}).Warn("Transfer held for review")
+	response := riskReviewResponse(review)
+	response["message"] = "Transfer held for review"
+	return c.JSON(http.StatusAccepted, response)
+}
+
+// recordFailedAttempt feeds the failed attempts risk rule. Failing to
+// record one must not change the answer to the user.
+func recordFailedAttempt(userID uint, operation, reason string) {
+	if err := risk.RecordFailure(database.Db, userID, operation, reason); err != nil {
+		logger.Logger.Error("Failed to record failed attempt: ", err.Error())
+	}
+}
+
+func GetRiskReviews(c echo.Context) error {
+	status := c.QueryParam("status")
+	if status == "" {
+		status = models.RiskReviewPending
+	}
+
+	var reviews []models.RiskReview
+	if err := database.Db.Where("status = ?", status).Order("id").Limit(200).Find(&reviews).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	response := make([]map[string]interface{}, 0, len(reviews))
+	for i := range reviews {
+		response = append(response, riskReviewResponse(&reviews[i]))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func ReleaseRiskReview(c echo.Context) error {
+	return decideRiskReview(c, models.RiskReviewReleased)
+}
+
+func RejectRiskReview(c echo.Context) error {
+	return decideRiskReview(c, models.RiskReviewRejected)
+}
+
+func decideRiskReview(c echo.Context, status string) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
+	reviewID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert review ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid review ID format"})
+	}
+
+	decisionReq := new(models.RiskDecisionRequest)
+	if err := c.Bind(decisionReq); err != nil {
+		logger.Logger.Error("Invalid input: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+
+	if err := validation.ValidateStruct().Struct(decisionReq); err != nil {
+		return validationFailed(c, err)
+	}
+
+	now := time.Now().UTC()
+	tx := database.Db.Begin()
+
+	var review *models.RiskReview
+	var result *transfers.Result
+	if status == models.RiskReviewReleased {
+		review, result, err = transfers.ReleaseReview(tx, uint(reviewID), adminUserID, decisionReq.Reason, now)
+	} else {
+		review, err = risk.Reject(tx, uint(reviewID), adminUserID, decisionReq.Reason, now)
+	}
+	if err != nil {
+		tx.Rollback()
+
+		var limitErr *velocity.LimitError
+		switch {
+		case errors.Is(err, risk.ErrReviewNotFound):
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Review not found"})
+		case errors.Is(err, risk.ErrReviewNotPending),
+			errors.Is(err, models.ErrUserInactive), errors.Is(err, ledger.ErrAccountClosed):
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		case errors.Is(err, ledger.ErrInsufficientBalance):
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Sender has insufficient balance"})
+		case errors.As(err, &limitErr):
+			return c.JSON(http.StatusUnprocessableEntity, velocityRejected(limitErr))
+		}
+
+		logger.Logger.Error("Failed to decide review: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to decide review"})
+	}
+
+	response := riskReviewResponse(review)
+	if result != nil && result.FeeEntry != nil {
+		response["fee"] = money.Format(result.Fee, money.Default)
+	}
+
+	if err := recordAudit(c, tx, http.StatusOK, &audit.Change{
+		Action:     "risk_review_" + status,
+		TargetType: "risk_review",
+		TargetID:   review.ID,
+		Before:     map[string]string{"status": models.RiskReviewPending},
+		After:      response,
+	}); err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to record audit event: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record audit event"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"adminID":  adminUserID,
+		"reviewID": review.ID,
+		"senderID": review.SenderID,
+		"status":   review.Status,
+	}).Info("Risk review decided")
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func riskReviewResponse(review *models.RiskReview) map[string]interface{} {
+	return map[string]interface{}{
+		"review_id":       review.ID,
+		"sender_id":       review.SenderID,
+		"receiver_id":     review.ReceiverID,
+		"amount":          money.Format(review.Amount, money.Default),
+		"metadata":        json.RawMessage(review.Metadata),
+		"findings":        json.RawMessage(review.Findings),
+		"status":          review.Status,
+		"decided_by":      review.DecidedBy,
+		"decision_reason": review.DecisionReason,
+		"entry_id":        review.EntryID,
+		"decided_at":      review.DecidedAt,
+		"created_at":      review.CreatedAt,
+	}
+}
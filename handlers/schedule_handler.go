@@ -0,0 +1,249 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/money"
+	"ledger-app/internal/validation"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func CreateSchedule(c echo.Context) error {
+	senderID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	if !canAccessUser(c, senderID) {
+		logger.Logger.Warnf("User ID %v attempted to schedule a transfer for User ID %d", c.Get("userID"), senderID)
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
+	}
+
+	scheduleReq := new(models.ScheduleRequest)
+	if err := c.Bind(scheduleReq); err != nil {
+		logger.Logger.Error("Invalid input: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+
+	if err := validation.ValidateStruct().Struct(scheduleReq); err != nil {
+		return validationFailed(c, err)
+	}
+
+	amount, err := scheduleReq.MinorUnits()
+	if err != nil {
+		logger.Logger.Error("Invalid amount: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	if scheduleReq.StartAt.Before(time.Now().UTC()) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "StartAt must be in the future"})
+	}
+
+	if scheduleReq.ReceiverID == uint(senderID) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Cannot schedule a transfer to the same user"})
+	}
+
+	var sender models.User
+	if err := database.Db.First(&sender, senderID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Logger.Error("Sender not found with ID: ", strconv.Itoa(senderID))
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Sender not found"})
+		}
+
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	var receiver models.User
+	if err := database.Db.First(&receiver, scheduleReq.ReceiverID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Logger.Error("Receiver not found with ID: ", scheduleReq.ReceiverID)
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Receiver not found"})
+		}
+
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	if refuseSender(c, &sender) || refuseReceiver(c, &receiver) {
+		return nil
+	}
+
+	startAt := scheduleReq.StartAt.UTC()
+	schedule := models.ScheduledTransfer{
+		SenderID:   uint(senderID),
+		ReceiverID: scheduleReq.ReceiverID,
+		Amount:     amount,
+		Frequency:  scheduleReq.Frequency,
+		StartAt:    startAt,
+		NextRunAt:  startAt,
+		EndAt:      scheduleReq.EndAt,
+		MaxRuns:    scheduleReq.MaxRuns,
+		Status:     models.ScheduleStatusActive,
+	}
+
+	if err := database.Db.Create(&schedule).Error; err != nil {
+		logger.Logger.Error("Failed to create schedule: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create schedule"})
+	}
+
+	logger.Logger.Infof("User ID %d scheduled a %s transfer of %v to User ID %d", senderID, schedule.Frequency, scheduleReq.Amount, schedule.ReceiverID)
+	return c.JSON(http.StatusCreated, scheduleResponse(&schedule))
+}
+
+func GetUserSchedules(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	if !canAccessUser(c, userID) {
+		logger.Logger.Warnf("User ID %v attempted to list schedules of User ID %d", c.Get("userID"), userID)
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
+	}
+
+	query := database.Db.Where("sender_id = ?", userID)
+	if status := c.QueryParam("status"); status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var schedules []models.ScheduledTransfer
+	if err := query.Order("id DESC").Find(&schedules).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	response := make([]map[string]interface{}, 0, len(schedules))
+	for i := range schedules {
+		response = append(response, scheduleResponse(&schedules[i]))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func GetScheduleRuns(c echo.Context) error {
+	schedule, ok := findSchedule(c, database.Db)
+	if !ok {
+		return nil
+	}
+
+	var runs []models.ScheduleRun
+	if err := database.Db.Where("scheduled_transfer_id = ?", schedule.ID).Order("id DESC").Find(&runs).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, runs)
+}
+
+func CancelSchedule(c echo.Context) error {
+	return changeScheduleStatus(c, models.ScheduleStatusCancelled, models.ScheduleStatusActive, models.ScheduleStatusPaused)
+}
+
+func PauseSchedule(c echo.Context) error {
+	return changeScheduleStatus(c, models.ScheduleStatusPaused, models.ScheduleStatusActive)
+}
+
+func ResumeSchedule(c echo.Context) error {
+	return changeScheduleStatus(c, models.ScheduleStatusActive, models.ScheduleStatusPaused)
+}
+
+func changeScheduleStatus(c echo.Context, to string, from ...string) error {
+	tx := database.Db.Begin()
+
+	schedule, ok := findSchedule(c, tx.Clauses(clause.Locking{Strength: "UPDATE"}))
+	if !ok {
+		tx.Rollback()
+		return nil
+	}
+
+	allowed := false
+	for _, status := range from {
+		allowed = allowed || schedule.Status == status
+	}
+
+	if !allowed {
+		tx.Rollback()
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Schedule is " + schedule.Status})
+	}
+
+	previous := schedule.Status
+	if err := tx.Model(schedule).Update("status", to).Error; err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to update schedule: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update schedule"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	logger.Logger.Infof("Schedule %d changed from %s to %s", schedule.ID, previous, to)
+	schedule.Status = to
+	return c.JSON(http.StatusOK, scheduleResponse(schedule))
+}
+
+// findSchedule loads the schedule named in the path after checking the
+// caller may access its sender. When it reports false the error response
+// has already been written.
+func findSchedule(c echo.Context, db *gorm.DB) (*models.ScheduledTransfer, bool) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		_ = c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+		return nil, false
+	}
+
+	if !canAccessUser(c, userID) {
+		logger.Logger.Warnf("User ID %v attempted to access schedules of User ID %d", c.Get("userID"), userID)
+		_ = c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
+		return nil, false
+	}
+
+	scheduleID, err := strconv.Atoi(c.Param("schedule_id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert schedule ID: ", err.Error())
+		_ = c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid schedule ID format"})
+		return nil, false
+	}
+
+	var schedule models.ScheduledTransfer
+	if err := db.Where("id = ? AND sender_id = ?", scheduleID, userID).First(&schedule).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = c.JSON(http.StatusNotFound, map[string]string{"error": "Schedule not found"})
+			return nil, false
+		}
+
+		logger.Logger.Error("Database error: ", err.Error())
+		_ = c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return nil, false
+	}
+
+	return &schedule, true
+}
+
+func scheduleResponse(schedule *models.ScheduledTransfer) map[string]interface{} {
+	return map[string]interface{}{
+		"schedule_id": schedule.ID,
+		"sender_id":   schedule.SenderID,
+		"receiver_id": schedule.ReceiverID,
+		"amount":      money.Format(schedule.Amount, money.Default),
+		"frequency":   schedule.Frequency,
+		"start_at":    schedule.StartAt,
+		"next_run_at": schedule.NextRunAt,
+		"end_at":      schedule.EndAt,
+		"max_runs":    schedule.MaxRuns,
+		"run_count":   schedule.RunCount,
+		"status":      schedule.Status,
+	}
+}
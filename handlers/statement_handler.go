@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
+	"ledger-app/internal/statement"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func GetUserStatement(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	if !canAccessUser(c, userID) {
+		logger.Logger.Warnf("User ID %v attempted to export the statement of User ID %d", c.Get("userID"), userID)
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
+	}
+
+	format := c.QueryParam("format")
+	if format == "" {
+		format = "csv"
+	}
+
+	contentType, extension, err := statement.ContentType(format)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	from, err := time.Parse(time.RFC3339, c.QueryParam("from"))
+	if err != nil {
+		logger.Logger.Error("Failed to parse from: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid from time format"})
+	}
+
+	to := time.Now().UTC()
+	if value := c.QueryParam("to"); value != "" {
+		if to, err = time.Parse(time.RFC3339, value); err != nil {
+			logger.Logger.Error("Failed to parse to: ", err.Error())
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid to time format"})
+		}
+	}
+
+	if !from.Before(to) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "from must be before to"})
+	}
+
+	var user models.User
+	if err := database.Db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Logger.Error("User not found with ID: ", strconv.Itoa(userID))
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+		}
+
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	account, err := ledger.UserAccount(database.Db, user.ID)
+	if err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	header := &statement.Header{
+		UserID:   user.ID,
+		UserName: user.Name,
+		Account:  account.Code,
+		From:     from,
+		To:       to,
+		Currency: money.Default,
+	}
+
+	filename := fmt.Sprintf("statement-%d-%s-%s.%s", user.ID, from.UTC().Format("20060102"), to.UTC().Format("20060102"), extension)
+	c.Response().Header().Set(echo.HeaderContentType, contentType)
+	c.Response().Header().Set(echo.HeaderContentDisposition, fmt.Sprintf("attachment; filename=%q", filename))
+	c.Response().WriteHeader(http.StatusOK)
+
+	// The status line is already sent, so failures from here on can only be logged.
+	closing, err := statement.Write(database.Db, c.Response(), format, account.ID, header)
+	if err != nil {
+		logger.Logger.Error("Failed to write statement: ", err.Error())
+		return nil
+	}
+
+	logger.Logger.Infof("Exported %s statement for User ID %d from %v to %v, closing balance %s",
+		format, user.ID, from, to, money.Format(closing, money.Default))
+	return nil
+}
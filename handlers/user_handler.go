@@ -3,14 +3,19 @@ package handlers
 import (
 	"errors"
 	"fmt"
-	"github.com/go-playground/validator/v10"
 	"github.com/labstack/echo/v4"
-	"github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"ledger-app/internal/approvals"
+	"ledger-app/internal/audit"
 	"ledger-app/internal/auth"
 	"ledger-app/internal/connections/database"
+	"ledger-app/internal/fees"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
+	"ledger-app/internal/transfers"
 	"ledger-app/internal/validation"
+	"ledger-app/internal/velocity"
 	"ledger-app/logger"
 	"ledger-app/models"
 	"net/http"
@@ -19,9 +24,20 @@ import (
 )
 
 func GetAllUser(c echo.Context) error {
-	var users []models.User
-
-	if err := database.Db.Preload("Credits").Find(&users).Error; err != nil {
+	var users []struct {
+		ID      uint
+		Name    string
+		IsAdmin bool
+		Status  string
+		Balance int64
+	}
+
+	// The stored account balance stands in for the user's postings.
+	if err := database.Db.Table("users").
+		Select("users.id, users.name, users.is_admin, users.status, COALESCE(accounts.balance, 0) AS balance").
+		Joins("LEFT JOIN accounts ON accounts.user_id = users.id").
+		Order("users.id").
+		Scan(&users).Error; err != nil {
 		logger.Logger.Error(fmt.Sprintf("Failed to fetch user: %s", err.Error()))
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to fetch users"})
 	}
@@ -31,15 +47,29 @@ func GetAllUser(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{"message": "No users found"})
 	}
 
+	response := make([]map[string]interface{}, 0, len(users))
+	for _, user := range users {
+		response = append(response, map[string]interface{}{
+			"ID":       user.ID,
+			"Name":     user.Name,
+			"IsAdmin":  user.IsAdmin,
+			"Status":   user.Status,
+			"Balance":  money.Format(user.Balance, money.Default),
+			"Currency": money.Default.Code,
+		})
+	}
+
 	logger.Logger.WithFields(map[string]interface{}{
 		"Status":     http.StatusOK,
 		"User Count": len(users),
 	}).Info("Listen all users")
 
-	return c.JSON(http.StatusOK, users)
+	return c.JSON(http.StatusOK, response)
 }
 
 func AddCreditToUser(c echo.Context) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
 	userID, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		logger.Logger.Error("Failed to convert user ID: ", err.Error())
@@ -53,18 +83,13 @@ func AddCreditToUser(c echo.Context) error {
 	}
 
 	if err := validation.ValidateStruct().Struct(creditReq); err != nil {
-		var errorMessage []string
-		for _, err := range err.(validator.ValidationErrors) {
-			errorMessage = append(errorMessage, fmt.Sprintf("Field %s failed validation: %s parameter: %s", err.Field(), err.Tag(), err.Param()))
-		}
+		return validationFailed(c, err)
+	}
 
-		logger.Logger.WithFields(logrus.Fields{
-			"details": errorMessage,
-		}).Error("Validation failed")
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"error":   "Validation failed",
-			"details": errorMessage,
-		})
+	amount, err := creditReq.MinorUnits()
+	if err != nil {
+		logger.Logger.Error("Invalid amount: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
 	var user models.User
@@ -78,17 +103,58 @@ func AddCreditToUser(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
 	}
 
-	credit := models.Transaction{
-		UserID:          uint(userID),
-		Amount:          creditReq.Amount,
-		TransactionTime: time.Now().UTC(),
+	if refuseReceiver(c, &user) {
+		return nil
 	}
 
-	if err := database.Db.Create(&credit).Error; err != nil {
+	if approvals.CreditNeedsApproval(amount) {
+		request, err := approvals.SubmitCredit(database.Db, user.ID, amount, creditReq.EntryMetadata, adminUserID, time.Now().UTC())
+		if err != nil {
+			logger.Logger.Error("Failed to submit credit for approval: ", err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to submit credit for approval"})
+		}
+
+		return pendingApproval(c, request)
+	}
+
+	tx := database.Db.Begin()
+
+	// The status is checked again now that it cannot change before commit.
+	var entry *models.JournalEntry
+	err = ledger.CheckStatus(tx, 0, uint(userID))
+	if err == nil {
+		entry, err = ledger.Credit(tx, uint(userID), amount, creditReq.EntryMetadata)
+	}
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, models.ErrUserInactive) || errors.Is(err, ledger.ErrAccountClosed) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+
 		logger.Logger.Error("Failed to add credit: ", err.Error())
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to add credit"})
 	}
 
+	if err := recordAudit(c, tx, http.StatusOK, &audit.Change{
+		Action:     "credit",
+		TargetType: "user",
+		TargetID:   user.ID,
+		After: map[string]interface{}{
+			"amount":           money.Format(amount, money.Default),
+			"journal_entry_id": entry.ID,
+			"reference":        creditReq.Reference,
+		},
+	}); err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to record audit event: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record audit event"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
 	logger.Logger.Infof("Credit of %v added to User ID %d", creditReq.Amount, userID)
 	return c.JSON(http.StatusOK, map[string]string{"message": "Credit added successfully"})
 }
@@ -114,7 +180,7 @@ func GetUserBalance(c echo.Context) error {
 	}
 
 	var user models.User
-	if err := database.Db.Preload("Credits").First(&user, requestUserID).Error; err != nil {
+	if err := database.Db.First(&user, requestUserID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			logger.Logger.Error("User not found with ID: ", strconv.Itoa(requestUserID))
 			return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
@@ -124,16 +190,22 @@ func GetUserBalance(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
 	}
 
-	totalBalance := 0.0
-	for _, credit := range user.Credits {
-		totalBalance += credit.Amount
+	account, err := ledger.UserAccount(database.Db, user.ID)
+	if err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
 	}
 
-	logger.Logger.Infof("User ID %d has total balance of %v", requestUserID, totalBalance)
+	logger.Logger.Infof("User ID %d has total balance of %v", requestUserID, account.Balance)
 	return c.JSON(http.StatusOK, map[string]interface{}{
-		"user_id":       user.ID,
-		"user_name":     user.Name,
-		"total_balance": totalBalance,
+		"user_id":            user.ID,
+		"user_name":          user.Name,
+		"total_balance":      money.Format(account.Balance, money.Default),
+		"held_balance":       money.Format(account.Held, money.Default),
+		"available_balance":  money.Format(account.Available(), money.Default),
+		"overdraft_limit":    money.Format(account.OverdraftLimit, money.Default),
+		"remaining_headroom": money.Format(account.Headroom(), money.Default),
+		"currency":           money.Default.Code,
 	})
 }
 
@@ -162,6 +234,11 @@ func TransferCredit(c echo.Context) error {
 		return c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
 	}
 
+	if senderID == receiverID {
+		logger.Logger.Warnf("User ID %d attempted to transfer credit to themselves", senderID)
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": transfers.ErrSelfTransfer.Error()})
+	}
+
 	creditReq := new(models.CreditRequest)
 	if err := c.Bind(creditReq); err != nil {
 		logger.Logger.Error("Invalid input: ", err.Error())
@@ -169,22 +246,17 @@ func TransferCredit(c echo.Context) error {
 	}
 
 	if err := validation.ValidateStruct().Struct(creditReq); err != nil {
-		var errorMessage []string
-		for _, err := range err.(validator.ValidationErrors) {
-			errorMessage = append(errorMessage, fmt.Sprintf("Field %s failed validation: %s parameter: %s", err.Field(), err.Tag(), err.Param()))
-		}
+		return validationFailed(c, err)
+	}
 
-		logger.Logger.WithFields(logrus.Fields{
-			"details": errorMessage,
-		}).Error("Validation failed")
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"error":   "Validation failed",
-			"details": errorMessage,
-		})
+	amount, err := creditReq.MinorUnits()
+	if err != nil {
+		logger.Logger.Error("Invalid amount: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
 	var sender models.User
-	if err := database.Db.Preload("Credits").First(&sender, senderID).Error; err != nil {
+	if err := database.Db.First(&sender, senderID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			logger.Logger.Error("Sender not found with ID: ", strconv.Itoa(senderID))
 			return c.JSON(http.StatusNotFound, map[string]string{"error": "Sender not found"})
@@ -195,7 +267,7 @@ func TransferCredit(c echo.Context) error {
 	}
 
 	var receiver models.User
-	if err := database.Db.Preload("Credits").First(&receiver, receiverID).Error; err != nil {
+	if err := database.Db.First(&receiver, receiverID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			logger.Logger.Error("Receiver not found with ID: ", strconv.Itoa(receiverID))
 			return c.JSON(http.StatusNotFound, map[string]string{"error": "Receiver not found"})
@@ -205,43 +277,23 @@ func TransferCredit(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
 	}
 
-	totalBalance := 0.0
-	for _, credit := range sender.Credits {
-		totalBalance += credit.Amount
+	if refuseSender(c, &sender) || refuseReceiver(c, &receiver) {
+		return nil
 	}
 
-	if totalBalance < creditReq.Amount {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Insufficient balance"})
+	transferReq := &transfers.Request{
+		SenderID:   sender.ID,
+		ReceiverID: receiver.ID,
+		Amount:     amount,
+		Meta:       creditReq.EntryMetadata,
 	}
 
 	tx := database.Db.Begin()
 
-	transaction := models.Transaction{
-		UserID:          uint(senderID),
-		Amount:          -creditReq.Amount,
-		TransactionTime: time.Now().UTC(),
-		SenderID:        uintPointer(uint(senderID)),
-		ReceiverID:      uintPointer(uint(receiverID)),
-	}
-
-	receiverTransaction := models.Transaction{
-		UserID:          uint(receiverID),
-		Amount:          creditReq.Amount,
-		TransactionTime: time.Now().UTC(),
-		SenderID:        uintPointer(uint(senderID)),
-		ReceiverID:      uintPointer(uint(receiverID)),
-	}
-
-	if err := tx.Create(&transaction).Error; err != nil {
-		tx.Rollback()
-		logger.Logger.Error("Failed to add credit: ", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to add credit"})
-	}
-
-	if err := tx.Create(&receiverTransaction).Error; err != nil {
+	result, err := transfers.Execute(tx, transferReq, time.Now().UTC())
+	if err != nil {
 		tx.Rollback()
-		logger.Logger.Error("Failed to add credit: ", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to add credit"})
+		return transferFailed(c, transferReq, err)
 	}
 
 	if err := tx.Commit().Error; err != nil {
@@ -249,13 +301,49 @@ func TransferCredit(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
 	}
 
-	logger.Logger.Infof("Credit of %v transferred from User ID %d to User ID %d", creditReq.Amount, senderID, receiverID)
-	return c.JSON(http.StatusOK, map[string]string{"message": "Credit transferred successfully"})
+	logger.Logger.Infof("Credit of %v transferred from User ID %d to User ID %d with a fee of %s",
+		creditReq.Amount, senderID, receiverID, money.Format(result.Fee, money.Default))
+	return c.JSON(http.StatusOK, chargedResponse("Credit transferred successfully", result.Entry, result.FeeEntry, amount, result.Fee))
+}
+
+// transferFailed answers a transfer that transfers.Execute refused.
+func transferFailed(c echo.Context, req *transfers.Request, err error) error {
+	var riskErr *transfers.RiskError
+	var limitErr *velocity.LimitError
+	switch {
+	case errors.Is(err, transfers.ErrSelfTransfer):
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	case errors.As(err, &riskErr):
+		return riskRefused(c, riskErr, req)
+	case errors.Is(err, models.ErrUserInactive), errors.Is(err, ledger.ErrAccountClosed):
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	case errors.Is(err, ledger.ErrInsufficientBalance):
+		recordFailedAttempt(req.SenderID, models.EntryTypeTransfer, "insufficient_balance")
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Insufficient balance"})
+	case errors.As(err, &limitErr):
+		recordFailedAttempt(req.SenderID, models.EntryTypeTransfer, limitErr.Code)
+		logger.Logger.Warnf("Transfer by User ID %d rejected: %s", req.SenderID, limitErr.Code)
+		return c.JSON(http.StatusUnprocessableEntity, velocityRejected(limitErr))
+	}
+
+	logger.Logger.Error("Failed to transfer credit: ", err.Error())
+	return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to transfer credit"})
 }
 
 func GetAllUsersTotalBalance(c echo.Context) error {
-	var users []models.User
-	if err := database.Db.Preload("Credits").Find(&users).Error; err != nil {
+	var users []struct {
+		ID             uint
+		Name           string
+		Balance        int64
+		Held           int64
+		OverdraftLimit int64
+	}
+	if err := database.Db.Table("users").
+		Select("users.id, users.name, COALESCE(accounts.balance, 0) AS balance, " +
+			"COALESCE(accounts.held, 0) AS held, COALESCE(accounts.overdraft_limit, 0) AS overdraft_limit").
+		Joins("LEFT JOIN accounts ON accounts.user_id = users.id").
+		Order("users.id").
+		Scan(&users).Error; err != nil {
 		logger.Logger.Error(fmt.Sprintf("Failed to fetch users: %s", err.Error()))
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch users"})
 	}
@@ -267,16 +355,16 @@ func GetAllUsersTotalBalance(c echo.Context) error {
 
 	var userWithBalances []map[string]interface{}
 	for _, user := range users {
-		totalBalance := 0.0
-
-		for _, credit := range user.Credits {
-			totalBalance += credit.Amount
-		}
+		account := models.Account{Balance: user.Balance, Held: user.Held, OverdraftLimit: user.OverdraftLimit}
 
 		userWithBalances = append(userWithBalances, map[string]interface{}{
-			"user_id":       user.ID,
-			"user_name":     user.Name,
-			"total_balance": totalBalance,
+			"user_id":            user.ID,
+			"user_name":          user.Name,
+			"total_balance":      money.Format(account.Balance, money.Default),
+			"available_balance":  money.Format(account.Available(), money.Default),
+			"overdraft_limit":    money.Format(account.OverdraftLimit, money.Default),
+			"remaining_headroom": money.Format(account.Headroom(), money.Default),
+			"currency":           money.Default.Code,
 		})
 	}
 
@@ -310,23 +398,17 @@ func UserWithdrawsCredit(c echo.Context) error {
 	}
 
 	if err := validation.ValidateStruct().Struct(creditReq); err != nil {
-		var errorMessage []string
-
-		for _, err := range err.(validator.ValidationErrors) {
-			errorMessage = append(errorMessage, fmt.Sprintf("Field %s failed validation: %s parameter: %s", err.Field(), err.Tag(), err.Param()))
-		}
+		return validationFailed(c, err)
+	}
 
-		logger.Logger.WithFields(logrus.Fields{
-			"details": errorMessage,
-		}).Error("Validation failed")
-		return c.JSON(http.StatusBadRequest, map[string]interface{}{
-			"error":   "Validation failed",
-			"details": errorMessage,
-		})
+	amount, err := creditReq.MinorUnits()
+	if err != nil {
+		logger.Logger.Error("Invalid amount: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
 	var user models.User
-	if err := database.Db.Preload("Credits").First(&user, userID).Error; err != nil {
+	if err := database.Db.First(&user, userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			logger.Logger.Error("User not found with ID: ", strconv.Itoa(userID))
 			return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
@@ -336,27 +418,61 @@ func UserWithdrawsCredit(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
 	}
 
-	totalBalance := 0.0
-	for _, credit := range user.Credits {
-		totalBalance += credit.Amount
+	if refuseSender(c, &user) {
+		return nil
 	}
 
-	if totalBalance < creditReq.Amount {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Insufficient balance"})
+	fee, err := fees.Quote(database.Db, models.FeeOperationWithdrawal, user.Role(), amount)
+	if err != nil {
+		logger.Logger.Error("Failed to compute fee: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to compute fee"})
 	}
 
 	tx := database.Db.Begin()
 
-	transaction := &models.Transaction{
-		UserID:          uint(userID),
-		Amount:          -creditReq.Amount,
-		TransactionTime: time.Now().UTC(),
+	// The withdrawal and its fee lock their accounts together, in the
+	// order transfers lock theirs, so the two cannot deadlock.
+	systemCodes := []string{models.SystemAccountWithdrawals}
+	if fee > 0 {
+		systemCodes = append(systemCodes, models.SystemAccountFeeRevenue)
 	}
 
-	if err := tx.Create(transaction).Error; err != nil {
+	// The status is checked again now that it cannot change before commit.
+	var entry *models.JournalEntry
+	err = ledger.CheckStatus(tx, uint(userID), 0)
+	if err == nil {
+		_, err = ledger.LockUserAccounts(tx, []uint{uint(userID)}, systemCodes...)
+	}
+	if err == nil {
+		entry, err = ledger.Withdraw(tx, uint(userID), amount, creditReq.EntryMetadata)
+	}
+	if err == nil {
+		err = velocity.Check(tx, uint(userID), user.Role(), models.EntryTypeWithdrawal, []int64{amount}, time.Now().UTC())
+	}
+	var feeEntry *models.JournalEntry
+	if err == nil {
+		feeEntry, err = ledger.ChargeFee(tx, uint(userID), fee, entry)
+	}
+	if err != nil {
 		tx.Rollback()
-		logger.Logger.Error("Failed to add credit: ", err.Error())
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to add credit"})
+		if errors.Is(err, models.ErrUserInactive) || errors.Is(err, ledger.ErrAccountClosed) {
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+
+		if errors.Is(err, ledger.ErrInsufficientBalance) {
+			recordFailedAttempt(user.ID, models.EntryTypeWithdrawal, "insufficient_balance")
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Insufficient balance"})
+		}
+
+		var limitErr *velocity.LimitError
+		if errors.As(err, &limitErr) {
+			recordFailedAttempt(user.ID, models.EntryTypeWithdrawal, limitErr.Code)
+			logger.Logger.Warnf("Withdrawal by User ID %d rejected: %s", userID, limitErr.Code)
+			return c.JSON(http.StatusUnprocessableEntity, velocityRejected(limitErr))
+		}
+
+		logger.Logger.Error("Failed to withdraw credit: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to withdraw credit"})
 	}
 
 	if err := tx.Commit().Error; err != nil {
@@ -364,8 +480,9 @@ func UserWithdrawsCredit(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
 	}
 
-	logger.Logger.Infof("Credit of %v withdrawn from User ID %d", creditReq.Amount, userID)
-	return c.JSON(http.StatusOK, map[string]string{"message": "Credit withdrawn successfully"})
+	logger.Logger.Infof("Credit of %v withdrawn from User ID %d with a fee of %s",
+		creditReq.Amount, userID, money.Format(fee, money.Default))
+	return c.JSON(http.StatusOK, chargedResponse("Credit withdrawn successfully", entry, feeEntry, amount, fee))
 }
 
 func RegisterUser(c echo.Context) error {
@@ -402,6 +519,12 @@ func RegisterUser(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create user"})
 	}
 
+	// Open the ledger account up front so concurrent first postings never race to create it.
+	if _, err := ledger.UserAccount(database.Db, newUser.ID); err != nil {
+		logger.Logger.Error(fmt.Sprintf("Failed to open account: %s", err.Error()))
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create user"})
+	}
+
 	token, err := auth.GenerateToken(newUser.ID, "user")
 	if err != nil {
 		logger.Logger.Error("Error generating token")
@@ -439,7 +562,7 @@ func GetUserBalanceAtTime(c echo.Context) error {
 	}
 
 	var user models.User
-	if err := database.Db.Preload("Credits").First(&user, userID).Error; err != nil {
+	if err := database.Db.First(&user, userID).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			logger.Logger.Error("User not found with ID: ", strconv.Itoa(userID))
 			return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
@@ -455,18 +578,24 @@ func GetUserBalanceAtTime(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid time format"})
 	}
 
-	totalBalance := 0.0
-	for _, credit := range user.Credits {
-		if credit.TransactionTime.Before(transactionTime) {
-			totalBalance += credit.Amount
-		}
+	account, err := ledger.UserAccount(database.Db, user.ID)
+	if err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	totalBalance, err := ledger.BalanceAt(database.Db, account.ID, transactionTime)
+	if err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
 	}
 
 	logger.Logger.Infof("User ID %d has total balance of %v at time %v", userID, totalBalance, transactionTime)
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"user_id":       user.ID,
 		"user_name":     user.Name,
-		"total_balance": totalBalance,
+		"total_balance": money.Format(totalBalance, money.Default),
+		"currency":      money.Default.Code,
 	})
 }
 
@@ -484,29 +613,34 @@ func LoginUser(c echo.Context) error {
 	var user models.User
 	if err := database.Db.Where("name = ?", loginPayload.Username).First(&user).Error; err != nil {
 		logger.Logger.Warn("Invalid username or password")
+		recordLogin(c, nil, loginPayload.Username, http.StatusUnauthorized)
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid username or password"})
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(loginPayload.Password)); err != nil {
 		logger.Logger.Warn("Invalid username or password")
+		recordLogin(c, &user, loginPayload.Username, http.StatusUnauthorized)
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid username or password"})
 	}
 
-	role := "user"
-	if user.IsAdmin {
-		role = "admin"
+	if user.Status == models.UserStatusSuspended {
+		logger.Logger.Warnf("Suspended User ID %d attempted to log in", user.ID)
+		recordLogin(c, &user, user.Name, http.StatusForbidden)
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Account suspended"})
 	}
 
-	token, err := auth.GenerateToken(user.ID, role)
+	token, err := auth.GenerateToken(user.ID, user.Role())
 	if err != nil {
 		logger.Logger.Error("Error generating token")
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Error generating token"})
 	}
 
+	recordLogin(c, &user, user.Name, http.StatusOK)
+
 	logger.Logger.WithFields(map[string]interface{}{
 		"userID":   user.ID,
 		"username": user.Name,
-		"role":     role,
+		"role":     user.Role(),
 	}).Info("User logged in successfully")
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
@@ -514,7 +648,3 @@ func LoginUser(c echo.Context) error {
 		"token":   token,
 	})
 }
-
-func uintPointer(val uint) *uint {
-	return &val
-}
@@ -0,0 +1,321 @@
+package handlers
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"ledger-app/internal/audit"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/money"
+	"ledger-app/internal/validation"
+	"ledger-app/internal/velocity"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func GetVelocityLimits(c echo.Context) error {
+	var limits []models.VelocityLimit
+	if err := database.Db.Order("operation").Order("user_id").Order("role").Order("time_window").Find(&limits).Error; err != nil {
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	response := make([]map[string]interface{}, 0, len(limits))
+	for i := range limits {
+		response = append(response, velocityLimitResponse(&limits[i]))
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func SetVelocityLimits(c echo.Context) error {
+	operation, role, ok := velocityLimitParams(c)
+	if !ok {
+		return nil
+	}
+
+	return setVelocityLimits(c, operation, role, 0)
+}
+
+func DeleteVelocityLimits(c echo.Context) error {
+	operation, role, ok := velocityLimitParams(c)
+	if !ok {
+		return nil
+	}
+
+	return deleteVelocityLimits(c, operation, role, 0)
+}
+
+func SetUserVelocityLimits(c echo.Context) error {
+	operation, user, ok := userVelocityLimitParams(c)
+	if !ok {
+		return nil
+	}
+
+	return setVelocityLimits(c, operation, "", user.ID)
+}
+
+func DeleteUserVelocityLimits(c echo.Context) error {
+	operation, user, ok := userVelocityLimitParams(c)
+	if !ok {
+		return nil
+	}
+
+	return deleteVelocityLimits(c, operation, "", user.ID)
+}
+
+// GetUserVelocityLimits shows the user the limits that apply to them and
+// how much of each is left.
+func GetUserVelocityLimits(c echo.Context) error {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	if !canAccessUser(c, userID) {
+		logger.Logger.Warnf("User ID %v attempted to access velocity limits of User ID %d", c.Get("userID"), userID)
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Access denied"})
+	}
+
+	var user models.User
+	if err := database.Db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			logger.Logger.Error("User not found with ID: ", strconv.Itoa(userID))
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+		}
+
+		logger.Logger.Error("Database error: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	now := time.Now().UTC()
+	response := map[string]interface{}{"user_id": user.ID}
+
+	for _, operation := range []string{models.EntryTypeTransfer, models.EntryTypeWithdrawal} {
+		allowances, err := velocity.Allowances(database.Db, user.ID, user.Role(), operation, now)
+		if err != nil {
+			logger.Logger.Error("Failed to compute velocity allowances: ", err.Error())
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to compute velocity allowances"})
+		}
+
+		windows := make([]map[string]interface{}, 0, len(allowances))
+		for _, allowance := range allowances {
+			windows = append(windows, allowanceResponse(allowance))
+		}
+		response[operation] = windows
+	}
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func setVelocityLimits(c echo.Context, operation, role string, userID uint) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
+	limitReq := new(models.VelocityLimitRequest)
+	if err := c.Bind(limitReq); err != nil {
+		logger.Logger.Error("Invalid input: ", err.Error())
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+	}
+
+	if err := validation.ValidateStruct().Struct(limitReq); err != nil {
+		return validationFailed(c, err)
+	}
+
+	limits := make([]models.VelocityLimit, 0, len(limitReq.Windows))
+	for _, windowReq := range limitReq.Windows {
+		if windowReq.Window == models.VelocityWindowTransaction && windowReq.MaxCount != 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "MaxCount does not apply to the transaction window"})
+		}
+
+		maxAmount, err := optionalMinorUnits(windowReq.MaxAmount)
+		if err != nil || maxAmount < 0 {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "MaxAmount must be a non-negative amount"})
+		}
+
+		limits = append(limits, models.VelocityLimit{
+			Window:    windowReq.Window,
+			MaxAmount: maxAmount,
+			MaxCount:  windowReq.MaxCount,
+			UpdatedBy: adminUserID,
+		})
+	}
+
+	tx := database.Db.Begin()
+
+	if err := velocity.SetLimits(tx, operation, role, userID, limits); err != nil {
+		tx.Rollback()
+		if errors.Is(err, velocity.ErrDuplicateWindow) {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+
+		logger.Logger.Error("Failed to set velocity limits: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to set velocity limits"})
+	}
+
+	response := make([]map[string]interface{}, 0, len(limits))
+	for i := range limits {
+		response = append(response, velocityLimitResponse(&limits[i]))
+	}
+
+	change := &audit.Change{After: response}
+	if userID != 0 {
+		change = &audit.Change{
+			Action:     "velocity_limit_override",
+			TargetType: "user",
+			TargetID:   userID,
+			After:      response,
+		}
+	}
+
+	if err := recordAudit(c, tx, http.StatusOK, change); err != nil {
+		tx.Rollback()
+		logger.Logger.Error("Failed to record audit event: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record audit event"})
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		logger.Logger.Error("Failed to commit transaction: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"adminID":   adminUserID,
+		"operation": operation,
+		"role":      role,
+		"userID":    userID,
+		"windows":   len(limits),
+	}).Info("Velocity limits set")
+
+	return c.JSON(http.StatusOK, response)
+}
+
+func deleteVelocityLimits(c echo.Context, operation, role string, userID uint) error {
+	adminUserID := uint(c.Get("userID").(float64))
+
+	if err := velocity.DeleteLimits(database.Db, operation, role, userID); err != nil {
+		logger.Logger.Error("Failed to delete velocity limits: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete velocity limits"})
+	}
+
+	logger.Logger.WithFields(logrus.Fields{
+		"adminID":   adminUserID,
+		"operation": operation,
+		"role":      role,
+		"userID":    userID,
+	}).Info("Velocity limits removed")
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Velocity limits deleted successfully"})
+}
+
+// velocityLimitParams reads the operation and role from the path. When
+// they are invalid it writes the error response and returns false.
+func velocityLimitParams(c echo.Context) (string, string, bool) {
+	operation, ok := velocityOperation(c)
+	if !ok {
+		return "", "", false
+	}
+
+	role := c.Param("role")
+	if role != "user" && role != "admin" && role != models.VelocityRoleAny {
+		_ = c.JSON(http.StatusBadRequest, map[string]string{"error": "Role must be user, admin or any"})
+		return "", "", false
+	}
+
+	return operation, role, true
+}
+
+func userVelocityLimitParams(c echo.Context) (string, *models.User, bool) {
+	operation, ok := velocityOperation(c)
+	if !ok {
+		return "", nil, false
+	}
+
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		logger.Logger.Error("Failed to convert user ID: ", err.Error())
+		_ = c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+		return "", nil, false
+	}
+
+	var user models.User
+	if err := database.Db.First(&user, userID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			_ = c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+			return "", nil, false
+		}
+
+		logger.Logger.Error("Database error: ", err.Error())
+		_ = c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		return "", nil, false
+	}
+
+	return operation, &user, true
+}
+
+func velocityOperation(c echo.Context) (string, bool) {
+	operation := c.Param("operation")
+	if operation != models.EntryTypeTransfer && operation != models.EntryTypeWithdrawal {
+		_ = c.JSON(http.StatusBadRequest, map[string]string{"error": "Operation must be transfer or withdrawal"})
+		return "", false
+	}
+
+	return operation, true
+}
+
+// velocityRejected answers an operation that would exceed a limit, with
+// the reason code and what was left of the allowance.
+func velocityRejected(limitErr *velocity.LimitError) map[string]interface{} {
+	response := allowanceResponse(limitErr.Allowance)
+	response["error"] = "Velocity limit exceeded"
+	response["code"] = limitErr.Code
+	return response
+}
+
+func allowanceResponse(allowance velocity.Allowance) map[string]interface{} {
+	response := map[string]interface{}{
+		"window":           allowance.Window,
+		"max_amount":       nil,
+		"max_count":        nil,
+		"remaining_amount": nil,
+		"remaining_count":  nil,
+	}
+
+	if allowance.MaxAmount > 0 {
+		response["max_amount"] = money.Format(allowance.MaxAmount, money.Default)
+		response["remaining_amount"] = money.Format(*allowance.RemainingAmount, money.Default)
+	}
+	if allowance.RemainingCount != nil {
+		response["max_count"] = allowance.MaxCount
+		response["remaining_count"] = *allowance.RemainingCount
+	}
+	if allowance.Window != models.VelocityWindowTransaction {
+		response["used_amount"] = money.Format(allowance.UsedAmount, money.Default)
+		response["used_count"] = allowance.UsedCount
+	}
+
+	return response
+}
+
+func velocityLimitResponse(limit *models.VelocityLimit) map[string]interface{} {
+	response := map[string]interface{}{
+		"operation":  limit.Operation,
+		"window":     limit.Window,
+		"max_amount": money.Format(limit.MaxAmount, money.Default),
+		"max_count":  limit.MaxCount,
+		"updated_by": limit.UpdatedBy,
+		"updated_at": limit.UpdatedAt,
+	}
+
+	if limit.UserID != 0 {
+		response["user_id"] = limit.UserID
+	} else {
+		response["role"] = limit.Role
+	}
+
+	return response
+}
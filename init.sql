@@ -4,6 +4,7 @@ CREATE TABLE IF NOT EXISTS users (
                                      name VARCHAR(100) NOT NULL,
     password_hash VARCHAR(255) NOT NULL,
     is_admin BOOLEAN DEFAULT FALSE,
+    status VARCHAR(16) NOT NULL DEFAULT 'active',
     created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
     );
 
@@ -11,21 +12,89 @@ CREATE TABLE IF NOT EXISTS users (
 INSERT INTO users (name, password_hash, is_admin) VALUES ('John Doe', 'hashed_password_123', FALSE);
 INSERT INTO users (name, password_hash, is_admin) VALUES ('Jane Smith', 'hashed_password_456', TRUE);
 
+-- Create accounts table
+CREATE TABLE IF NOT EXISTS accounts (
+                                        id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                                        user_id BIGINT UNSIGNED,
+                                        code VARCHAR(64) NOT NULL,
+    type VARCHAR(16) NOT NULL,
+    class VARCHAR(32) NOT NULL DEFAULT 'standard',
+    currency CHAR(3) NOT NULL,
+    balance BIGINT NOT NULL DEFAULT 0,
+    held BIGINT NOT NULL DEFAULT 0,
+    overdraft_limit BIGINT NOT NULL DEFAULT 0,
+    version INT UNSIGNED NOT NULL DEFAULT 0,
+    chain_hash CHAR(64) NOT NULL DEFAULT '',
+    chain_start_id BIGINT UNSIGNED NULL,
+    closed_at TIMESTAMP NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_accounts_user FOREIGN KEY (user_id) REFERENCES users(id),
+    UNIQUE INDEX idx_accounts_user_id (user_id),
+    UNIQUE INDEX idx_accounts_code (code)
+    );
+
+-- Create journal entries table
+CREATE TABLE IF NOT EXISTS journal_entries (
+                                               id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                                               type VARCHAR(32) NOT NULL,
+    posted_at TIMESTAMP NOT NULL,
+    reversal_of_id BIGINT UNSIGNED,
+    fee_for_id BIGINT UNSIGNED,
+    reversed_amount BIGINT NOT NULL DEFAULT 0,
+    description VARCHAR(255),
+    reference VARCHAR(64),
+    category VARCHAR(32),
+    import_key VARCHAR(64),
+    UNIQUE INDEX idx_journal_entries_import_key (import_key),
+    INDEX idx_journal_entries_reversal_of_id (reversal_of_id),
+    INDEX idx_journal_entries_fee_for_id (fee_for_id),
+    INDEX idx_journal_entries_reference (reference),
+    INDEX idx_journal_entries_category (category),
+    INDEX idx_journal_entries_type (type),
+    INDEX idx_journal_entries_posted_at (posted_at)
+    );
+
+-- Create entry tags table
+CREATE TABLE IF NOT EXISTS entry_tags (
+                                          id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+                                          journal_entry_id BIGINT UNSIGNED NOT NULL,
+                                          tag VARCHAR(32) NOT NULL,
+    CONSTRAINT fk_journal_entries_tags FOREIGN KEY (journal_entry_id) REFERENCES journal_entries(id),
+    UNIQUE INDEX idx_entry_tags_entry_tag (journal_entry_id, tag),
+    INDEX idx_entry_tags_tag (tag)
+    );
+
 -- Create transactions table
 CREATE TABLE IF NOT EXISTS transactions (
                                             id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
-                                            user_id BIGINT UNSIGNED NOT NULL,
-                                            amount DOUBLE NOT NULL,
+                                            journal_entry_id BIGINT UNSIGNED NOT NULL,
+                                            account_id BIGINT UNSIGNED NOT NULL,
+                                            user_id BIGINT UNSIGNED,
+                                            amount BIGINT NOT NULL,
                                             transaction_time TIMESTAMP NOT NULL,
                                             sender_id BIGINT UNSIGNED,
                                             receiver_id BIGINT UNSIGNED,
-                                            CONSTRAINT fk_users_transactions FOREIGN KEY (user_id) REFERENCES users(id),
+                                            prev_hash CHAR(64) NOT NULL DEFAULT '',
+                                            hash CHAR(64) NOT NULL DEFAULT '',
+                                            CONSTRAINT fk_journal_entries_postings FOREIGN KEY (journal_entry_id) REFERENCES journal_entries(id),
+    CONSTRAINT fk_accounts_transactions FOREIGN KEY (account_id) REFERENCES accounts(id),
+    CONSTRAINT fk_users_transactions FOREIGN KEY (user_id) REFERENCES users(id),
     CONSTRAINT fk_transactions_sender FOREIGN KEY (sender_id) REFERENCES users(id),
     CONSTRAINT fk_transactions_receiver FOREIGN KEY (receiver_id) REFERENCES users(id),
+    INDEX idx_transactions_journal_entry_id (journal_entry_id),
+    INDEX idx_transactions_account_id (account_id),
+    INDEX idx_transactions_transaction_time (transaction_time),
+    INDEX idx_transactions_user_id (user_id),
     INDEX idx_transactions_sender_id (sender_id),
     INDEX idx_transactions_receiver_id (receiver_id)
     );
 
--- Example of inserting a transaction
-INSERT INTO transactions (user_id, amount, transaction_time, sender_id, receiver_id)
-VALUES (1, 100.50, CURRENT_TIMESTAMP, NULL, 2);
\ No newline at end of file
+-- Example of a balanced journal entry crediting user 1 with 100.50 USD (amounts are in minor units)
+INSERT INTO accounts (user_id, code, type, currency, balance, version) VALUES (NULL, 'system:issuance', 'system', 'USD', -10050, 1);
+INSERT INTO accounts (user_id, code, type, currency, balance, version) VALUES (1, 'user:1', 'user', 'USD', 10050, 1);
+
+INSERT INTO journal_entries (type, posted_at) VALUES ('credit', CURRENT_TIMESTAMP);
+
+INSERT INTO transactions (journal_entry_id, account_id, user_id, amount, transaction_time, sender_id, receiver_id)
+VALUES (1, 1, NULL, -10050, CURRENT_TIMESTAMP, NULL, NULL),
+       (1, 2, 1, 10050, CURRENT_TIMESTAMP, NULL, NULL);
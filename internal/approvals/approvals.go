@@ -0,0 +1,400 @@
+package approvals
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/internal/importer"
+	"ledger-app/internal/journal"
+	"ledger-app/internal/ledger"
+	"ledger-app/models"
+	"time"
+)
+
+var (
+	ErrNotFound       = errors.New("approval request not found")
+	ErrNotPending     = errors.New("approval request is no longer pending")
+	ErrExpired        = errors.New("approval request has expired")
+	ErrSelfApproval   = errors.New("approval request must be decided by a different admin")
+	ErrAlreadyPending = errors.New("an identical approval request is already pending")
+	ErrImportFailed   = errors.New("import no longer applies cleanly")
+)
+
+var (
+	// creditThreshold is the largest credit, overdraft limit or import row
+	// an admin may apply alone; importThreshold the largest volume a whole
+	// import may move. Zero disables the check.
+	creditThreshold int64
+	importThreshold int64
+	ttl             = 72 * time.Hour
+)
+
+func Configure(credit, imports int64, requestTTL time.Duration) {
+	creditThreshold = credit
+	importThreshold = imports
+	ttl = requestTTL
+}
+
+func CreditNeedsApproval(amount int64) bool {
+	return creditThreshold > 0 && amount > creditThreshold
+}
+
+// ImportNeedsApproval applies the credit threshold to an import row in
+// either direction; a large debit rewrites history as much as a credit.
+func ImportNeedsApproval(amount int64) bool {
+	if amount < 0 {
+		amount = -amount
+	}
+
+	return CreditNeedsApproval(amount)
+}
+
+// OverdraftNeedsApproval reports whether the overdraft limit lets the user
+// spend more than an admin may credit alone.
+func OverdraftNeedsApproval(limit int64) bool {
+	return CreditNeedsApproval(limit)
+}
+
+// ImportVolumeNeedsApproval reports whether a whole import moves more than
+// an admin may import alone, however small its rows are.
+func ImportVolumeNeedsApproval(volume int64) bool {
+	return importThreshold > 0 && volume > importThreshold
+}
+
+// PromotionNeedsApproval reports whether the role change grants admin
+// rights; every promotion goes through approval.
+func PromotionNeedsApproval(user *models.User, role string) bool {
+	return role == "admin" && !user.IsAdmin
+}
+
+type rolePayload struct {
+	Role string `json:"role"`
+}
+
+type overdraftPayload struct {
+	Reason string `json:"reason"`
+}
+
+type journalPayload struct {
+	Filename string `json:"filename"`
+	Content  string `json:"content"`
+}
+
+func SubmitCredit(db *gorm.DB, userID uint, amount int64, meta models.EntryMetadata, requestedBy uint, now time.Time) (*models.ApprovalRequest, error) {
+	payload, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &models.ApprovalRequest{
+		Operation:    models.ApprovalOperationCredit,
+		TargetUserID: userID,
+		Amount:       amount,
+		Payload:      string(payload),
+		Status:       models.ApprovalStatusPending,
+		RequestedBy:  requestedBy,
+		ExpiresAt:    now.Add(ttl),
+	}
+
+	return request, db.Create(request).Error
+}
+
+func SubmitRoleChange(db *gorm.DB, userID uint, role string, requestedBy uint, now time.Time) (*models.ApprovalRequest, error) {
+	payload, err := json.Marshal(rolePayload{Role: role})
+	if err != nil {
+		return nil, err
+	}
+
+	request := &models.ApprovalRequest{
+		Operation:    models.ApprovalOperationRoleChange,
+		TargetUserID: userID,
+		Payload:      string(payload),
+		Status:       models.ApprovalStatusPending,
+		RequestedBy:  requestedBy,
+		ExpiresAt:    now.Add(ttl),
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var pending int64
+		if err := tx.Model(&models.ApprovalRequest{}).
+			Where("operation = ? AND target_user_id = ? AND status = ? AND expires_at > ?",
+				models.ApprovalOperationRoleChange, userID, models.ApprovalStatusPending, now).
+			Count(&pending).Error; err != nil {
+			return err
+		}
+		if pending > 0 {
+			return ErrAlreadyPending
+		}
+
+		return tx.Create(request).Error
+	})
+
+	return request, err
+}
+
+// SubmitImportRow holds back an import row. A row with the same reference
+// already waiting is not submitted twice.
+func SubmitImportRow(tx *gorm.DB, row importer.Row, requestedBy uint, now time.Time) (*models.ApprovalRequest, error) {
+	payload, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []models.ApprovalRequest
+	if err := tx.Where("operation = ? AND target_user_id = ? AND status = ? AND expires_at > ?",
+		models.ApprovalOperationImport, row.UserID, models.ApprovalStatusPending, now).
+		Find(&pending).Error; err != nil {
+		return nil, err
+	}
+	for i := range pending {
+		if ImportRowPayload(&pending[i]).Reference == row.Reference {
+			return nil, ErrAlreadyPending
+		}
+	}
+
+	request := &models.ApprovalRequest{
+		Operation:    models.ApprovalOperationImport,
+		TargetUserID: row.UserID,
+		Amount:       row.Amount,
+		Payload:      string(payload),
+		Status:       models.ApprovalStatusPending,
+		RequestedBy:  requestedBy,
+		ExpiresAt:    now.Add(ttl),
+	}
+
+	return request, tx.Create(request).Error
+}
+
+func SubmitOverdraftLimit(db *gorm.DB, userID uint, limit int64, reason string, requestedBy uint, now time.Time) (*models.ApprovalRequest, error) {
+	payload, err := json.Marshal(overdraftPayload{Reason: reason})
+	if err != nil {
+		return nil, err
+	}
+
+	request := &models.ApprovalRequest{
+		Operation:    models.ApprovalOperationOverdraft,
+		TargetUserID: userID,
+		Amount:       limit,
+		Payload:      string(payload),
+		Status:       models.ApprovalStatusPending,
+		RequestedBy:  requestedBy,
+		ExpiresAt:    now.Add(ttl),
+	}
+
+	return request, db.Create(request).Error
+}
+
+// SubmitImportFile holds back a whole CSV import whose volume needs
+// approval. The rows are stored as parsed and imported together once
+// approved.
+func SubmitImportFile(db *gorm.DB, rows []importer.Row, volume int64, requestedBy uint, now time.Time) (*models.ApprovalRequest, error) {
+	payload, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	request := &models.ApprovalRequest{
+		Operation:   models.ApprovalOperationImportFile,
+		Amount:      volume,
+		Payload:     string(payload),
+		Status:      models.ApprovalStatusPending,
+		RequestedBy: requestedBy,
+		ExpiresAt:   now.Add(ttl),
+	}
+
+	return request, db.Create(request).Error
+}
+
+// SubmitJournalImport holds back a journal import whose volume needs
+// approval. The file is stored as uploaded and imported once approved.
+func SubmitJournalImport(db *gorm.DB, filename string, content []byte, volume int64, requestedBy uint, now time.Time) (*models.ApprovalRequest, error) {
+	payload, err := json.Marshal(journalPayload{Filename: filename, Content: string(content)})
+	if err != nil {
+		return nil, err
+	}
+
+	request := &models.ApprovalRequest{
+		Operation:   models.ApprovalOperationJournal,
+		Amount:      volume,
+		Payload:     string(payload),
+		Status:      models.ApprovalStatusPending,
+		RequestedBy: requestedBy,
+		ExpiresAt:   now.Add(ttl),
+	}
+
+	return request, db.Create(request).Error
+}
+
+// Approve applies the pending operation in tx and marks the request
+// approved.
+func Approve(tx *gorm.DB, id, adminID uint, reason string, now time.Time) (*models.ApprovalRequest, error) {
+	request, err := lockPending(tx, id, adminID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	switch request.Operation {
+	case models.ApprovalOperationCredit:
+		if err := ledger.CheckStatus(tx, 0, request.TargetUserID); err != nil {
+			return nil, err
+		}
+
+		var meta models.EntryMetadata
+		if err := json.Unmarshal([]byte(request.Payload), &meta); err != nil {
+			return nil, err
+		}
+
+		entry, err := ledger.Credit(tx, request.TargetUserID, request.Amount, meta)
+		if err != nil {
+			return nil, err
+		}
+		request.ResultEntryID = &entry.ID
+
+	case models.ApprovalOperationImport:
+		entry, err := importer.PostRow(tx, ImportRowPayload(request), now)
+		if err != nil {
+			return nil, err
+		}
+		request.ResultEntryID = &entry.ID
+
+	case models.ApprovalOperationImportFile:
+		var rows []importer.Row
+		if err := json.Unmarshal([]byte(request.Payload), &rows); err != nil {
+			return nil, err
+		}
+
+		result, err := importer.Import(tx, rows, nil, false, now, nil)
+		if err != nil {
+			return nil, err
+		}
+		if !result.Committed {
+			first := result.Errors[0]
+			return nil, fmt.Errorf("%w: line %d: %s", ErrImportFailed, first.Line, first.Error)
+		}
+
+	case models.ApprovalOperationJournal:
+		result, err := journal.Import(tx, bytes.NewReader([]byte(journalFile(request).Content)))
+		if err != nil {
+			return nil, err
+		}
+		if !result.Committed {
+			first := result.Errors[0]
+			return nil, fmt.Errorf("%w: line %d: %s", ErrImportFailed, first.Line, first.Error)
+		}
+
+	case models.ApprovalOperationOverdraft:
+		var payload overdraftPayload
+		if err := json.Unmarshal([]byte(request.Payload), &payload); err != nil {
+			return nil, err
+		}
+
+		if _, err := ledger.SetOverdraftLimit(tx, request.TargetUserID, request.Amount, request.RequestedBy, payload.Reason); err != nil {
+			return nil, err
+		}
+
+	case models.ApprovalOperationRoleChange:
+		var payload rolePayload
+		if err := json.Unmarshal([]byte(request.Payload), &payload); err != nil {
+			return nil, err
+		}
+
+		if err := tx.Model(&models.User{}).
+			Where("id = ?", request.TargetUserID).
+			Update("is_admin", payload.Role == "admin").Error; err != nil {
+			return nil, err
+		}
+	}
+
+	return request, decide(tx, request, models.ApprovalStatusApproved, adminID, reason, now)
+}
+
+func Reject(tx *gorm.DB, id, adminID uint, reason string, now time.Time) (*models.ApprovalRequest, error) {
+	request, err := lockPending(tx, id, adminID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	return request, decide(tx, request, models.ApprovalStatusRejected, adminID, reason, now)
+}
+
+// Expire marks pending requests past their expiry and returns how many
+// were expired.
+func Expire(db *gorm.DB, now time.Time) (int64, error) {
+	result := db.Model(&models.ApprovalRequest{}).
+		Where("status = ? AND expires_at <= ?", models.ApprovalStatusPending, now).
+		Update("status", models.ApprovalStatusExpired)
+	return result.RowsAffected, result.Error
+}
+
+// RolePayload returns the role a role change request grants.
+func RolePayload(request *models.ApprovalRequest) string {
+	var payload rolePayload
+	_ = json.Unmarshal([]byte(request.Payload), &payload)
+	return payload.Role
+}
+
+// ImportRowPayload returns the row an import request posts.
+func ImportRowPayload(request *models.ApprovalRequest) importer.Row {
+	var row importer.Row
+	_ = json.Unmarshal([]byte(request.Payload), &row)
+	return row
+}
+
+// OverdraftReason returns the reason an overdraft limit request gives.
+func OverdraftReason(request *models.ApprovalRequest) string {
+	var payload overdraftPayload
+	_ = json.Unmarshal([]byte(request.Payload), &payload)
+	return payload.Reason
+}
+
+// ImportFileRows returns the rows a CSV import request posts.
+func ImportFileRows(request *models.ApprovalRequest) []importer.Row {
+	var rows []importer.Row
+	_ = json.Unmarshal([]byte(request.Payload), &rows)
+	return rows
+}
+
+// JournalFilename returns the name of the file a journal import request
+// posts.
+func JournalFilename(request *models.ApprovalRequest) string {
+	return journalFile(request).Filename
+}
+
+func journalFile(request *models.ApprovalRequest) journalPayload {
+	var payload journalPayload
+	_ = json.Unmarshal([]byte(request.Payload), &payload)
+	return payload
+}
+
+func lockPending(tx *gorm.DB, id, adminID uint, now time.Time) (*models.ApprovalRequest, error) {
+	var request models.ApprovalRequest
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&request, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+
+	switch {
+	case request.Status != models.ApprovalStatusPending:
+		return nil, ErrNotPending
+	case !request.ExpiresAt.After(now):
+		return nil, ErrExpired
+	case request.RequestedBy == adminID:
+		return nil, ErrSelfApproval
+	}
+
+	return &request, nil
+}
+
+func decide(tx *gorm.DB, request *models.ApprovalRequest, status string, adminID uint, reason string, now time.Time) error {
+	request.Status = status
+	request.DecidedBy = &adminID
+	request.DecisionReason = reason
+	request.DecidedAt = &now
+
+	return tx.Save(request).Error
+}
@@ -0,0 +1,251 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"hash"
+	"ledger-app/models"
+	"strconv"
+	"time"
+)
+
+var errChainBroken = errors.New("audit chain broken")
+
+// chainKey keys the event hashes when set; it is the ledger chain key.
+var chainKey []byte
+
+func SetChainKey(key string) {
+	chainKey = []byte(key)
+}
+
+// Change is the before and after state an operation attaches to its
+// event. Either side may be nil.
+type Change struct {
+	Action     string
+	TargetType string
+	TargetID   uint
+	Before     interface{}
+	After      interface{}
+}
+
+// Record appends the event to the log. The head is locked while the new
+// event is numbered and linked to the newest one, so the chain has a
+// single writer. When db is a handler's transaction the event commits or
+// rolls back with the operation it describes.
+func Record(db *gorm.DB, event *models.AuditEvent) error {
+	event.CreatedAt = time.Now().UTC().Truncate(time.Second)
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		head, err := lockHead(tx)
+		if err != nil {
+			return err
+		}
+
+		event.Seq = head.Seq + 1
+		event.PrevHash = head.Hash
+		event.Hash = eventHash(event)
+
+		if err := tx.Create(event).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(head).Updates(map[string]interface{}{"seq": event.Seq, "hash": event.Hash}).Error
+	})
+}
+
+func lockHead(tx *gorm.DB) (*models.AuditHead, error) {
+	var head models.AuditHead
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&head, models.AuditHeadID).Error
+	return &head, err
+}
+
+// Apply copies the change onto the event, encoding both states as JSON.
+func Apply(event *models.AuditEvent, change *Change) error {
+	if change.Action != "" {
+		event.Action = change.Action
+	}
+	if change.TargetType != "" {
+		event.TargetType = change.TargetType
+		targetID := change.TargetID
+		event.TargetID = &targetID
+	}
+
+	var err error
+	if event.Before, err = encode(change.Before); err != nil {
+		return err
+	}
+	event.After, err = encode(change.After)
+	return err
+}
+
+func encode(state interface{}) (string, error) {
+	if state == nil {
+		return "", nil
+	}
+
+	data, err := json.Marshal(state)
+	return string(data), err
+}
+
+// Prune deletes events older than the cutoff and returns how many were
+// removed. The head remembers the last sequence removed, so the oldest
+// remaining event must follow it.
+func Prune(db *gorm.DB, before time.Time) (int64, error) {
+	var pruned int64
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		head, err := lockHead(tx)
+		if err != nil {
+			return err
+		}
+
+		var through uint64
+		if err := tx.Model(&models.AuditEvent{}).
+			Where("created_at < ?", before).
+			Select("COALESCE(MAX(seq), 0)").
+			Scan(&through).Error; err != nil {
+			return err
+		}
+		if through <= head.PrunedThrough {
+			return nil
+		}
+
+		result := tx.Exec("DELETE FROM audit_events WHERE seq <= ?", through)
+		if result.Error != nil {
+			return result.Error
+		}
+		pruned = result.RowsAffected
+
+		return tx.Model(head).Update("pruned_through", through).Error
+	})
+
+	return pruned, err
+}
+
+// Break describes the first event that does not verify. EventID is zero
+// when the log does not end where the head says it does.
+type Break struct {
+	EventID uint   `json:"event_id,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// Verify walks the log in order and recomputes each hash. Sequence
+// numbers must run without gaps from the last one retention removed to
+// the head, and the newest event must be the one the head records, so
+// deleting events from either end is caught as well as editing them. The
+// oldest event is trusted to link to whatever retention removed. It
+// returns the first break found, or nil, and the number of events checked.
+func Verify(db *gorm.DB) (*Break, int, error) {
+	var head models.AuditHead
+	if err := db.First(&head, models.AuditHeadID).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var chainBreak *Break
+	previous := ""
+	expected := head.PrunedThrough + 1
+	checked := 0
+
+	var batch []models.AuditEvent
+	err := db.FindInBatches(&batch, 1000, func(_ *gorm.DB, _ int) error {
+		for i := range batch {
+			event := &batch[i]
+
+			switch {
+			case event.Seq != expected:
+				chainBreak = &Break{EventID: event.ID, Reason: fmt.Sprintf("event has sequence %d, expected %d", event.Seq, expected)}
+			case (checked > 0 || head.PrunedThrough == 0) && event.PrevHash != previous:
+				chainBreak = &Break{EventID: event.ID, Reason: "previous hash does not match the preceding event"}
+			case eventHash(event) != event.Hash:
+				chainBreak = &Break{EventID: event.ID, Reason: "event content does not match its hash"}
+			}
+
+			checked++
+			if chainBreak != nil {
+				return errChainBroken
+			}
+			previous = event.Hash
+			expected++
+		}
+		return nil
+	}).Error
+	if err != nil && !errors.Is(err, errChainBroken) {
+		return nil, checked, err
+	}
+	if chainBreak != nil {
+		return chainBreak, checked, nil
+	}
+
+	switch {
+	case expected-1 != head.Seq:
+		chainBreak = &Break{Reason: fmt.Sprintf("log ends at sequence %d but the head is at %d", expected-1, head.Seq)}
+	case checked > 0 && previous != head.Hash:
+		chainBreak = &Break{Reason: "newest event does not match the head"}
+	}
+
+	return chainBreak, checked, nil
+}
+
+// InitLog creates the head of an empty log. Events without a head mean
+// the head was removed; rebuilding it would rehash whatever the events
+// now hold, so the log is refused instead.
+func InitLog(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var heads int64
+		if err := tx.Model(&models.AuditHead{}).Where("id = ?", models.AuditHeadID).Count(&heads).Error; err != nil {
+			return err
+		}
+		if heads > 0 {
+			return nil
+		}
+
+		var events int64
+		if err := tx.Model(&models.AuditEvent{}).Count(&events).Error; err != nil {
+			return err
+		}
+		if events > 0 {
+			return fmt.Errorf("%w: %d events have no head", errChainBroken, events)
+		}
+
+		return tx.Create(&models.AuditHead{ID: models.AuditHeadID}).Error
+	})
+}
+
+func eventHash(event *models.AuditEvent) string {
+	var h hash.Hash
+	if len(chainKey) > 0 {
+		h = hmac.New(sha256.New, chainKey)
+	} else {
+		h = sha256.New()
+	}
+
+	fmt.Fprintf(h, "%d|%s|%s|%s|%s|%q|%q|%d|%s|%s|%d|%s",
+		event.Seq,
+		optionalID(event.ActorID),
+		event.Action,
+		event.TargetType,
+		optionalID(event.TargetID),
+		event.Before,
+		event.After,
+		event.Status,
+		event.RequestID,
+		event.SourceIP,
+		event.CreatedAt.Unix(),
+		event.PrevHash)
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func optionalID(id *uint) string {
+	if id == nil {
+		return "-"
+	}
+
+	return strconv.FormatUint(uint64(*id), 10)
+}
@@ -0,0 +1,41 @@
+package audit
+
+import (
+	"github.com/labstack/echo/v4"
+	"ledger-app/models"
+)
+
+// NewEvent starts the event for a request: the route as the action, the
+// authenticated user as the actor, the request ID and the client address
+// as resolved by the server's IP extractor.
+func NewEvent(c echo.Context, status int) models.AuditEvent {
+	event := models.AuditEvent{
+		Action:   c.Request().Method + " " + c.Path(),
+		Status:   status,
+		SourceIP: c.RealIP(),
+	}
+
+	if actorID, ok := c.Get("userID").(float64); ok {
+		id := uint(actorID)
+		event.ActorID = &id
+	}
+	event.RequestID, _ = c.Get("requestID").(string)
+
+	return event
+}
+
+// RouteParams describes a request by its route parameters, for operations
+// that do not describe their change themselves.
+func RouteParams(c echo.Context) *Change {
+	names := c.ParamNames()
+	if len(names) == 0 {
+		return nil
+	}
+
+	params := make(map[string]string, len(names))
+	for _, name := range names {
+		params[name] = c.Param(name)
+	}
+
+	return &Change{After: map[string]interface{}{"params": params}}
+}
@@ -0,0 +1,60 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+type command struct {
+	description string
+	run         func(args []string) error
+}
+
+var registry = map[string]command{
+	"export-journal": {
+		description: "Write the ledger as an hledger journal or Beancount file",
+		run:         exportJournal,
+	},
+	"import": {
+		description: "Load historical postings from a CSV or OFX file",
+		run:         importTransactions,
+	},
+	"import-journal": {
+		description: "Post the transactions of an hledger journal or Beancount file",
+		run:         importJournal,
+	},
+	"rebuild-balances": {
+		description: "Recompute stored account balances from the transaction history",
+		run:         rebuildBalances,
+	},
+	"verify-chain": {
+		description: "Check the hash chain of every account for tampered postings",
+		run:         verifyChain,
+	},
+}
+
+// Run executes the maintenance command named by args[0] against the
+// already initialised database.
+func Run(args []string) error {
+	cmd, ok := registry[args[0]]
+	if !ok {
+		printUsage()
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+
+	return cmd.run(args[1:])
+}
+
+func printUsage() {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintln(os.Stderr, "Available commands:")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  %-20s %s\n", name, registry[name].description)
+	}
+}
@@ -0,0 +1,69 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/importer"
+	"os"
+	"time"
+)
+
+func importTransactions(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	file := flags.String("file", "", "path of the CSV or OFX file")
+	format := flags.String("format", importer.FormatCSV, "file format: csv or ofx")
+	mappingJSON := flags.String("mapping", "", `CSV column mapping as JSON, e.g. {"amount":"Value"}`)
+	userID := flags.Uint("user-id", 0, "owner of an OFX statement")
+	dryRun := flags.Bool("dry-run", false, "validate the file without committing")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return errors.New("import needs -file")
+	}
+
+	var mapping importer.Mapping
+	if *mappingJSON != "" {
+		if err := json.Unmarshal([]byte(*mappingJSON), &mapping); err != nil {
+			return fmt.Errorf("invalid mapping: %w", err)
+		}
+	}
+
+	src, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	rows, rowErrors, err := importer.Parse(*format, src, mapping, *userID)
+	if err != nil {
+		return err
+	}
+
+	// Whoever runs commands has the database itself; there is no second
+	// admin to hold rows for.
+	result, err := importer.Import(database.Db, rows, rowErrors, *dryRun, time.Now().UTC(), nil)
+	if err != nil {
+		return err
+	}
+
+	for _, rowError := range result.Errors {
+		fmt.Fprintf(os.Stderr, "line %d %s: %s\n", rowError.Line, rowError.Reference, rowError.Error)
+	}
+
+	switch {
+	case result.Committed:
+		fmt.Printf("Imported %d of %d row(s)\n", result.Imported, result.Rows)
+	case len(result.Errors) > 0:
+		return fmt.Errorf("import rejected, %d of %d row(s) failed", len(result.Errors), result.Rows)
+	default:
+		fmt.Printf("Dry run passed, %d row(s) would be imported\n", result.Imported)
+	}
+
+	return nil
+}
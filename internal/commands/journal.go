@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/journal"
+	"os"
+)
+
+func exportJournal(args []string) error {
+	flags := flag.NewFlagSet("export-journal", flag.ContinueOnError)
+	format := flags.String("format", journal.FormatHledger, "journal format: hledger or beancount")
+	userID := flags.Uint("user-id", 0, "export only the entries of this user")
+	out := flags.String("out", "", "output file, standard output when empty")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		file, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	var user *uint
+	if *userID != 0 {
+		id := *userID
+		user = &id
+	}
+
+	count, err := journal.Export(database.Db, w, *format, user)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Exported %d journal entries\n", count)
+	return nil
+}
+
+func importJournal(args []string) error {
+	flags := flag.NewFlagSet("import-journal", flag.ContinueOnError)
+	file := flags.String("file", "", "path of the hledger journal or Beancount file")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *file == "" {
+		return errors.New("import-journal needs -file")
+	}
+
+	src, err := os.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	result, err := journal.Import(database.Db, src)
+	if err != nil {
+		return err
+	}
+
+	for _, lineError := range result.Errors {
+		fmt.Fprintf(os.Stderr, "line %d: %s\n", lineError.Line, lineError.Error)
+	}
+
+	if !result.Committed {
+		return fmt.Errorf("journal import rejected, %d error(s)", len(result.Errors))
+	}
+
+	fmt.Printf("Imported %d journal entries, created %d user(s)\n", result.Entries, result.UsersCreated)
+	return nil
+}
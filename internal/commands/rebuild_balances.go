@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"fmt"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
+	"ledger-app/logger"
+)
+
+func rebuildBalances(_ []string) error {
+	corrections, err := ledger.RebuildBalances(database.Db)
+	if err != nil {
+		return err
+	}
+
+	for _, correction := range corrections {
+		logger.Logger.Warnf("Account %s balance corrected from %s to %s",
+			correction.Code,
+			money.Format(correction.Stored, money.Default),
+			money.Format(correction.Computed, money.Default))
+	}
+
+	fmt.Printf("Rebuilt balances, %d account(s) corrected\n", len(corrections))
+	return nil
+}
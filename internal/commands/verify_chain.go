@@ -0,0 +1,21 @@
+package commands
+
+import (
+	"fmt"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/ledger"
+)
+
+func verifyChain(_ []string) error {
+	chainBreak, checked, err := ledger.VerifyChain(database.Db)
+	if err != nil {
+		return err
+	}
+
+	if chainBreak != nil {
+		return fmt.Errorf("chain broken in %s", chainBreak)
+	}
+
+	fmt.Printf("Transaction chain verified, %d posting(s) checked\n", checked)
+	return nil
+}
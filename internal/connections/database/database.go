@@ -1,12 +1,17 @@
 package database
 
 import (
+	"fmt"
 	_ "github.com/go-sql-driver/mysql"
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"ledger-app/config"
+	"ledger-app/internal/audit"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
 	"ledger-app/logger"
 	"ledger-app/models"
+	"strings"
 )
 
 var Db *gorm.DB
@@ -21,10 +26,106 @@ func Connect() {
 		logger.Logger.Fatal("Error connecting to the database:", err)
 	}
 
-	err = Db.AutoMigrate(&models.User{}, &models.Transaction{})
+	err = Migrate(Db)
 	if err != nil {
 		logger.Logger.Fatal("Error migrate the database", err)
 	}
 
 	logger.Logger.Infof("Connected to the database with GORM")
 }
+
+// Tables lists every model the application stores.
+func Tables() []interface{} {
+	return []interface{}{&models.User{}, &models.Account{}, &models.JournalEntry{}, &models.EntryTag{}, &models.Transaction{}, &models.IdempotencyKey{}, &models.BalanceException{}, &models.Hold{}, &models.ScheduledTransfer{}, &models.ScheduleRun{}, &models.OverdraftLimitChange{}, &models.BalanceSnapshot{}, &models.PeriodClose{}, &models.InterestRate{}, &models.InterestAccrual{}, &models.FeeSchedule{}, &models.FeeTier{}, &models.ReconciliationRun{}, &models.AuditEvent{}, &models.AuditHead{}, &models.LedgerIdentity{}, &models.ApprovalRequest{}, &models.VelocityLimit{}, &models.RiskReview{}, &models.FailedAttempt{}, &models.UserStatusChange{}}
+}
+
+func Migrate(db *gorm.DB) error {
+	if err := moveLegacyTransactions(db); err != nil {
+		return err
+	}
+
+	if err := db.AutoMigrate(Tables()...); err != nil {
+		return err
+	}
+
+	if err := postLegacyTransactions(db); err != nil {
+		return err
+	}
+
+	return audit.InitLog(db)
+}
+
+// moveLegacyTransactions moves the single-sided transactions of a ledger
+// that predates journal entries into the legacy table, so AutoMigrate
+// creates the new transactions table instead of bolting the journal
+// columns onto the old rows. It can be run again if it is interrupted.
+func moveLegacyTransactions(db *gorm.DB) error {
+	migrator := db.Migrator()
+	if !migrator.HasTable(&models.Transaction{}) || migrator.HasColumn(&models.Transaction{}, "JournalEntryID") {
+		return nil
+	}
+
+	logger.Logger.Info("Moving legacy transactions aside")
+
+	amount, err := legacyAmount(db)
+	if err != nil {
+		return err
+	}
+
+	// The copy is made without the foreign keys of the old table, whose
+	// names the new table reuses. The old table is left untouched until
+	// the copy is complete.
+	for _, statement := range []string{
+		"DROP TABLE IF EXISTS " + ledger.LegacyTable,
+		"CREATE TABLE " + ledger.LegacyTable + " LIKE transactions",
+		"ALTER TABLE " + ledger.LegacyTable + " MODIFY amount BIGINT NOT NULL",
+		"INSERT INTO " + ledger.LegacyTable + " (id, user_id, amount, transaction_time, sender_id, receiver_id) " +
+			"SELECT id, user_id, " + amount + ", transaction_time, sender_id, receiver_id FROM transactions",
+		"DROP TABLE transactions",
+	} {
+		if err := db.Exec(statement).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// legacyAmount returns the expression that reads a legacy amount in minor
+// units. Amounts kept as floating point numbers of whole currency units
+// are scaled by the precision of the ledger currency and rounded.
+func legacyAmount(db *gorm.DB) (string, error) {
+	columns, err := db.Migrator().ColumnTypes("transactions")
+	if err != nil {
+		return "", err
+	}
+
+	for _, column := range columns {
+		if column.Name() != "amount" {
+			continue
+		}
+
+		switch strings.ToUpper(column.DatabaseTypeName()) {
+		case "DOUBLE", "FLOAT", "DECIMAL":
+			return fmt.Sprintf("ROUND(amount * POW(10, %d))", money.Default.Precision), nil
+		}
+	}
+
+	return "amount", nil
+}
+
+// postLegacyTransactions posts the moved rows as journal entries and drops
+// the legacy table once they are committed.
+func postLegacyTransactions(db *gorm.DB) error {
+	if !db.Migrator().HasTable(ledger.LegacyTable) {
+		return nil
+	}
+
+	posted, err := ledger.PostLegacy(db)
+	if err != nil {
+		return err
+	}
+
+	logger.Logger.Infof("Posted %d legacy transactions as journal entries", posted)
+	return db.Migrator().DropTable(ledger.LegacyTable)
+}
@@ -0,0 +1,107 @@
+package database_test
+
+import (
+	"gorm.io/gorm"
+	"ledger-app/internal/connections/database"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/testdb"
+	"ledger-app/models"
+	"testing"
+)
+
+// legacySchema is the schema of the ledger before it kept journal
+// entries.
+var legacySchema = []string{
+	`CREATE TABLE users (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		name VARCHAR(100) NOT NULL,
+		password_hash VARCHAR(255) NOT NULL,
+		is_admin BOOLEAN DEFAULT FALSE,
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`,
+	`CREATE TABLE transactions (
+		id BIGINT UNSIGNED AUTO_INCREMENT PRIMARY KEY,
+		user_id BIGINT UNSIGNED NOT NULL,
+		amount DOUBLE NOT NULL,
+		transaction_time TIMESTAMP NOT NULL,
+		sender_id BIGINT UNSIGNED,
+		receiver_id BIGINT UNSIGNED,
+		CONSTRAINT fk_users_transactions FOREIGN KEY (user_id) REFERENCES users(id),
+		CONSTRAINT fk_transactions_sender FOREIGN KEY (sender_id) REFERENCES users(id),
+		CONSTRAINT fk_transactions_receiver FOREIGN KEY (receiver_id) REFERENCES users(id)
+	)`,
+	`INSERT INTO users (name, password_hash) VALUES ('alice', 'x'), ('bob', 'x')`,
+	`INSERT INTO transactions (user_id, amount, transaction_time, sender_id, receiver_id) VALUES
+		(1, 100.50, '2024-01-01 10:00:00', NULL, NULL),
+		(1, -20.07, '2024-01-02 10:00:00', 1, 2),
+		(2, 20.07, '2024-01-02 10:00:00', 1, 2),
+		(2, -5, '2024-01-03 10:00:00', NULL, NULL),
+		(2, 3, '2024-01-04 10:00:00', 1, 2)`,
+}
+
+func TestMigratePostsLegacyTransactions(t *testing.T) {
+	db := testdb.Open(t)
+
+	if err := db.Migrator().DropTable(database.Tables()...); err != nil {
+		t.Fatal(err)
+	}
+	for _, statement := range legacySchema {
+		if err := db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	if db.Migrator().HasTable(ledger.LegacyTable) {
+		t.Error("legacy table was not dropped")
+	}
+
+	// Amounts are scaled to cents, and the unmatched transfer row is
+	// posted against the migration account.
+	want := map[string]int64{
+		"user:1":                        8043,
+		"user:2":                        1807,
+		models.SystemAccountIssuance:    -10050,
+		models.SystemAccountWithdrawals: 500,
+		models.SystemAccountMigration:   -300,
+	}
+	checkBalances(t, db, want)
+
+	var transfers int64
+	if err := db.Model(&models.JournalEntry{}).Where("type = ?", models.EntryTypeTransfer).Count(&transfers).Error; err != nil {
+		t.Fatal(err)
+	}
+	if transfers != 1 {
+		t.Errorf("transfers = %d, want the two transfer rows posted as one entry", transfers)
+	}
+
+	chainBreak, _, err := ledger.VerifyChain(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chainBreak != nil {
+		t.Errorf("migrated ledger does not verify: %s", chainBreak)
+	}
+
+	// A second start finds nothing left to migrate.
+	if err := database.Migrate(db); err != nil {
+		t.Fatal(err)
+	}
+	checkBalances(t, db, want)
+}
+
+func checkBalances(t *testing.T, db *gorm.DB, want map[string]int64) {
+	t.Helper()
+
+	for code, balance := range want {
+		var account models.Account
+		if err := db.Where("code = ?", code).First(&account).Error; err != nil {
+			t.Fatalf("load account %s: %v", code, err)
+		}
+		if account.Balance != balance {
+			t.Errorf("%s balance = %d, want %d", code, account.Balance, balance)
+		}
+	}
+}
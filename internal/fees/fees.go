@@ -0,0 +1,109 @@
+package fees
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"ledger-app/internal/money"
+	"ledger-app/models"
+	"sort"
+)
+
+var ErrDuplicateTier = errors.New("fee tiers must start at different amounts")
+
+// Lookup returns the schedule for the role, falling back to the schedule
+// for any role. It returns nil when the operation is free.
+func Lookup(db *gorm.DB, operation, role string) (*models.FeeSchedule, error) {
+	var schedules []models.FeeSchedule
+	if err := db.Preload("Tiers").
+		Where("operation = ? AND role IN ?", operation, []string{role, models.FeeRoleAny}).
+		Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+
+	var fallback *models.FeeSchedule
+	for i := range schedules {
+		if schedules[i].Role == role {
+			return &schedules[i], nil
+		}
+		fallback = &schedules[i]
+	}
+
+	return fallback, nil
+}
+
+// Quote computes the fee for an operation of amount by a user of role.
+func Quote(db *gorm.DB, operation, role string, amount int64) (int64, error) {
+	schedule, err := Lookup(db, operation, role)
+	if err != nil || schedule == nil {
+		return 0, err
+	}
+
+	return Compute(schedule, amount)
+}
+
+// Compute applies the schedule to amount. Tiers are not marginal: the
+// whole amount is charged at the rate of the tier it falls in.
+func Compute(schedule *models.FeeSchedule, amount int64) (int64, error) {
+	var fee int64
+
+	var tier *models.FeeTier
+	for i := range schedule.Tiers {
+		candidate := &schedule.Tiers[i]
+		if candidate.AmountFrom <= amount && (tier == nil || candidate.AmountFrom > tier.AmountFrom) {
+			tier = candidate
+		}
+	}
+
+	if tier != nil {
+		percentage, err := money.ApplyPercent(amount, tier.RatePPM)
+		if err != nil {
+			return 0, err
+		}
+
+		if fee, err = money.Add(tier.Flat, percentage); err != nil {
+			return 0, err
+		}
+	}
+
+	if fee < schedule.Min {
+		fee = schedule.Min
+	}
+	if schedule.Max > 0 && fee > schedule.Max {
+		fee = schedule.Max
+	}
+
+	return fee, nil
+}
+
+// SetSchedule replaces the schedule of an operation and role.
+func SetSchedule(tx *gorm.DB, schedule *models.FeeSchedule) error {
+	sort.Slice(schedule.Tiers, func(i, j int) bool { return schedule.Tiers[i].AmountFrom < schedule.Tiers[j].AmountFrom })
+	for i := 1; i < len(schedule.Tiers); i++ {
+		if schedule.Tiers[i].AmountFrom == schedule.Tiers[i-1].AmountFrom {
+			return ErrDuplicateTier
+		}
+	}
+
+	if err := DeleteSchedule(tx, schedule.Operation, schedule.Role); err != nil {
+		return err
+	}
+
+	return tx.Create(schedule).Error
+}
+
+func DeleteSchedule(tx *gorm.DB, operation, role string) error {
+	var existing models.FeeSchedule
+	if err := tx.Where("operation = ? AND role = ?", operation, role).Limit(1).Find(&existing).Error; err != nil {
+		return err
+	}
+
+	if existing.ID == 0 {
+		return nil
+	}
+
+	if err := tx.Where("schedule_id = ?", existing.ID).Delete(&models.FeeTier{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Delete(&existing).Error
+}
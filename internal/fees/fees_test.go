@@ -0,0 +1,47 @@
+package fees_test
+
+import (
+	"errors"
+	"ledger-app/internal/fees"
+	"ledger-app/internal/money"
+	"ledger-app/models"
+	"math"
+	"testing"
+)
+
+func TestCompute(t *testing.T) {
+	// 1.00 flat below 100.00, 1% from 100.00 and 0.5% plus 2.00 from
+	// 1,000.00, listed out of order.
+	tiered := &models.FeeSchedule{Tiers: []models.FeeTier{
+		{AmountFrom: 100000, Flat: 200, RatePPM: 5_000},
+		{AmountFrom: 0, Flat: 100},
+		{AmountFrom: 10000, RatePPM: 10_000},
+	}}
+
+	tests := []struct {
+		name     string
+		schedule *models.FeeSchedule
+		amount   int64
+		want     int64
+		err      error
+	}{
+		{"first tier", tiered, 5000, 100, nil},
+		{"tier starts at its amount", tiered, 10000, 100, nil},
+		{"whole amount at the tier rate", tiered, 99999, 1000, nil},
+		{"flat plus rate", tiered, 100000, 700, nil},
+		{"rate rounds half away from zero", tiered, 10050, 101, nil},
+		{"below every tier", &models.FeeSchedule{Tiers: []models.FeeTier{{AmountFrom: 1000, Flat: 50}}}, 999, 0, nil},
+		{"minimum", &models.FeeSchedule{Min: 25, Tiers: []models.FeeTier{{RatePPM: 1_000}}}, 1000, 25, nil},
+		{"maximum", &models.FeeSchedule{Max: 500, Tiers: []models.FeeTier{{RatePPM: 100_000}}}, 100000, 500, nil},
+		{"no maximum when zero", &models.FeeSchedule{Tiers: []models.FeeTier{{RatePPM: 100_000}}}, 100000, 10000, nil},
+		{"no tiers", &models.FeeSchedule{Min: 10}, 1000, 10, nil},
+		{"overflow", &models.FeeSchedule{Tiers: []models.FeeTier{{Flat: 1, RatePPM: 1_000_000}}}, math.MaxInt64, 0, money.ErrAmountOutOfRange},
+	}
+
+	for _, tt := range tests {
+		got, err := fees.Compute(tt.schedule, tt.amount)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("%s: Compute(%d) = %d, %v, want %d, %v", tt.name, tt.amount, got, err, tt.want, tt.err)
+		}
+	}
+}
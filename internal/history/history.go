@@ -0,0 +1,228 @@
+package history
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm"
+	"ledger-app/models"
+	"time"
+)
+
+const (
+	DefaultLimit = 50
+	MaxLimit     = 200
+
+	DirectionIn  = "in"
+	DirectionOut = "out"
+
+	SortTime   = "time"
+	SortAmount = "amount"
+)
+
+// Columns selects the fields of Row from a query built by Apply.
+const Columns = "transactions.id, transactions.journal_entry_id, journal_entries.type, " +
+	"transactions.amount, transactions.transaction_time, transactions.sender_id, transactions.receiver_id, " +
+	"journal_entries.description, journal_entries.reference, journal_entries.category, journal_entries.fee_for_id"
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Filter narrows down the postings of one account. Zero values mean the
+// filter is not applied.
+type Filter struct {
+	From           *time.Time
+	To             *time.Time
+	MinAmount      *int64
+	MaxAmount      *int64
+	Direction      string
+	CounterpartyID *uint
+	Type           string
+	Category       string
+	Tag            string
+	Reference      string
+	SortBy         string
+	Descending     bool
+	Limit          int
+	Cursor         string
+}
+
+// Row is one posting on the account together with its entry details.
+type Row struct {
+	ID              uint
+	JournalEntryID  uint
+	Type            string
+	Amount          int64
+	TransactionTime time.Time
+	SenderID        *uint
+	ReceiverID      *uint
+	Description     string
+	Reference       string
+	Category        string
+	FeeForID        *uint
+	Tags            []string `gorm:"-"`
+}
+
+// CounterpartyID is the other user of a transfer, if there is one.
+func (r *Row) CounterpartyID() *uint {
+	if r.Amount < 0 {
+		return r.ReceiverID
+	}
+
+	return r.SenderID
+}
+
+type cursor struct {
+	SortBy     string    `json:"s"`
+	Descending bool      `json:"d"`
+	Time       time.Time `json:"t,omitempty"`
+	Amount     int64     `json:"a,omitempty"`
+	ID         uint      `json:"i"`
+}
+
+// Apply adds the filter conditions for accountID to a query on the
+// transactions table joined with journal_entries. Sorting and paging are
+// left to the caller.
+func Apply(db *gorm.DB, accountID uint, filter Filter) *gorm.DB {
+	query := db.Table("transactions").
+		Joins("JOIN journal_entries ON journal_entries.id = transactions.journal_entry_id").
+		Where("transactions.account_id = ?", accountID)
+
+	if filter.From != nil {
+		query = query.Where("transactions.transaction_time >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("transactions.transaction_time < ?", *filter.To)
+	}
+	if filter.MinAmount != nil {
+		query = query.Where("ABS(transactions.amount) >= ?", *filter.MinAmount)
+	}
+	if filter.MaxAmount != nil {
+		query = query.Where("ABS(transactions.amount) <= ?", *filter.MaxAmount)
+	}
+
+	switch filter.Direction {
+	case DirectionIn:
+		query = query.Where("transactions.amount > 0")
+	case DirectionOut:
+		query = query.Where("transactions.amount < 0")
+	}
+
+	if filter.CounterpartyID != nil {
+		query = query.Where("((transactions.amount < 0 AND transactions.receiver_id = ?) OR (transactions.amount > 0 AND transactions.sender_id = ?))",
+			*filter.CounterpartyID, *filter.CounterpartyID)
+	}
+	if filter.Type != "" {
+		query = query.Where("journal_entries.type = ?", filter.Type)
+	}
+	if filter.Category != "" {
+		query = query.Where("journal_entries.category = ?", filter.Category)
+	}
+	if filter.Reference != "" {
+		query = query.Where("journal_entries.reference = ?", filter.Reference)
+	}
+	if filter.Tag != "" {
+		query = query.Where("EXISTS (SELECT 1 FROM entry_tags WHERE entry_tags.journal_entry_id = journal_entries.id AND entry_tags.tag = ?)", filter.Tag)
+	}
+
+	return query
+}
+
+// List returns one page of postings and the cursor for the next page,
+// which is empty on the last page.
+func List(db *gorm.DB, accountID uint, filter Filter) ([]Row, string, error) {
+	if filter.Limit <= 0 || filter.Limit > MaxLimit {
+		filter.Limit = DefaultLimit
+	}
+	if filter.SortBy == "" {
+		filter.SortBy = SortTime
+	}
+
+	column := "transactions.transaction_time"
+	if filter.SortBy == SortAmount {
+		column = "transactions.amount"
+	}
+
+	order, comparison := "ASC", ">"
+	if filter.Descending {
+		order, comparison = "DESC", "<"
+	}
+
+	query := Apply(db, accountID, filter).Select(Columns)
+
+	if filter.Cursor != "" {
+		after, err := decodeCursor(filter.Cursor)
+		if err != nil || after.SortBy != filter.SortBy || after.Descending != filter.Descending {
+			return nil, "", ErrInvalidCursor
+		}
+
+		var key interface{} = after.Time
+		if filter.SortBy == SortAmount {
+			key = after.Amount
+		}
+
+		query = query.Where("(("+column+" "+comparison+" ?) OR ("+column+" = ? AND transactions.id "+comparison+" ?))", key, key, after.ID)
+	}
+
+	var rows []Row
+	if err := query.Order(column + " " + order).Order("transactions.id " + order).Limit(filter.Limit + 1).Scan(&rows).Error; err != nil {
+		return nil, "", err
+	}
+
+	next := ""
+	if len(rows) > filter.Limit {
+		rows = rows[:filter.Limit]
+		last := rows[len(rows)-1]
+		next = encodeCursor(cursor{
+			SortBy:     filter.SortBy,
+			Descending: filter.Descending,
+			Time:       last.TransactionTime,
+			Amount:     last.Amount,
+			ID:         last.ID,
+		})
+	}
+
+	return rows, next, attachTags(db, rows)
+}
+
+func attachTags(db *gorm.DB, rows []Row) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	entryIDs := make([]uint, 0, len(rows))
+	for _, row := range rows {
+		entryIDs = append(entryIDs, row.JournalEntryID)
+	}
+
+	var tags []models.EntryTag
+	if err := db.Where("journal_entry_id IN ?", entryIDs).Order("tag").Find(&tags).Error; err != nil {
+		return err
+	}
+
+	byEntry := make(map[uint][]string)
+	for _, tag := range tags {
+		byEntry[tag.JournalEntryID] = append(byEntry[tag.JournalEntryID], tag.Tag)
+	}
+
+	for i := range rows {
+		rows[i].Tags = byEntry[rows[i].JournalEntryID]
+	}
+
+	return nil
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (cursor, error) {
+	var c cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return c, err
+	}
+
+	return c, json.Unmarshal(data, &c)
+}
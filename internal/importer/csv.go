@@ -0,0 +1,169 @@
+package importer
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"ledger-app/internal/money"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Mapping names the CSV header column that holds each field. Empty names
+// fall back to the field name itself, e.g. "amount".
+type Mapping struct {
+	UserID      string `json:"user_id"`
+	Amount      string `json:"amount"`
+	Date        string `json:"date"`
+	Reference   string `json:"reference"`
+	Description string `json:"description"`
+	Category    string `json:"category"`
+	// DateFormat is a Go time layout; dates default to RFC 3339 or 2006-01-02.
+	DateFormat string `json:"date_format"`
+}
+
+func (m Mapping) withDefaults() Mapping {
+	defaults := []struct {
+		field *string
+		name  string
+	}{
+		{&m.UserID, "user_id"},
+		{&m.Amount, "amount"},
+		{&m.Date, "date"},
+		{&m.Reference, "reference"},
+		{&m.Description, "description"},
+		{&m.Category, "category"},
+	}
+
+	for _, d := range defaults {
+		if *d.field == "" {
+			*d.field = d.name
+		}
+	}
+
+	return m
+}
+
+// ParseCSV reads a CSV file whose first line is a header. Rows that cannot
+// be parsed are returned as errors instead of aborting the whole file.
+func ParseCSV(r io.Reader, mapping Mapping) ([]Row, []RowError, error) {
+	mapping = mapping.withDefaults()
+
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, nil, errors.New("csv file is empty")
+		}
+		return nil, nil, err
+	}
+
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))] = i
+	}
+
+	for _, required := range []string{mapping.UserID, mapping.Amount, mapping.Date, mapping.Reference} {
+		if _, ok := columns[required]; !ok {
+			return nil, nil, fmt.Errorf("csv header has no %q column", required)
+		}
+	}
+
+	var rows []Row
+	var rowErrors []RowError
+
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				rowErrors = append(rowErrors, RowError{Line: parseErr.Line, Error: parseErr.Err.Error()})
+				continue
+			}
+			return nil, nil, err
+		}
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row, err := csvRow(line, field, mapping)
+		if err != nil {
+			rowErrors = append(rowErrors, RowError{Line: line, Reference: row.Reference, Error: err.Error()})
+			continue
+		}
+
+		rows = append(rows, row)
+	}
+
+	return rows, rowErrors, nil
+}
+
+func csvRow(line int, field func(string) string, mapping Mapping) (Row, error) {
+	row := Row{
+		Line:        line,
+		Reference:   field(mapping.Reference),
+		Description: field(mapping.Description),
+		Category:    field(mapping.Category),
+	}
+
+	if row.Reference == "" {
+		return row, errors.New("reference is required")
+	}
+
+	userID, err := strconv.ParseUint(field(mapping.UserID), 10, 64)
+	if err != nil || userID == 0 {
+		return row, errors.New("invalid user id")
+	}
+	row.UserID = uint(userID)
+
+	if row.Amount, err = parseAmount(field(mapping.Amount)); err != nil {
+		return row, err
+	}
+
+	if row.Time, err = parseDate(field(mapping.Date), mapping.DateFormat); err != nil {
+		return row, err
+	}
+
+	return row, nil
+}
+
+func parseAmount(value string) (int64, error) {
+	amount, err := money.Parse(strings.TrimPrefix(value, "+"), money.Default)
+	if err != nil {
+		return 0, err
+	}
+
+	if amount == 0 {
+		return 0, errors.New("amount must not be zero")
+	}
+
+	return amount, nil
+}
+
+func parseDate(value, layout string) (time.Time, error) {
+	layouts := []string{time.RFC3339, "2006-01-02"}
+	if layout != "" {
+		layouts = []string{layout}
+	}
+
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
@@ -0,0 +1,274 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
+	"ledger-app/internal/validation"
+	"ledger-app/models"
+	"sort"
+	"time"
+)
+
+var (
+	ErrDuplicateReference = errors.New("duplicate reference, already imported")
+	ErrFutureDate         = errors.New("date is in the future")
+
+	errRejected = errors.New("import rejected")
+)
+
+// Row is one historical posting to load into a user's account. Positive
+// amounts credit the user, negative amounts debit them.
+type Row struct {
+	Line        int
+	UserID      uint
+	Amount      int64
+	Time        time.Time
+	Reference   string
+	Description string
+	Category    string
+}
+
+type RowError struct {
+	Line      int    `json:"line"`
+	Reference string `json:"reference,omitempty"`
+	Error     string `json:"error"`
+}
+
+type Result struct {
+	DryRun      bool       `json:"dry_run"`
+	Rows        int        `json:"rows"`
+	Imported    int        `json:"imported"`
+	Held        int        `json:"held_for_approval"`
+	ApprovalIDs []uint     `json:"approval_request_ids,omitempty"`
+	Committed   bool       `json:"committed"`
+	Errors      []RowError `json:"errors"`
+}
+
+// Holder decides whether a row has to wait for a second admin. It submits
+// a row that does in tx and returns the approval request ID; zero means
+// the row is posted right away.
+type Holder func(tx *gorm.DB, row Row) (uint, error)
+
+// Import posts every row against the migration account in a single
+// database transaction. Rows whose reference was already imported are
+// reported as duplicates, and rows hold submits for approval are posted
+// once approved. Nothing is committed if any row fails or when dryRun is
+// set, so a dry run reports exactly what a real run would.
+func Import(db *gorm.DB, rows []Row, parseErrors []RowError, dryRun bool, now time.Time, hold Holder) (*Result, error) {
+	result := &Result{DryRun: dryRun, Rows: len(rows) + len(parseErrors), Errors: parseErrors}
+
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].Time.Before(rows[j].Time) })
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		duplicates, err := existingReferences(tx, rows)
+		if err != nil {
+			return err
+		}
+
+		migration, err := ledger.SystemAccount(tx, models.SystemAccountMigration)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[string]int)
+		for _, row := range rows {
+			if line, ok := seen[row.Reference]; ok {
+				result.addError(row, fmt.Sprintf("duplicate reference, already used on line %d", line))
+				continue
+			}
+			seen[row.Reference] = row.Line
+
+			if duplicates[row.Reference] {
+				result.addError(row, ErrDuplicateReference.Error())
+				continue
+			}
+
+			var approvalID uint
+			err := tx.Transaction(func(rowTx *gorm.DB) error {
+				if hold != nil {
+					var err error
+					if approvalID, err = hold(rowTx, row); err != nil || approvalID != 0 {
+						return err
+					}
+				}
+
+				_, err := postRow(rowTx, migration, row, now)
+				return err
+			})
+			if err != nil {
+				result.addError(row, err.Error())
+				continue
+			}
+
+			if approvalID != 0 {
+				result.Held++
+				result.ApprovalIDs = append(result.ApprovalIDs, approvalID)
+				continue
+			}
+			result.Imported++
+		}
+
+		if dryRun || len(result.Errors) > 0 {
+			return errRejected
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, errRejected) {
+		return result, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	result.Committed = true
+	return result, nil
+}
+
+// Volume returns the gross amount the rows post, counting debits and
+// credits alike.
+func Volume(rows []Row) (int64, error) {
+	var volume int64
+	for _, row := range rows {
+		amount := row.Amount
+		if amount < 0 {
+			amount = -amount
+		}
+
+		var err error
+		if volume, err = money.Add(volume, amount); err != nil {
+			return 0, err
+		}
+	}
+
+	return volume, nil
+}
+
+// PostRow posts a row that was held for approval. Its reference is
+// checked again, as the same row may have been imported meanwhile.
+func PostRow(tx *gorm.DB, row Row, now time.Time) (*models.JournalEntry, error) {
+	duplicates, err := existingReferences(tx, []Row{row})
+	if err != nil {
+		return nil, err
+	}
+	if duplicates[row.Reference] {
+		return nil, ErrDuplicateReference
+	}
+
+	migration, err := ledger.SystemAccount(tx, models.SystemAccountMigration)
+	if err != nil {
+		return nil, err
+	}
+
+	return postRow(tx, migration, row, now)
+}
+
+// postRow posts the row against the migration account. A positive row
+// pays into the user's account and a negative one out of it, so the user's
+// status must allow that direction like any other entry.
+func postRow(tx *gorm.DB, migration *models.Account, row Row, now time.Time) (*models.JournalEntry, error) {
+	if row.Time.After(now) {
+		return nil, ErrFutureDate
+	}
+
+	var user models.User
+	if err := tx.First(&user, row.UserID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("user %d not found", row.UserID)
+		}
+		return nil, err
+	}
+
+	senderID, receiverID := uint(0), user.ID
+	if row.Amount < 0 {
+		senderID, receiverID = user.ID, 0
+	}
+	if err := ledger.CheckStatus(tx, senderID, receiverID); err != nil {
+		return nil, err
+	}
+
+	account, err := ledger.UserAccount(tx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.JournalEntry{
+		Type:     models.EntryTypeImport,
+		PostedAt: row.Time.UTC(),
+		Postings: []models.Transaction{
+			{AccountID: migration.ID, Amount: -row.Amount},
+			{AccountID: account.ID, UserID: &user.ID, Amount: row.Amount},
+		},
+	}
+	meta := models.EntryMetadata{
+		Description: row.Description,
+		Reference:   row.Reference,
+		Category:    row.Category,
+	}
+	if err := validation.ValidateStruct().Struct(meta); err != nil {
+		return nil, fmt.Errorf("invalid description, reference or category: %w", err)
+	}
+	entry.SetMetadata(meta)
+
+	return entry, ledger.Post(tx, entry)
+}
+
+func existingReferences(tx *gorm.DB, rows []Row) (map[string]bool, error) {
+	existing := make(map[string]bool)
+
+	references := make([]string, 0, len(rows))
+	for _, row := range rows {
+		references = append(references, row.Reference)
+	}
+
+	for start := 0; start < len(references); start += 500 {
+		end := start + 500
+		if end > len(references) {
+			end = len(references)
+		}
+
+		var found []string
+		if err := tx.Model(&models.JournalEntry{}).
+			Where("type = ? AND reference IN ?", models.EntryTypeImport, references[start:end]).
+			Pluck("reference", &found).Error; err != nil {
+			return nil, err
+		}
+
+		for _, reference := range found {
+			existing[reference] = true
+		}
+	}
+
+	return existing, nil
+}
+
+func (r *Result) addError(row Row, message string) {
+	r.Errors = append(r.Errors, RowError{Line: row.Line, Reference: row.Reference, Error: message})
+}
+
+const (
+	FormatCSV = "csv"
+	FormatOFX = "ofx"
+)
+
+// Parse reads rows in the named format. The mapping only applies to CSV;
+// userID is the account owner of an OFX statement.
+func Parse(format string, r io.Reader, mapping Mapping, userID uint) ([]Row, []RowError, error) {
+	switch format {
+	case FormatCSV:
+		return ParseCSV(r, mapping)
+	case FormatOFX:
+		if userID == 0 {
+			return nil, nil, errors.New("ofx import needs the user id of the statement owner")
+		}
+		return ParseOFX(r, userID)
+	default:
+		return nil, nil, fmt.Errorf("unsupported import format %q", format)
+	}
+}
@@ -0,0 +1,163 @@
+package importer_test
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"ledger-app/internal/approvals"
+	"ledger-app/internal/importer"
+	"ledger-app/internal/testdb"
+	"ledger-app/models"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportRejectsFutureRowsAndInactiveUsers(t *testing.T) {
+	db := testdb.Open(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	alice := testdb.NewUser(t, db, "alice", 0)
+	frozen := testdb.NewUser(t, db, "frozen", 0)
+	testdb.SetStatus(t, db, frozen, models.UserStatusFrozen)
+	suspended := testdb.NewUser(t, db, "suspended", 0)
+	testdb.SetStatus(t, db, suspended, models.UserStatusSuspended)
+
+	rows := []importer.Row{
+		{Line: 2, UserID: alice.ID, Amount: 100, Time: now.Add(time.Hour), Reference: "future"},
+		{Line: 3, UserID: suspended.ID, Amount: 100, Time: now.Add(-time.Hour), Reference: "suspended"},
+		{Line: 4, UserID: frozen.ID, Amount: -100, Time: now.Add(-time.Hour), Reference: "frozen-debit"},
+		{Line: 5, UserID: frozen.ID, Amount: 100, Time: now.Add(-time.Hour), Reference: "frozen-credit"},
+	}
+
+	result, err := importer.Import(db, rows, nil, false, now, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if result.Committed || result.Imported != 1 || len(result.Errors) != 3 {
+		t.Fatalf("result = %+v, want only the credit to the frozen user to pass", result)
+	}
+
+	want := map[int]string{
+		2: importer.ErrFutureDate.Error(),
+		3: models.ErrUserInactive.Error(),
+		4: models.ErrUserInactive.Error(),
+	}
+	for _, rowError := range result.Errors {
+		if !strings.Contains(rowError.Error, want[rowError.Line]) {
+			t.Errorf("line %d error = %q, want %q", rowError.Line, rowError.Error, want[rowError.Line])
+		}
+	}
+}
+
+func TestImportHoldsRowsOverTheApprovalThreshold(t *testing.T) {
+	db := testdb.Open(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	approvals.Configure(1000, 0, time.Hour)
+	t.Cleanup(func() { approvals.Configure(0, 0, 72*time.Hour) })
+
+	maker := testdb.NewUser(t, db, "maker", 0)
+	checker := testdb.NewUser(t, db, "checker", 0)
+	alice := testdb.NewUser(t, db, "alice", 0)
+
+	hold := func(tx *gorm.DB, row importer.Row) (uint, error) {
+		if !approvals.ImportNeedsApproval(row.Amount) {
+			return 0, nil
+		}
+		request, err := approvals.SubmitImportRow(tx, row, maker.ID, now)
+		if err != nil {
+			return 0, err
+		}
+		return request.ID, nil
+	}
+
+	rows := []importer.Row{
+		{Line: 2, UserID: alice.ID, Amount: 500, Time: now.Add(-2 * time.Hour), Reference: "small"},
+		{Line: 3, UserID: alice.ID, Amount: 5000, Time: now.Add(-time.Hour), Reference: "large"},
+	}
+
+	result, err := importer.Import(db, rows, nil, false, now, hold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.Committed || result.Imported != 1 || result.Held != 1 || len(result.ApprovalIDs) != 1 {
+		t.Fatalf("result = %+v, want the large row held", result)
+	}
+	if got := testdb.Account(t, db, alice.ID).Balance; got != 500 {
+		t.Fatalf("balance before approval = %d, want 500", got)
+	}
+
+	// Importing the file again must not submit the held row twice.
+	again, err := importer.Import(db, rows[1:], nil, false, now, hold)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Committed || len(again.Errors) != 1 {
+		t.Fatalf("re-import = %+v, want the pending row rejected", again)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := approvals.Approve(tx, result.ApprovalIDs[0], maker.ID, "", now)
+		return err
+	})
+	if !errors.Is(err, approvals.ErrSelfApproval) {
+		t.Fatalf("approval by the maker = %v, want %v", err, approvals.ErrSelfApproval)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := approvals.Approve(tx, result.ApprovalIDs[0], checker.ID, "", now)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := testdb.Account(t, db, alice.ID).Balance; got != 5500 {
+		t.Errorf("balance after approval = %d, want 5500", got)
+	}
+}
+
+func TestApprovedImportFileImportsEveryRow(t *testing.T) {
+	db := testdb.Open(t)
+	now := time.Now().UTC().Truncate(time.Second)
+
+	approvals.Configure(0, 1000, time.Hour)
+	t.Cleanup(func() { approvals.Configure(0, 0, 72*time.Hour) })
+
+	maker := testdb.NewUser(t, db, "maker", 0)
+	checker := testdb.NewUser(t, db, "checker", 0)
+	alice := testdb.NewUser(t, db, "alice", 0)
+
+	// No single row reaches a threshold, but together they do.
+	rows := []importer.Row{
+		{Line: 2, UserID: alice.ID, Amount: 600, Time: now.Add(-2 * time.Hour), Reference: "first"},
+		{Line: 3, UserID: alice.ID, Amount: 600, Time: now.Add(-time.Hour), Reference: "second"},
+	}
+
+	volume, err := importer.Volume(rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !approvals.ImportVolumeNeedsApproval(volume) {
+		t.Fatalf("volume %d does not need approval", volume)
+	}
+
+	request, err := approvals.SubmitImportFile(db, rows, volume, maker.ID, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := testdb.Account(t, db, alice.ID).Balance; got != 0 {
+		t.Fatalf("balance before approval = %d, want 0", got)
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		_, err := approvals.Approve(tx, request.ID, checker.ID, "", now)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := testdb.Account(t, db, alice.ID).Balance; got != 1200 {
+		t.Errorf("balance after approval = %d, want 1200", got)
+	}
+}
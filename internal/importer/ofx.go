@@ -0,0 +1,139 @@
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+var ofxEntities = strings.NewReplacer("&amp;", "&", "&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'")
+
+// ParseOFX reads the STMTTRN records of an OFX statement. Both the SGML
+// (1.x) and XML (2.x) dialects are accepted. An OFX statement describes a
+// single account, so every row is booked to userID. Rows are numbered by
+// their position in the statement.
+func ParseOFX(r io.Reader, userID uint) ([]Row, []RowError, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var rows []Row
+	var rowErrors []RowError
+
+	var fields map[string]string
+	position := 0
+
+	rest := string(data)
+	for {
+		start := strings.IndexByte(rest, '<')
+		if start < 0 {
+			break
+		}
+
+		end := strings.IndexByte(rest[start:], '>')
+		if end < 0 {
+			break
+		}
+
+		tag := strings.ToUpper(strings.TrimSpace(rest[start+1 : start+end]))
+		rest = rest[start+end+1:]
+
+		value := rest
+		if next := strings.IndexByte(rest, '<'); next >= 0 {
+			value = rest[:next]
+		}
+		value = ofxEntities.Replace(strings.TrimSpace(value))
+
+		switch {
+		case tag == "STMTTRN":
+			fields = make(map[string]string)
+		case tag == "/STMTTRN" && fields != nil:
+			position++
+			row, err := ofxRow(position, fields, userID)
+			if err != nil {
+				rowErrors = append(rowErrors, RowError{Line: position, Reference: row.Reference, Error: err.Error()})
+			} else {
+				rows = append(rows, row)
+			}
+			fields = nil
+		case fields != nil && !strings.HasPrefix(tag, "/"):
+			fields[tag] = value
+		}
+	}
+
+	if position == 0 {
+		return nil, nil, errors.New("ofx file contains no transactions")
+	}
+
+	return rows, rowErrors, nil
+}
+
+func ofxRow(position int, fields map[string]string, userID uint) (Row, error) {
+	row := Row{
+		Line:        position,
+		UserID:      userID,
+		Reference:   fields["FITID"],
+		Description: fields["NAME"],
+		Category:    strings.ToLower(fields["TRNTYPE"]),
+	}
+
+	if memo := fields["MEMO"]; memo != "" {
+		if row.Description != "" {
+			row.Description += " - "
+		}
+		row.Description += memo
+	}
+
+	if row.Reference == "" {
+		return row, errors.New("FITID is required")
+	}
+
+	var err error
+	if row.Amount, err = parseAmount(fields["TRNAMT"]); err != nil {
+		return row, err
+	}
+
+	if row.Time, err = parseOFXDate(fields["DTPOSTED"]); err != nil {
+		return row, err
+	}
+
+	return row, nil
+}
+
+// parseOFXDate reads dates such as 20240131, 20240131120000 or
+// 20240131120000.000[-5:EST]. Dates without an offset are in UTC.
+func parseOFXDate(value string) (time.Time, error) {
+	datetime, zone, _ := strings.Cut(value, "[")
+	datetime, _, _ = strings.Cut(datetime, ".")
+
+	layout := "20060102150405"
+	if len(datetime) < len(layout) {
+		layout = layout[:len(datetime)]
+	}
+
+	location := time.UTC
+	if zone != "" {
+		offset, name, _ := strings.Cut(strings.TrimSuffix(zone, "]"), ":")
+		var hours float64
+		if _, err := fmt.Sscanf(offset, "%g", &hours); err != nil {
+			return time.Time{}, fmt.Errorf("invalid DTPOSTED %q", value)
+		}
+		location = time.FixedZone(name, int(hours*3600))
+	}
+
+	switch len(datetime) {
+	case 8, 12, 14:
+	default:
+		return time.Time{}, fmt.Errorf("invalid DTPOSTED %q", value)
+	}
+
+	t, err := time.ParseInLocation(layout, datetime, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid DTPOSTED %q", value)
+	}
+
+	return t.UTC(), nil
+}
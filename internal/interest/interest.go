@@ -0,0 +1,277 @@
+package interest
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/internal/ledger"
+	"ledger-app/models"
+	"math/big"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// microUnits is the number of accrual units in one minor unit.
+const microUnits = 1_000_000
+
+var ErrAccrualOutOfRange = errors.New("accrued interest is out of range")
+
+// Run accrues interest for every open user account whose class has a
+// rate, one day at a time up to the latest UTC midnight. Accounts of
+// suspended users accrue nothing. Accrued interest is
+// paid into the account from the interest account on the first day of
+// each month. It returns the number of payments posted.
+func Run(db *gorm.DB, now time.Time) (int, error) {
+	var rates []models.InterestRate
+	if err := db.Find(&rates).Error; err != nil {
+		return 0, err
+	}
+
+	if len(rates) == 0 {
+		return 0, nil
+	}
+
+	byClass := make(map[string]models.InterestRate, len(rates))
+	classes := make([]string, 0, len(rates))
+	for _, rate := range rates {
+		byClass[rate.Class] = rate
+		classes = append(classes, rate.Class)
+	}
+
+	var accounts []models.Account
+	if err := db.Where("type = ? AND class IN ? AND closed_at IS NULL", models.AccountTypeUser, classes).Order("id").Find(&accounts).Error; err != nil {
+		return 0, err
+	}
+
+	boundary := now.UTC().Truncate(day)
+	paid := 0
+	for _, account := range accounts {
+		rate := byClass[account.Class]
+
+		payments := 0
+		err := db.Transaction(func(tx *gorm.DB) error {
+			state, err := lockAccrual(tx, account.ID, boundary)
+			if err != nil {
+				return err
+			}
+
+			// Suspended users earn nothing while suspended: their days are
+			// skipped rather than paid out once they are reinstated.
+			var user models.User
+			if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).Select("id", "status").First(&user, *account.UserID).Error; err != nil {
+				return err
+			}
+
+			if user.Status == models.UserStatusSuspended {
+				state.AccruedThrough = boundary
+			} else if payments, err = accrue(tx, &account, &rate, state, boundary, true); err != nil {
+				return err
+			}
+
+			return tx.Model(state).Updates(map[string]interface{}{
+				"accrued_through": state.AccruedThrough,
+				"accrued":         state.Accrued,
+			}).Error
+		})
+		if err != nil {
+			return paid, fmt.Errorf("account %s: %w", account.Code, err)
+		}
+
+		paid += payments
+	}
+
+	return paid, nil
+}
+
+// Preview is the interest an account has earned and not been paid yet,
+// including days the job has not processed.
+type Preview struct {
+	Rate           *models.InterestRate
+	AccruedThrough time.Time
+	Accrued        int64
+}
+
+func PreviewAccount(db *gorm.DB, account *models.Account, now time.Time) (*Preview, error) {
+	var rate models.InterestRate
+	if err := db.Where("class = ?", account.Class).Limit(1).Find(&rate).Error; err != nil {
+		return nil, err
+	}
+
+	boundary := now.UTC().Truncate(day)
+	state := &models.InterestAccrual{AccountID: account.ID, AccruedThrough: boundary}
+	if err := db.Where("account_id = ?", account.ID).Limit(1).Find(state).Error; err != nil {
+		return nil, err
+	}
+
+	if rate.ID == 0 {
+		return &Preview{AccruedThrough: state.AccruedThrough, Accrued: state.Accrued}, nil
+	}
+
+	if _, err := accrue(db, account, &rate, state, boundary, false); err != nil {
+		return nil, err
+	}
+
+	return &Preview{Rate: &rate, AccruedThrough: state.AccruedThrough, Accrued: state.Accrued}, nil
+}
+
+// WholeUnits is the accrued amount in minor units, rounded down.
+func (p *Preview) WholeUnits() int64 {
+	return p.Accrued / microUnits
+}
+
+// lockAccrual returns the locked accrual state of the account. Accounts
+// seen for the first time start accruing at boundary.
+func lockAccrual(tx *gorm.DB, accountID uint, boundary time.Time) (*models.InterestAccrual, error) {
+	state := &models.InterestAccrual{AccountID: accountID, AccruedThrough: boundary}
+	if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(state).Error; err != nil {
+		return nil, err
+	}
+
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("account_id = ?", accountID).First(state).Error; err != nil {
+		return nil, err
+	}
+
+	return state, nil
+}
+
+// accrue adds the interest of every day from state.AccruedThrough up to
+// boundary, using the balance at the end of each day. Simple interest is
+// earned on the balance alone; compound interest also on the interest
+// accrued but not yet paid. When pay is set, the whole minor units accrued
+// are paid out after the last day of each month.
+func accrue(tx *gorm.DB, account *models.Account, rate *models.InterestRate, state *models.InterestAccrual, boundary time.Time, pay bool) (int, error) {
+	payments := 0
+
+	for d := state.AccruedThrough.UTC(); d.Before(boundary); d = d.Add(day) {
+		end := d.Add(day)
+
+		balance, err := ledger.BalanceAt(tx, account.ID, end)
+		if err != nil {
+			return payments, err
+		}
+
+		earned := dailyInterest(rate, d, balance, state.Accrued)
+		earned.Add(earned, big.NewInt(state.Accrued))
+		if !earned.IsInt64() {
+			return payments, ErrAccrualOutOfRange
+		}
+
+		state.Accrued = earned.Int64()
+		state.AccruedThrough = end
+
+		if pay && end.Day() == 1 {
+			paid, err := payOut(tx, account, state)
+			if err != nil {
+				return payments, err
+			}
+			if paid {
+				payments++
+			}
+		}
+	}
+
+	return payments, nil
+}
+
+func payOut(tx *gorm.DB, account *models.Account, state *models.InterestAccrual) (bool, error) {
+	amount := state.Accrued / microUnits
+	if amount <= 0 {
+		return false, nil
+	}
+
+	interestAccount, err := ledger.SystemAccount(tx, models.SystemAccountInterest)
+	if err != nil {
+		return false, err
+	}
+
+	period := state.AccruedThrough.AddDate(0, -1, 0)
+	entry := &models.JournalEntry{
+		Type: models.EntryTypeInterest,
+		Postings: []models.Transaction{
+			{AccountID: interestAccount.ID, Amount: -amount},
+			{AccountID: account.ID, UserID: account.UserID, Amount: amount},
+		},
+	}
+	entry.SetMetadata(models.EntryMetadata{
+		Description: fmt.Sprintf("Interest for %s", period.Format("January 2006")),
+		Category:    "interest",
+	})
+
+	if err := ledger.Post(tx, entry); err != nil {
+		return false, err
+	}
+
+	state.Accrued -= amount * microUnits
+	return true, nil
+}
+
+// dailyInterest is the interest, in accrual units, that day d earns on
+// the balance at its end and, for compound interest, on the interest
+// accrued but not yet paid. Negative balances earn nothing; fractions of
+// an accrual unit are dropped.
+func dailyInterest(rate *models.InterestRate, d time.Time, balance, accrued int64) *big.Int {
+	base := new(big.Int)
+	if balance > 0 {
+		base.Mul(big.NewInt(balance), big.NewInt(microUnits))
+	}
+	if rate.Method == models.InterestCompound {
+		base.Add(base, big.NewInt(accrued))
+	}
+
+	numerator, denominator := dayFraction(rate.DayCount, d)
+	earned := base.Mul(base, big.NewInt(rate.RatePPM*numerator))
+	return earned.Quo(earned, big.NewInt(microUnits*denominator))
+}
+
+// dayFraction is the share of a year one day counts for. Under 30/360
+// every month counts as 30 days, so the 31st earns nothing and the last
+// day of February makes up for the days February is short.
+func dayFraction(convention string, d time.Time) (int64, int64) {
+	switch convention {
+	case models.DayCountActual360:
+		return 1, 360
+	case models.DayCount30360:
+		if d.Day() == 31 {
+			return 0, 360
+		}
+		if d.Month() == time.February && d.AddDate(0, 0, 1).Month() == time.March {
+			return int64(31 - d.Day()), 360
+		}
+		return 1, 360
+	default:
+		return 1, 365
+	}
+}
+
+// SetRate creates or replaces the rate of an account class.
+func SetRate(tx *gorm.DB, class string, ratePPM int64, method, dayCount string, updatedBy uint) (*models.InterestRate, error) {
+	rate := &models.InterestRate{Class: class}
+	if err := tx.Where("class = ?", class).Limit(1).Find(rate).Error; err != nil {
+		return nil, err
+	}
+
+	rate.RatePPM = ratePPM
+	rate.Method = method
+	rate.DayCount = dayCount
+	rate.UpdatedBy = updatedBy
+
+	return rate, tx.Save(rate).Error
+}
+
+// SetAccountClass moves the user's account to another class. Days not yet
+// accrued are accrued at the rate of the new class.
+func SetAccountClass(tx *gorm.DB, userID uint, class string) (*models.Account, string, error) {
+	account, err := ledger.UserAccount(tx, userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	previous := account.Class
+	if err := tx.Model(account).Update("class", class).Error; err != nil {
+		return nil, "", err
+	}
+
+	return account, previous, nil
+}
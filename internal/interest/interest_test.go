@@ -0,0 +1,105 @@
+package interest
+
+import (
+	"ledger-app/models"
+	"testing"
+	"time"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// yearFraction adds up the fractions of every day from start up to end.
+func yearFraction(convention string, start, end time.Time) (int64, int64) {
+	var numerator, denominator int64
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		n, den := dayFraction(convention, d)
+		numerator += n
+		denominator = den
+	}
+
+	return numerator, denominator
+}
+
+func TestDayFractionSumsToTheConventionYear(t *testing.T) {
+	tests := []struct {
+		convention string
+		start, end time.Time
+		want       int64
+		per        int64
+	}{
+		{models.DayCountActual365, date(2023, 1, 1), date(2024, 1, 1), 365, 365},
+		{models.DayCountActual365, date(2024, 1, 1), date(2025, 1, 1), 366, 365},
+		{models.DayCountActual360, date(2023, 1, 1), date(2024, 1, 1), 365, 360},
+		{models.DayCount30360, date(2023, 1, 1), date(2024, 1, 1), 360, 360},
+		{models.DayCount30360, date(2024, 1, 1), date(2025, 1, 1), 360, 360},
+		{"", date(2023, 1, 1), date(2024, 1, 1), 365, 365},
+	}
+
+	for _, tt := range tests {
+		got, per := yearFraction(tt.convention, tt.start, tt.end)
+		if got != tt.want || per != tt.per {
+			t.Errorf("%q from %s: %d/%d, want %d/%d", tt.convention, tt.start.Format("2006-01-02"), got, per, tt.want, tt.per)
+		}
+	}
+}
+
+func TestDayFraction30360CountsEveryMonthAsThirtyDays(t *testing.T) {
+	for _, year := range []int{2023, 2024} {
+		for month := time.January; month <= time.December; month++ {
+			start := date(year, month, 1)
+			got, _ := yearFraction(models.DayCount30360, start, start.AddDate(0, 1, 0))
+			if got != 30 {
+				t.Errorf("%s counts %d days, want 30", start.Format("January 2006"), got)
+			}
+		}
+	}
+
+	tests := []struct {
+		day  time.Time
+		want int64
+	}{
+		{date(2023, 1, 31), 0},
+		{date(2023, 2, 28), 3},
+		{date(2024, 2, 28), 1},
+		{date(2024, 2, 29), 2},
+		{date(2023, 4, 30), 1},
+	}
+	for _, tt := range tests {
+		if got, _ := dayFraction(models.DayCount30360, tt.day); got != tt.want {
+			t.Errorf("%s counts %d days, want %d", tt.day.Format("2006-01-02"), got, tt.want)
+		}
+	}
+}
+
+func TestDailyInterest(t *testing.T) {
+	simple := &models.InterestRate{RatePPM: 36_500, Method: models.InterestSimple, DayCount: models.DayCountActual365}
+	compound := &models.InterestRate{RatePPM: 36_500, Method: models.InterestCompound, DayCount: models.DayCountActual365}
+	thirty := &models.InterestRate{RatePPM: 36_000, Method: models.InterestSimple, DayCount: models.DayCount30360}
+
+	tests := []struct {
+		name             string
+		rate             *models.InterestRate
+		day              time.Time
+		balance, accrued int64
+		want             int64
+	}{
+		// 3.65% of 100.00 over 365 days is a cent a day.
+		{"simple", simple, date(2023, 3, 1), 10000, 0, microUnits},
+		{"simple ignores accrued interest", simple, date(2023, 3, 1), 10000, 5 * microUnits, microUnits},
+		{"compound earns on accrued interest", compound, date(2023, 3, 1), 10000, 100 * microUnits, microUnits + 10_000},
+		{"negative balance earns nothing", simple, date(2023, 3, 1), -10000, 0, 0},
+		{"compound on a negative balance earns on accrued only", compound, date(2023, 3, 1), -10000, 100 * microUnits, 10_000},
+		{"fractions of a unit are dropped", simple, date(2023, 3, 1), 1, 0, 100},
+		{"the 31st earns nothing under 30/360", thirty, date(2023, 3, 31), 10000, 0, 0},
+		{"February makes up its short days", thirty, date(2023, 2, 28), 10000, 0, 3 * microUnits},
+	}
+
+	for _, tt := range tests {
+		got := dailyInterest(tt.rate, tt.day, tt.balance, tt.accrued)
+		if !got.IsInt64() || got.Int64() != tt.want {
+			t.Errorf("%s: dailyInterest = %s, want %d", tt.name, got, tt.want)
+		}
+	}
+}
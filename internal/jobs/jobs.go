@@ -0,0 +1,28 @@
+package jobs
+
+import (
+	"ledger-app/logger"
+	"time"
+)
+
+// Every runs fn in its own goroutine once per interval for the lifetime
+// of the process. Failures are logged and the job keeps its schedule.
+func Every(name string, interval time.Duration, fn func() error) {
+	if interval <= 0 {
+		logger.Logger.Warnf("Job %s disabled", name)
+		return
+	}
+
+	logger.Logger.Infof("Job %s scheduled every %s", name, interval)
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := fn(); err != nil {
+				logger.Logger.Errorf("Job %s failed: %v", name, err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,68 @@
+package journal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"ledger-app/internal/money"
+	"strconv"
+	"time"
+)
+
+type beancountWriter struct {
+	out      *bufio.Writer
+	currency money.Currency
+}
+
+func newBeancountWriter(w io.Writer, currency money.Currency) writer {
+	return &beancountWriter{out: bufio.NewWriter(w), currency: currency}
+}
+
+// Begin opens every account on the date of the first entry, since
+// Beancount rejects postings to accounts that are not open yet.
+func (w *beancountWriter) Begin(opened time.Time, accounts []Account) error {
+	fmt.Fprintf(w.out, "option \"operating_currency\" %s\n\n", strconv.Quote(w.currency.Code))
+
+	date := opened.Format("2006-01-02")
+	for _, account := range accounts {
+		fmt.Fprintf(w.out, "%s open %s %s\n", date, account.Name, w.currency.Code)
+		if account.UserName != "" {
+			fmt.Fprintf(w.out, "  name: %s\n", strconv.Quote(account.UserName))
+		}
+	}
+
+	_, err := fmt.Fprintln(w.out)
+	return err
+}
+
+func (w *beancountWriter) Entry(e *Entry) error {
+	fmt.Fprintf(w.out, "%s * %s", e.Time.Format("2006-01-02"), strconv.Quote(e.Description))
+	for _, tag := range e.Tags {
+		fmt.Fprintf(w.out, " #%s", tag)
+	}
+	fmt.Fprintln(w.out)
+
+	fmt.Fprintf(w.out, "  type: %s\n", strconv.Quote(e.Type))
+	fmt.Fprintf(w.out, "  time: %s\n", strconv.Quote(e.Time.Format(time.RFC3339Nano)))
+	fmt.Fprintf(w.out, "  entry: %s\n", strconv.Quote(entryTag(e)))
+	if e.Reference != "" {
+		fmt.Fprintf(w.out, "  reference: %s\n", strconv.Quote(e.Reference))
+	}
+	if e.Category != "" {
+		fmt.Fprintf(w.out, "  category: %s\n", strconv.Quote(e.Category))
+	}
+	if e.ReversalOfID != nil {
+		fmt.Fprintf(w.out, "  reversal-of: \"%d\"\n", *e.ReversalOfID)
+	}
+
+	for _, posting := range e.Postings {
+		fmt.Fprintf(w.out, "  %-40s  %s %s\n", posting.Account, money.Format(posting.Amount, w.currency), w.currency.Code)
+	}
+
+	_, err := fmt.Fprintln(w.out)
+	return err
+}
+
+func (w *beancountWriter) End() error {
+	return w.out.Flush()
+}
@@ -0,0 +1,79 @@
+package journal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"ledger-app/internal/money"
+	"strings"
+	"time"
+)
+
+type hledgerWriter struct {
+	out      *bufio.Writer
+	currency money.Currency
+}
+
+func newHledgerWriter(w io.Writer, currency money.Currency) writer {
+	return &hledgerWriter{out: bufio.NewWriter(w), currency: currency}
+}
+
+func (w *hledgerWriter) Begin(_ time.Time, accounts []Account) error {
+	fmt.Fprintf(w.out, "commodity %s %s\n\n", money.Format(0, w.currency), w.currency.Code)
+
+	for _, account := range accounts {
+		if account.UserName != "" {
+			fmt.Fprintf(w.out, "account %s  ; name:%s\n", account.Name, hledgerValue(account.UserName))
+			continue
+		}
+		fmt.Fprintf(w.out, "account %s\n", account.Name)
+	}
+
+	_, err := fmt.Fprintln(w.out)
+	return err
+}
+
+// Entry writes the entry with its details as hledger tags. The posting
+// time is kept in the time tag because hledger dates have no time of day.
+func (w *hledgerWriter) Entry(e *Entry) error {
+	fmt.Fprintf(w.out, "%s *", e.Time.Format("2006-01-02"))
+	if e.Reference != "" {
+		fmt.Fprintf(w.out, " (%s)", e.Reference)
+	}
+	if e.Description != "" {
+		fmt.Fprintf(w.out, " %s", strings.ReplaceAll(e.Description, ";", ","))
+	}
+	fmt.Fprintln(w.out)
+
+	tags := []string{
+		"type:" + e.Type,
+		"time:" + e.Time.Format(time.RFC3339Nano),
+		"entry:" + entryTag(e),
+	}
+	if e.Category != "" {
+		tags = append(tags, "category:"+e.Category)
+	}
+	if len(e.Tags) > 0 {
+		tags = append(tags, "tags:"+strings.Join(e.Tags, " "))
+	}
+	if e.ReversalOfID != nil {
+		tags = append(tags, fmt.Sprintf("reversal-of:%d", *e.ReversalOfID))
+	}
+	fmt.Fprintf(w.out, "    ; %s\n", strings.Join(tags, ", "))
+
+	for _, posting := range e.Postings {
+		fmt.Fprintf(w.out, "    %-40s  %s %s\n", posting.Account, money.Format(posting.Amount, w.currency), w.currency.Code)
+	}
+
+	_, err := fmt.Fprintln(w.out)
+	return err
+}
+
+func (w *hledgerWriter) End() error {
+	return w.out.Flush()
+}
+
+// hledgerValue keeps a tag value from running into the next tag.
+func hledgerValue(value string) string {
+	return strings.NewReplacer(",", " ", ";", " ").Replace(value)
+}
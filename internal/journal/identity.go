@@ -0,0 +1,29 @@
+package journal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/models"
+)
+
+// LedgerID returns the key naming this ledger in exported journals. It is
+// made up the first time it is needed and never changes afterwards.
+func LedgerID(db *gorm.DB) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	identity := models.LedgerIdentity{ID: models.LedgerIdentityID, Key: hex.EncodeToString(buf)}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&identity).Error; err != nil {
+		return "", err
+	}
+
+	if err := db.First(&identity, models.LedgerIdentityID).Error; err != nil {
+		return "", err
+	}
+
+	return identity.Key, nil
+}
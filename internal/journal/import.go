@@ -0,0 +1,387 @@
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
+	"ledger-app/internal/validation"
+	"ledger-app/models"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// disabledPassword is not a bcrypt hash, so no password matches it and
+// users created by an import cannot log in.
+const disabledPassword = "!"
+
+var errRejected = errors.New("journal import rejected")
+
+type ImportResult struct {
+	Entries      int         `json:"entries"`
+	Duplicates   int         `json:"duplicates"`
+	UsersCreated int         `json:"users_created"`
+	Committed    bool        `json:"committed"`
+	Errors       []LineError `json:"errors"`
+}
+
+type importer struct {
+	tx        *gorm.DB
+	ledgerID  string
+	reproduce bool
+	userNames map[uint]string
+	accounts  map[string]*models.Account
+	entryIDs  map[string]uint
+	result    *ImportResult
+}
+
+// Import reads an hledger journal or a Beancount file and posts its
+// transactions in date order. Users named by Assets:Users:U<id> accounts
+// are created with that ID when they do not exist yet; an existing user
+// must carry the name the file gives it. Transactions carrying an entry
+// tag are imported once: the tag names the ledger the file was exported
+// from and the entry's ID there, and entries already imported under the
+// same tag, or exported from this very ledger, are skipped and counted as
+// duplicates. Into an empty ledger balances are reproduced as written, so
+// overdrawn accounts are allowed and recorded as balance exceptions; into
+// a ledger in use the usual overdraft and user status rules apply.
+// Everything runs in one database transaction that is rolled back if any
+// transaction fails.
+func Import(db *gorm.DB, r io.Reader) (*ImportResult, error) {
+	p, err := parse(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ImportResult{Errors: p.errors}
+
+	entries := p.entries
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.Before(entries[j].Time) })
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var posted int64
+		if err := tx.Model(&models.JournalEntry{}).Count(&posted).Error; err != nil {
+			return err
+		}
+
+		ledgerID, err := LedgerID(tx)
+		if err != nil {
+			return err
+		}
+
+		im := &importer{
+			tx:        tx,
+			ledgerID:  ledgerID,
+			reproduce: posted == 0,
+			userNames: p.userNames,
+			accounts:  make(map[string]*models.Account),
+			entryIDs:  make(map[string]uint),
+			result:    result,
+		}
+
+		for i := range entries {
+			duplicate, err := im.post(&entries[i])
+			if err != nil {
+				result.Errors = append(result.Errors, LineError{Line: entries[i].Line, Error: err.Error()})
+				continue
+			}
+
+			if duplicate {
+				result.Duplicates++
+			} else {
+				result.Entries++
+			}
+		}
+
+		if len(result.Errors) > 0 {
+			return errRejected
+		}
+
+		return nil
+	})
+
+	if errors.Is(err, errRejected) {
+		sort.SliceStable(result.Errors, func(i, j int) bool { return result.Errors[i].Line < result.Errors[j].Line })
+		return result, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	result.Committed = true
+	return result, nil
+}
+
+// Volume returns the gross amount a journal posts to user accounts, the
+// figure an import is weighed by before it may run without approval.
+// Lines that do not parse are left to Import to report.
+func Volume(r io.Reader) (int64, error) {
+	p, err := parse(r)
+	if err != nil {
+		return 0, err
+	}
+
+	var volume int64
+	for _, entry := range p.entries {
+		for _, posting := range entry.Postings {
+			if !strings.HasPrefix(posting.Account, userAccountPrefix) {
+				continue
+			}
+
+			amount := posting.Amount
+			if amount < 0 {
+				amount = -amount
+			}
+			if volume, err = money.Add(volume, amount); err != nil {
+				return 0, err
+			}
+		}
+	}
+
+	return volume, nil
+}
+
+// post imports the entry and reports whether it was skipped as one
+// already imported.
+func (im *importer) post(e *parsedEntry) (bool, error) {
+	if e.ID != 0 {
+		existingID, err := im.imported(e.Source, e.ID)
+		if err != nil {
+			return false, err
+		}
+		if existingID != 0 {
+			return true, nil
+		}
+	}
+
+	entryType := e.Type
+	if entryType == "" {
+		entryType = models.EntryTypeImport
+	}
+
+	if !slices.Contains(models.EntryTypes, entryType) {
+		return false, fmt.Errorf("invalid type %q", entryType)
+	}
+
+	meta := models.EntryMetadata{
+		Description: e.Description,
+		Reference:   e.Reference,
+		Category:    e.Category,
+		Tags:        e.Tags,
+	}
+	if err := validation.ValidateStruct().Struct(meta); err != nil {
+		return false, fmt.Errorf("invalid description, reference, category or tags: %w", err)
+	}
+
+	entry := &models.JournalEntry{Type: entryType, PostedAt: e.Time}
+	entry.SetMetadata(meta)
+	if e.ID != 0 {
+		key := importKey(e.Source, e.ID)
+		entry.ImportKey = &key
+	}
+
+	for _, posting := range e.Postings {
+		account, err := im.account(posting.Account)
+		if err != nil {
+			return false, err
+		}
+
+		entry.Postings = append(entry.Postings, models.Transaction{
+			AccountID: account.ID,
+			UserID:    account.UserID,
+			Amount:    posting.Amount,
+		})
+	}
+
+	linkTransfer(entry)
+
+	switch {
+	case e.ReversalOfID != nil:
+		originalID, err := im.imported(e.Source, *e.ReversalOfID)
+		if err != nil {
+			return false, err
+		}
+		if originalID == 0 {
+			return false, fmt.Errorf("reversed entry %d has not been imported", *e.ReversalOfID)
+		}
+		entry.ReversalOfID = &originalID
+	case entryType == models.EntryTypeReversal:
+		return false, errors.New("reversal without the entry it reverses")
+	}
+
+	var opts ledger.PostOptions
+	if im.reproduce {
+		opts = ledger.PostOptions{AllowNegative: true, ExceptionReason: "journal import"}
+	} else if err := checkStatus(im.tx, entry); err != nil {
+		return false, err
+	}
+
+	err := im.tx.Transaction(func(tx *gorm.DB) error {
+		err := ledger.PostWithOptions(tx, entry, opts)
+		if err != nil || entry.ReversalOfID == nil {
+			return err
+		}
+
+		return tx.Model(&models.JournalEntry{}).
+			Where("id = ?", *entry.ReversalOfID).
+			Update("reversed_amount", gorm.Expr("reversed_amount + ?", entry.Amount())).Error
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if e.ID != 0 {
+		im.entryIDs[importKey(e.Source, e.ID)] = entry.ID
+	}
+
+	return false, nil
+}
+
+// checkStatus checks that every user the entry debits may send money and
+// every user it credits may receive it, in ascending user ID order.
+func checkStatus(tx *gorm.DB, entry *models.JournalEntry) error {
+	postings := slices.Clone(entry.Postings)
+	sort.Slice(postings, func(i, j int) bool {
+		return postings[i].UserID != nil && (postings[j].UserID == nil || *postings[i].UserID < *postings[j].UserID)
+	})
+
+	for _, posting := range postings {
+		if posting.UserID == nil {
+			continue
+		}
+
+		var err error
+		if posting.Amount < 0 {
+			err = ledger.CheckStatus(tx, *posting.UserID, 0)
+		} else {
+			err = ledger.CheckStatus(tx, 0, *posting.UserID)
+		}
+		if err != nil {
+			return fmt.Errorf("user %d: %w", *posting.UserID, err)
+		}
+	}
+
+	return nil
+}
+
+// imported returns the ID the entry with the given entry tag was imported
+// as, or zero if it has not been. An entry exported from this ledger is
+// the entry with that ID itself.
+func (im *importer) imported(source string, sourceID uint) (uint, error) {
+	key := importKey(source, sourceID)
+	if id, ok := im.entryIDs[key]; ok {
+		return id, nil
+	}
+
+	query := im.tx.Model(&models.JournalEntry{}).Where("import_key = ?", key)
+	if source == im.ledgerID {
+		query = im.tx.Model(&models.JournalEntry{}).Where("id = ?", sourceID)
+	}
+
+	var ids []uint
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	im.entryIDs[key] = ids[0]
+	return ids[0], nil
+}
+
+// importKey identifies an imported entry by the ledger it was exported
+// from and its ID there.
+func importKey(source string, sourceID uint) string {
+	if source == "" {
+		return fmt.Sprintf("entry:%d", sourceID)
+	}
+
+	return fmt.Sprintf("entry:%s:%d", source, sourceID)
+}
+
+func (im *importer) account(name string) (*models.Account, error) {
+	if account, ok := im.accounts[name]; ok {
+		return account, nil
+	}
+
+	code, userID, err := accountCode(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var account *models.Account
+	if userID != 0 {
+		if err := im.ensureUser(userID); err != nil {
+			return nil, err
+		}
+		account, err = ledger.UserAccount(im.tx, userID)
+	} else {
+		account, err = ledger.SystemAccount(im.tx, code)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	im.accounts[name] = account
+	return account, nil
+}
+
+func (im *importer) ensureUser(userID uint) error {
+	name, named := im.userNames[userID]
+	if (&models.User{Name: name, PasswordHash: disabledPassword}).Validate() != nil {
+		name = fmt.Sprintf("U%d", userID)
+	}
+
+	var user models.User
+	err := im.tx.First(&user, userID).Error
+	if err == nil {
+		// The ID alone does not say it is the same person; money the
+		// journal names for someone else must not land in this account.
+		if named && user.Name != name {
+			return fmt.Errorf("user %d is %s here but %s in the journal", userID, user.Name, name)
+		}
+		return nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	var taken int64
+	if err := im.tx.Model(&models.User{}).Where("name = ?", name).Count(&taken).Error; err != nil {
+		return err
+	}
+	if taken > 0 {
+		return fmt.Errorf("cannot create user %d: the name %s is taken", userID, name)
+	}
+
+	user = models.User{ID: userID, Name: name, PasswordHash: disabledPassword}
+	if err := im.tx.Create(&user).Error; err != nil {
+		return err
+	}
+
+	im.result.UsersCreated++
+	return nil
+}
+
+// linkTransfer fills in the sender and receiver of an entry that moves
+// money between exactly two users, as ledger.Transfer does.
+func linkTransfer(entry *models.JournalEntry) {
+	if len(entry.Postings) != 2 || entry.Postings[0].UserID == nil || entry.Postings[1].UserID == nil {
+		return
+	}
+
+	sender, receiver := entry.Postings[0].UserID, entry.Postings[1].UserID
+	if entry.Postings[0].Amount > 0 {
+		sender, receiver = receiver, sender
+	}
+
+	for i := range entry.Postings {
+		entry.Postings[i].SenderID = sender
+		entry.Postings[i].ReceiverID = receiver
+	}
+}
@@ -0,0 +1,274 @@
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"io"
+	"ledger-app/internal/money"
+	"ledger-app/models"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	FormatHledger   = "hledger"
+	FormatBeancount = "beancount"
+)
+
+var ErrUnknownFormat = errors.New("unknown journal format, use hledger or beancount")
+
+// userAccountPrefix names user accounts, e.g. Assets:Users:U42 for the
+// account of user 42.
+const userAccountPrefix = "Assets:Users:U"
+
+// systemPrefix names system accounts that have no entry in systemAccounts.
+const systemPrefix = "Equity:System:"
+
+var systemAccounts = map[string]string{
+	models.SystemAccountIssuance:    "Equity:Issuance",
+	models.SystemAccountWithdrawals: "Expenses:Withdrawals",
+	models.SystemAccountMigration:   "Equity:Migration",
+	models.SystemAccountInterest:    "Expenses:Interest",
+	models.SystemAccountFeeRevenue:  "Income:Fees",
+	models.SystemAccountClosed:      "Liabilities:ClosedAccounts",
+}
+
+// Account is a ledger account as it is declared in a journal.
+type Account struct {
+	Name     string
+	UserName string
+}
+
+// Entry is one journal entry with its postings keyed by account name.
+// Source is the ID of the ledger the entry was exported from; it is empty
+// for files that do not say, such as hand-written ones.
+type Entry struct {
+	ID           uint
+	Source       string
+	Type         string
+	Time         time.Time
+	Description  string
+	Reference    string
+	Category     string
+	Tags         []string
+	ReversalOfID *uint
+	Postings     []Posting
+}
+
+type Posting struct {
+	Account string
+	Amount  int64
+}
+
+// writer renders a journal one entry at a time.
+type writer interface {
+	Begin(opened time.Time, accounts []Account) error
+	Entry(e *Entry) error
+	End() error
+}
+
+type format struct {
+	extension string
+	newWriter func(w io.Writer, currency money.Currency) writer
+}
+
+var formats = map[string]format{
+	FormatHledger:   {extension: "journal", newWriter: newHledgerWriter},
+	FormatBeancount: {extension: "beancount", newWriter: newBeancountWriter},
+}
+
+// Extension returns the usual file extension of a format.
+func Extension(name string) (string, error) {
+	f, ok := formats[name]
+	if !ok {
+		return "", ErrUnknownFormat
+	}
+
+	return f.extension, nil
+}
+
+// Export writes the whole ledger, or only the entries touching the account
+// of userID when it is not nil, as a journal in the named format. Entries
+// are written complete with the postings of other accounts so that every
+// transaction in the file balances. It returns the number of entries.
+func Export(db *gorm.DB, w io.Writer, name string, userID *uint) (int, error) {
+	f, ok := formats[name]
+	if !ok {
+		return 0, ErrUnknownFormat
+	}
+
+	entries := db.Model(&models.JournalEntry{})
+	accounts := db.Model(&models.Account{})
+
+	if userID != nil {
+		touched := db.Model(&models.Transaction{}).
+			Select("transactions.journal_entry_id").
+			Joins("JOIN accounts ON accounts.id = transactions.account_id").
+			Where("accounts.user_id = ?", *userID)
+
+		entries = entries.Where("id IN (?)", touched)
+		accounts = accounts.Where("id IN (?)", db.Model(&models.Transaction{}).
+			Select("account_id").
+			Where("journal_entry_id IN (?)", touched))
+	}
+
+	var ledgerAccounts []models.Account
+	if err := accounts.Order("id").Find(&ledgerAccounts).Error; err != nil {
+		return 0, err
+	}
+
+	names, declared, err := accountNames(db, ledgerAccounts)
+	if err != nil {
+		return 0, err
+	}
+
+	source, err := LedgerID(db)
+	if err != nil {
+		return 0, err
+	}
+
+	var first *time.Time
+	if err := entries.Session(&gorm.Session{}).Select("MIN(posted_at)").Scan(&first).Error; err != nil {
+		return 0, err
+	}
+
+	opened := time.Now().UTC()
+	if first != nil {
+		opened = first.UTC()
+	}
+
+	out := f.newWriter(w, money.Default)
+	if err := out.Begin(opened, declared); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	var batch []models.JournalEntry
+	err = entries.Preload("Postings").Preload("Tags").
+		FindInBatches(&batch, 500, func(_ *gorm.DB, _ int) error {
+			for i := range batch {
+				entry := toEntry(&batch[i], source, names)
+				if err := out.Entry(&entry); err != nil {
+					return err
+				}
+				count++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	return count, out.End()
+}
+
+func accountNames(db *gorm.DB, accounts []models.Account) (map[uint]string, []Account, error) {
+	userIDs := make([]uint, 0, len(accounts))
+	for _, account := range accounts {
+		if account.UserID != nil {
+			userIDs = append(userIDs, *account.UserID)
+		}
+	}
+
+	var users []models.User
+	if len(userIDs) > 0 {
+		if err := db.Select("id", "name").Where("id IN ?", userIDs).Find(&users).Error; err != nil {
+			return nil, nil, err
+		}
+	}
+
+	userNames := make(map[uint]string, len(users))
+	for _, user := range users {
+		userNames[user.ID] = user.Name
+	}
+
+	names := make(map[uint]string, len(accounts))
+	declared := make([]Account, 0, len(accounts))
+	for _, account := range accounts {
+		declaration := Account{Name: AccountName(&account)}
+		if account.UserID != nil {
+			declaration.UserName = userNames[*account.UserID]
+		}
+
+		names[account.ID] = declaration.Name
+		declared = append(declared, declaration)
+	}
+
+	sort.Slice(declared, func(i, j int) bool { return declared[i].Name < declared[j].Name })
+	return names, declared, nil
+}
+
+// entryTag is the value of the entry tag: the entry ID, prefixed with
+// the ID of the ledger it comes from when that is known.
+func entryTag(e *Entry) string {
+	if e.Source == "" {
+		return strconv.FormatUint(uint64(e.ID), 10)
+	}
+
+	return e.Source + ":" + strconv.FormatUint(uint64(e.ID), 10)
+}
+
+func toEntry(entry *models.JournalEntry, source string, names map[uint]string) Entry {
+	e := Entry{
+		ID:           entry.ID,
+		Source:       source,
+		Type:         entry.Type,
+		Time:         entry.PostedAt.UTC(),
+		Description:  entry.Description,
+		Reference:    entry.Reference,
+		Category:     entry.Category,
+		ReversalOfID: entry.ReversalOfID,
+	}
+
+	for _, tag := range entry.Tags {
+		e.Tags = append(e.Tags, tag.Tag)
+	}
+	sort.Strings(e.Tags)
+
+	for _, posting := range entry.Postings {
+		e.Postings = append(e.Postings, Posting{Account: names[posting.AccountID], Amount: posting.Amount})
+	}
+
+	return e
+}
+
+// AccountName is the name an account is written under in a journal.
+func AccountName(account *models.Account) string {
+	if account.Type == models.AccountTypeUser && account.UserID != nil {
+		return userAccountPrefix + strconv.FormatUint(uint64(*account.UserID), 10)
+	}
+
+	if name, ok := systemAccounts[account.Code]; ok {
+		return name
+	}
+
+	code := strings.TrimPrefix(account.Code, "system:")
+	return systemPrefix + strings.ToUpper(code[:1]) + code[1:]
+}
+
+// accountCode maps a journal account name back to a ledger account. User
+// accounts return their user ID and an empty code.
+func accountCode(name string) (string, uint, error) {
+	if strings.HasPrefix(name, userAccountPrefix) {
+		id, err := strconv.ParseUint(strings.TrimPrefix(name, userAccountPrefix), 10, 64)
+		if err != nil || id == 0 {
+			return "", 0, fmt.Errorf("invalid user account %q", name)
+		}
+		return "", uint(id), nil
+	}
+
+	for code, accountName := range systemAccounts {
+		if accountName == name {
+			return code, 0, nil
+		}
+	}
+
+	if code := strings.TrimPrefix(name, systemPrefix); code != name && code != "" {
+		return "system:" + strings.ToLower(code[:1]) + code[1:], 0, nil
+	}
+
+	return "", 0, fmt.Errorf("unknown account %q", name)
+}
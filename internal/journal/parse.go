@@ -0,0 +1,402 @@
+package journal
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"ledger-app/internal/money"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LineError reports a journal transaction that could not be read or posted.
+type LineError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// directives are the dated Beancount directives that are not transactions.
+var directives = map[string]bool{
+	"open": true, "close": true, "balance": true, "pad": true, "note": true, "document": true,
+	"price": true, "event": true, "custom": true, "query": true, "commodity": true,
+}
+
+var (
+	metadataPattern = regexp.MustCompile(`^([a-z][A-Za-z0-9_-]*):(?:\s+(.*))?$`)
+	hledgerTag      = regexp.MustCompile(`([^\s,:]+):([^,]*)`)
+)
+
+var dateLayouts = []string{"2006-1-2", "2006/1/2", "2006.1.2"}
+
+type parsedEntry struct {
+	Entry
+	Line int
+}
+
+// parser reads both hledger journals and Beancount files. It understands
+// the subset of either syntax that Export writes, plus plain hand-written
+// transactions with one elided amount.
+type parser struct {
+	entries   []parsedEntry
+	userNames map[uint]string
+	errors    []LineError
+
+	current *parsedEntry
+	failed  bool
+	elided  int
+
+	openAccount string
+}
+
+func parse(r io.Reader) (*parser, error) {
+	p := &parser{userNames: make(map[uint]string)}
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(text)
+
+		if trimmed == "" {
+			p.finish()
+			p.openAccount = ""
+			continue
+		}
+
+		if text[0] == ' ' || text[0] == '\t' {
+			switch {
+			case p.current != nil:
+				p.entryLine(line, trimmed)
+			case p.openAccount != "":
+				if match := metadataPattern.FindStringSubmatch(trimmed); match != nil && match[1] == "name" {
+					p.setUserName(p.openAccount, unquote(match[2]))
+				}
+			}
+			continue
+		}
+
+		p.finish()
+		p.openAccount = ""
+
+		switch {
+		case strings.ContainsRune(";#*%|", rune(text[0])):
+		case text[0] >= '0' && text[0] <= '9':
+			p.datedLine(line, trimmed)
+		case strings.HasPrefix(trimmed, "account "):
+			name, comment, _ := strings.Cut(strings.TrimPrefix(trimmed, "account "), ";")
+			if tags := hledgerTags(comment); tags["name"] != "" {
+				p.setUserName(strings.TrimSpace(name), tags["name"])
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	p.finish()
+	return p, nil
+}
+
+func (p *parser) datedLine(line int, text string) {
+	fields := strings.Fields(text)
+
+	if len(fields) > 1 && directives[fields[1]] {
+		if fields[1] == "open" && len(fields) > 2 {
+			p.openAccount = fields[2]
+		}
+		return
+	}
+
+	dateText, _, _ := strings.Cut(fields[0], "=")
+	date, err := parseDate(dateText)
+	if err != nil {
+		p.fail(line, err)
+		return
+	}
+
+	p.current = &parsedEntry{Line: line, Entry: Entry{Time: date}}
+	p.failed = false
+	p.elided = -1
+
+	header, comment := splitComment(strings.TrimSpace(text[len(fields[0]):]))
+	if status, rest, _ := strings.Cut(header, " "); status == "*" || status == "!" || status == "txn" {
+		header = strings.TrimSpace(rest)
+	}
+
+	if strings.HasPrefix(header, "(") {
+		if end := strings.IndexByte(header, ')'); end > 0 {
+			p.current.Reference = header[1:end]
+			header = strings.TrimSpace(header[end+1:])
+		}
+	}
+
+	if strings.HasPrefix(header, `"`) {
+		if err := p.beancountHeader(header); err != nil {
+			p.fail(line, err)
+			return
+		}
+	} else {
+		p.current.Description = header
+	}
+
+	for key, value := range hledgerTags(comment) {
+		if err := p.setField(key, value); err != nil {
+			p.fail(line, err)
+			return
+		}
+	}
+}
+
+// beancountHeader reads the payee and narration strings followed by tags
+// and links.
+func (p *parser) beancountHeader(header string) error {
+	var texts []string
+
+	for header != "" {
+		if header[0] == '"' {
+			quoted, err := strconv.QuotedPrefix(header)
+			if err != nil {
+				return errors.New("unterminated string in transaction header")
+			}
+			texts = append(texts, unquote(quoted))
+			header = strings.TrimSpace(header[len(quoted):])
+			continue
+		}
+
+		token, rest, _ := strings.Cut(header, " ")
+		if strings.HasPrefix(token, "#") {
+			p.current.Tags = append(p.current.Tags, token[1:])
+		}
+		header = strings.TrimSpace(rest)
+	}
+
+	p.current.Description = strings.Join(texts, " | ")
+	return nil
+}
+
+func (p *parser) entryLine(line int, text string) {
+	if p.failed {
+		return
+	}
+
+	if strings.HasPrefix(text, ";") {
+		for key, value := range hledgerTags(text[1:]) {
+			if err := p.setField(key, value); err != nil {
+				p.fail(line, err)
+				return
+			}
+		}
+		return
+	}
+
+	if match := metadataPattern.FindStringSubmatch(text); match != nil {
+		if err := p.setField(match[1], unquote(match[2])); err != nil {
+			p.fail(line, err)
+		}
+		return
+	}
+
+	text, _ = splitComment(text)
+	text = strings.TrimPrefix(strings.TrimPrefix(text, "! "), "* ")
+
+	account, amountText := splitPosting(text)
+	if strings.HasPrefix(account, "(") || strings.HasPrefix(account, "[") {
+		p.fail(line, errors.New("virtual postings are not supported"))
+		return
+	}
+
+	amountText, _, _ = strings.Cut(amountText, "=")
+	amountText = strings.TrimSpace(amountText)
+	if strings.ContainsAny(amountText, "@{") {
+		p.fail(line, errors.New("costs and prices are not supported"))
+		return
+	}
+
+	posting := Posting{Account: account}
+	if amountText == "" {
+		if p.elided >= 0 {
+			p.fail(line, errors.New("only one posting may leave out its amount"))
+			return
+		}
+		p.elided = len(p.current.Postings)
+	} else {
+		amount, err := parseAmount(amountText)
+		if err != nil {
+			p.fail(line, err)
+			return
+		}
+		posting.Amount = amount
+	}
+
+	p.current.Postings = append(p.current.Postings, posting)
+}
+
+func (p *parser) setField(key, value string) error {
+	e := p.current
+	value = strings.TrimSpace(value)
+
+	switch key {
+	case "type":
+		e.Type = value
+	case "time":
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return fmt.Errorf("invalid time %q", value)
+		}
+		e.Time = t.UTC()
+	case "entry", "reversal-of":
+		// An entry names the ledger it was exported from before its ID.
+		if i := strings.LastIndex(value, ":"); key == "entry" && i >= 0 {
+			e.Source, value = value[:i], value[i+1:]
+		}
+
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", key, value)
+		}
+		if key == "entry" {
+			e.ID = uint(id)
+		} else {
+			reversalOf := uint(id)
+			e.ReversalOfID = &reversalOf
+		}
+	case "reference":
+		e.Reference = value
+	case "category":
+		e.Category = value
+	case "tags":
+		e.Tags = append(e.Tags, strings.Fields(value)...)
+	}
+
+	return nil
+}
+
+func (p *parser) setUserName(account, name string) {
+	if _, userID, err := accountCode(account); err == nil && userID != 0 {
+		p.userNames[userID] = name
+	}
+}
+
+func (p *parser) fail(line int, err error) {
+	p.errors = append(p.errors, LineError{Line: line, Error: err.Error()})
+	p.failed = true
+}
+
+// finish completes the transaction being read, filling in the elided
+// amount so that the postings balance.
+func (p *parser) finish() {
+	e := p.current
+	p.current = nil
+
+	if e == nil || p.failed {
+		return
+	}
+
+	if p.elided >= 0 {
+		var total int64
+		for _, posting := range e.Postings {
+			total += posting.Amount
+		}
+		e.Postings[p.elided].Amount = -total
+	}
+
+	p.entries = append(p.entries, *e)
+}
+
+func parseDate(value string) (time.Time, error) {
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("invalid date %q", value)
+}
+
+// parseAmount reads "12.34 USD", "USD 12.34" or a bare "12.34" in the
+// ledger currency.
+func parseAmount(value string) (int64, error) {
+	fields := strings.Fields(value)
+
+	number := ""
+	commodity := money.Default.Code
+	switch len(fields) {
+	case 1:
+		number = fields[0]
+	case 2:
+		number, commodity = fields[0], fields[1]
+		if _, err := strconv.ParseFloat(strings.ReplaceAll(fields[1], ",", ""), 64); err == nil {
+			number, commodity = fields[1], fields[0]
+		}
+	default:
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+
+	if commodity != money.Default.Code {
+		return 0, fmt.Errorf("commodity %s is not the ledger currency %s", commodity, money.Default.Code)
+	}
+
+	number = strings.TrimPrefix(strings.ReplaceAll(number, ",", ""), "+")
+	return money.Parse(number, money.Default)
+}
+
+// splitPosting separates the account from the amount. hledger account
+// names may contain single spaces, so two spaces or a tab end the name
+// when present.
+func splitPosting(text string) (string, string) {
+	end := strings.Index(text, "  ")
+	if tab := strings.IndexByte(text, '\t'); tab >= 0 && (end < 0 || tab < end) {
+		end = tab
+	}
+	if end < 0 {
+		end = strings.IndexByte(text, ' ')
+	}
+	if end < 0 {
+		return text, ""
+	}
+
+	return strings.TrimSpace(text[:end]), strings.TrimSpace(text[end:])
+}
+
+// splitComment cuts a line at the first semicolon outside a quoted string.
+func splitComment(text string) (string, string) {
+	quoted := false
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			quoted = !quoted
+		case ';':
+			if !quoted {
+				return strings.TrimSpace(text[:i]), text[i+1:]
+			}
+		}
+	}
+
+	return text, ""
+}
+
+func hledgerTags(comment string) map[string]string {
+	tags := make(map[string]string)
+	for _, match := range hledgerTag.FindAllStringSubmatch(comment, -1) {
+		tags[match[1]] = strings.TrimSpace(match[2])
+	}
+
+	return tags
+}
+
+func unquote(value string) string {
+	if unquoted, err := strconv.Unquote(strings.TrimSpace(value)); err == nil {
+		return unquoted
+	}
+
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,30 @@
+package ledger
+
+import (
+	"gorm.io/gorm"
+	"ledger-app/models"
+	"time"
+)
+
+// BalanceAt sums the account's postings made strictly before at, starting
+// from the latest snapshot taken at or before at.
+func BalanceAt(db *gorm.DB, accountID uint, at time.Time) (int64, error) {
+	var snapshot models.BalanceSnapshot
+	if err := db.Where("account_id = ? AND at <= ?", accountID, at).
+		Order("at DESC").
+		Limit(1).
+		Find(&snapshot).Error; err != nil {
+		return 0, err
+	}
+
+	query := db.Model(&models.Transaction{}).
+		Where("account_id = ? AND transaction_time < ?", accountID, at)
+	if snapshot.ID != 0 {
+		query = query.Where("transaction_time >= ?", snapshot.At)
+	}
+
+	var sum int64
+	err := query.Select("COALESCE(SUM(amount), 0)").Scan(&sum).Error
+
+	return snapshot.Balance + sum, err
+}
@@ -0,0 +1,279 @@
+package ledger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"hash"
+	"ledger-app/models"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var errChainBroken = errors.New("chain broken")
+
+// chainKey keys the posting hashes when set, so that rewriting the chain
+// needs the key and not just database access.
+var chainKey []byte
+
+func SetChainKey(key string) {
+	chainKey = []byte(key)
+}
+
+// ChainBreak describes the first link of an account chain that does not
+// verify, or an entry whose reversed total does not add up.
+type ChainBreak struct {
+	AccountID      uint   `json:"account_id,omitempty"`
+	AccountCode    string `json:"account_code,omitempty"`
+	TransactionID  uint   `json:"transaction_id,omitempty"`
+	JournalEntryID uint   `json:"journal_entry_id,omitempty"`
+	Reason         string `json:"reason"`
+}
+
+func (b *ChainBreak) String() string {
+	if b.AccountCode == "" {
+		return fmt.Sprintf("entry %d: %s", b.JournalEntryID, b.Reason)
+	}
+
+	return fmt.Sprintf("account %s at transaction %d: %s", b.AccountCode, b.TransactionID, b.Reason)
+}
+
+// chainPostings links every posting of the entry to the previous posting
+// of its account. The accounts must be locked, so each chain only ever
+// grows from one writer at a time.
+func chainPostings(entry *models.JournalEntry, accounts []models.Account) {
+	byID := make(map[uint]*models.Account, len(accounts))
+	for i := range accounts {
+		byID[accounts[i].ID] = &accounts[i]
+	}
+
+	for i := range entry.Postings {
+		posting := &entry.Postings[i]
+		account := byID[posting.AccountID]
+
+		posting.PrevHash = account.ChainHash
+		posting.Hash = postingHash(posting, entry)
+		account.ChainHash = posting.Hash
+	}
+}
+
+// startChains marks the first chained posting of accounts that had none.
+// Every posting of the account from there on must carry a hash; only the
+// ones before it may predate chaining. It runs once the postings have
+// their IDs.
+func startChains(entry *models.JournalEntry, accounts []models.Account) {
+	for i := range accounts {
+		account := &accounts[i]
+		if account.ChainStartID != nil {
+			continue
+		}
+
+		for _, posting := range entry.Postings {
+			if posting.AccountID == account.ID {
+				id := posting.ID
+				account.ChainStartID = &id
+				break
+			}
+		}
+	}
+}
+
+// postingHash covers every column of the posting except its ID, whose
+// order is protected by the chain itself, and the fixed columns of its
+// entry, so rewriting what an entry says it was is caught as well. The
+// reversed total of an entry changes with every refund and is checked
+// against the reversals instead.
+func postingHash(posting *models.Transaction, entry *models.JournalEntry) string {
+	var h hash.Hash
+	if len(chainKey) > 0 {
+		h = hmac.New(sha256.New, chainKey)
+	} else {
+		h = sha256.New()
+	}
+
+	tags := make([]string, 0, len(entry.Tags))
+	for _, tag := range entry.Tags {
+		tags = append(tags, tag.Tag)
+	}
+	sort.Strings(tags)
+
+	fmt.Fprintf(h, "%d|%d|%s|%d|%d|%s|%s|%s\n",
+		posting.JournalEntryID,
+		posting.AccountID,
+		optionalID(posting.UserID),
+		posting.Amount,
+		posting.TransactionTime.Unix(),
+		optionalID(posting.SenderID),
+		optionalID(posting.ReceiverID),
+		posting.PrevHash)
+
+	fmt.Fprintf(h, "%q|%s|%s|%q|%q|%q|%q",
+		entry.Type,
+		optionalID(entry.ReversalOfID),
+		optionalID(entry.FeeForID),
+		entry.Description,
+		entry.Reference,
+		entry.Category,
+		strings.Join(tags, ","))
+
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func optionalID(id *uint) string {
+	if id == nil {
+		return "-"
+	}
+
+	return strconv.FormatUint(uint64(*id), 10)
+}
+
+// VerifyChain walks the postings of every account in ID order and
+// recomputes each hash. Postings written before chaining was introduced
+// have no hash and are only accepted ahead of the chain start recorded on
+// the account. The last hash must match the head stored on the account,
+// which catches postings deleted from the end. Reversed totals are then
+// checked against the reversals posted. It returns the first break found,
+// or nil, and the number of postings checked.
+func VerifyChain(db *gorm.DB) (*ChainBreak, int, error) {
+	var accounts []models.Account
+	if err := db.Order("id").Find(&accounts).Error; err != nil {
+		return nil, 0, err
+	}
+
+	checked := 0
+	for _, account := range accounts {
+		chainBreak, count, err := verifyAccountChain(db, &account)
+		checked += count
+		if err != nil || chainBreak != nil {
+			return chainBreak, checked, err
+		}
+	}
+
+	chainBreak, err := verifyReversedAmounts(db)
+	return chainBreak, checked, err
+}
+
+func verifyAccountChain(db *gorm.DB, account *models.Account) (*ChainBreak, int, error) {
+	var chainBreak *ChainBreak
+	brokenAt := func(posting *models.Transaction, reason string) {
+		chainBreak = &ChainBreak{AccountID: account.ID, AccountCode: account.Code, Reason: reason}
+		if posting != nil {
+			chainBreak.TransactionID = posting.ID
+		}
+	}
+
+	previous := ""
+	checked := 0
+
+	var batch []models.Transaction
+	err := db.Where("account_id = ?", account.ID).
+		FindInBatches(&batch, 1000, func(tx *gorm.DB, _ int) error {
+			entries, err := loadEntries(db, batch)
+			if err != nil {
+				return err
+			}
+
+			for i := range batch {
+				posting := &batch[i]
+				checked++
+
+				legacy := account.ChainStartID == nil || posting.ID < *account.ChainStartID
+				switch {
+				case legacy && posting.Hash == "":
+					continue
+				case legacy:
+					brokenAt(posting, "hashed posting before the start of the account chain")
+				case posting.Hash == "":
+					brokenAt(posting, "posting has no hash")
+				case posting.PrevHash != previous:
+					brokenAt(posting, "previous hash does not match the preceding posting")
+				case postingHash(posting, entries[posting.JournalEntryID]) != posting.Hash:
+					brokenAt(posting, "posting or entry content does not match its hash")
+				}
+
+				if chainBreak != nil {
+					return errChainBroken
+				}
+
+				previous = posting.Hash
+			}
+			return nil
+		}).Error
+
+	if errors.Is(err, errChainBroken) {
+		return chainBreak, checked, nil
+	}
+	if err != nil {
+		return nil, checked, err
+	}
+
+	if account.ChainStartID != nil && previous == "" {
+		brokenAt(nil, "account chain start has no chained postings")
+	} else if account.ChainHash != previous {
+		brokenAt(nil, "account chain head does not match its last posting")
+	}
+
+	return chainBreak, checked, nil
+}
+
+// loadEntries returns the entries, with their tags, of a batch of postings.
+func loadEntries(db *gorm.DB, postings []models.Transaction) (map[uint]*models.JournalEntry, error) {
+	ids := make([]uint, 0, len(postings))
+	for _, posting := range postings {
+		ids = append(ids, posting.JournalEntryID)
+	}
+
+	var entries []models.JournalEntry
+	if err := db.Preload("Tags").Where("id IN ?", ids).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	byID := make(map[uint]*models.JournalEntry, len(entries))
+	for i := range entries {
+		byID[entries[i].ID] = &entries[i]
+	}
+
+	for _, id := range ids {
+		if byID[id] == nil {
+			// A posting whose entry is gone hashes against an empty header
+			// and fails to verify.
+			byID[id] = &models.JournalEntry{ID: id}
+		}
+	}
+
+	return byID, nil
+}
+
+// verifyReversedAmounts checks that every entry's reversed total equals
+// what its reversals actually moved.
+func verifyReversedAmounts(db *gorm.DB) (*ChainBreak, error) {
+	var mismatch struct {
+		ID             uint
+		ReversedAmount int64
+		Reversed       int64
+	}
+	err := db.Raw(`SELECT journal_entries.id, journal_entries.reversed_amount, COALESCE(reversals.total, 0) AS reversed
+		FROM journal_entries
+		LEFT JOIN (
+			SELECT entries.reversal_of_id AS id, SUM(transactions.amount) AS total
+			FROM journal_entries entries
+			JOIN transactions ON transactions.journal_entry_id = entries.id
+			WHERE entries.reversal_of_id IS NOT NULL AND transactions.amount > 0
+			GROUP BY entries.reversal_of_id
+		) reversals ON reversals.id = journal_entries.id
+		WHERE journal_entries.reversed_amount <> COALESCE(reversals.total, 0)
+		ORDER BY journal_entries.id
+		LIMIT 1`).Scan(&mismatch).Error
+	if err != nil || mismatch.ID == 0 {
+		return nil, err
+	}
+
+	return &ChainBreak{
+		JournalEntryID: mismatch.ID,
+		Reason:         fmt.Sprintf("reversed amount %d does not match the %d its reversals moved", mismatch.ReversedAmount, mismatch.Reversed),
+	}, nil
+}
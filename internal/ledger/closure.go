@@ -0,0 +1,68 @@
+package ledger
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"ledger-app/models"
+	"time"
+)
+
+var (
+	ErrActiveHolds   = errors.New("account has active holds")
+	ErrNegativeSweep = errors.New("a negative balance can only be written off to " + models.SystemAccountClosed)
+)
+
+// CloseAccount sweeps the user's balance into sweepTo and closes the
+// account, after which Post refuses any entry touching it. Its postings
+// stay in place, so the history remains readable. The sweep entry is nil
+// when the balance was already zero. A negative balance is never charged
+// to another user: it can only be written off to the closed accounts
+// system account.
+func CloseAccount(tx *gorm.DB, userID uint, sweepTo *models.Account, now time.Time) (*models.JournalEntry, error) {
+	account, err := UserAccount(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := lockAccounts(tx, map[uint]int64{account.ID: 0})
+	if err != nil {
+		return nil, err
+	}
+	account = &accounts[0]
+
+	if account.Held != 0 {
+		return nil, ErrActiveHolds
+	}
+
+	if account.Balance < 0 && sweepTo.Type == models.AccountTypeUser {
+		return nil, ErrNegativeSweep
+	}
+
+	var entry *models.JournalEntry
+	if account.Balance != 0 {
+		entry = &models.JournalEntry{
+			Type:        models.EntryTypeClosure,
+			Description: "Account closure",
+			Postings: []models.Transaction{
+				{AccountID: account.ID, UserID: &userID, Amount: -account.Balance},
+				{AccountID: sweepTo.ID, UserID: sweepTo.UserID, Amount: account.Balance},
+			},
+		}
+
+		if err := Post(tx, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	result := tx.Model(&models.Account{}).
+		Where("id = ?", account.ID).
+		Updates(map[string]interface{}{
+			"closed_at": now,
+			"version":   gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	return entry, nil
+}
@@ -0,0 +1,40 @@
+package ledger
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"ledger-app/models"
+)
+
+// ChargeFee posts a fee of amount from the user's account to the fee
+// revenue account as its own entry, linked to the entry it was charged
+// for. It must run in the same transaction as that entry. A zero fee
+// posts nothing and returns a nil entry.
+func ChargeFee(tx *gorm.DB, userID uint, amount int64, feeFor *models.JournalEntry) (*models.JournalEntry, error) {
+	if amount == 0 {
+		return nil, nil
+	}
+
+	userAccount, err := UserAccount(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	revenue, err := SystemAccount(tx, models.SystemAccountFeeRevenue)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.JournalEntry{
+		Type:        models.EntryTypeFee,
+		FeeForID:    &feeFor.ID,
+		Description: fmt.Sprintf("Fee for %s entry %d", feeFor.Type, feeFor.ID),
+		Category:    "fee",
+		Postings: []models.Transaction{
+			{AccountID: userAccount.ID, UserID: &userID, Amount: -amount},
+			{AccountID: revenue.ID, Amount: amount},
+		},
+	}
+
+	return entry, Post(tx, entry)
+}
@@ -0,0 +1,167 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/models"
+	"time"
+)
+
+var (
+	ErrHoldNotFound       = errors.New("hold not found")
+	ErrHoldNotActive      = errors.New("hold is no longer active")
+	ErrCaptureExceedsHold = errors.New("capture amount exceeds the held amount")
+)
+
+// PlaceHold reserves amount on the user's account. The money stays in the
+// account but no longer counts towards its available balance.
+func PlaceHold(tx *gorm.DB, userID uint, amount int64, ttl time.Duration) (*models.Hold, error) {
+	account, err := UserAccount(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := lockAccounts(tx, map[uint]int64{account.ID: -amount})
+	if err != nil {
+		return nil, err
+	}
+	account = &accounts[0]
+
+	if account.Headroom() < amount {
+		return nil, fmt.Errorf("%w: account %s", ErrInsufficientBalance, account.Code)
+	}
+
+	hold := &models.Hold{
+		UserID:    userID,
+		AccountID: account.ID,
+		Amount:    amount,
+		Status:    models.HoldStatusActive,
+		ExpiresAt: time.Now().UTC().Add(ttl),
+	}
+
+	if err := tx.Create(hold).Error; err != nil {
+		return nil, err
+	}
+
+	return hold, updateAccount(tx, account, 0, amount)
+}
+
+// StartCapture locks an active hold for capture and works out the amount
+// to capture; zero captures the hold in full. The capture itself is a
+// transfer from the holder, posted by the caller, which must call
+// SettleCapture once the holder's account is locked and FinishCapture
+// once the transfer is posted.
+func StartCapture(tx *gorm.DB, holdID uint, amount int64, now time.Time) (*models.Hold, int64, error) {
+	hold, err := lockActiveHold(tx, holdID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if !hold.ExpiresAt.After(now) {
+		return nil, 0, ErrHoldNotActive
+	}
+
+	if amount == 0 {
+		amount = hold.Amount
+	}
+
+	if amount > hold.Amount {
+		return nil, 0, ErrCaptureExceedsHold
+	}
+
+	return hold, amount, nil
+}
+
+// SettleCapture releases the whole held amount of a hold being captured,
+// so the captured part can be transferred and the rest is available
+// again.
+func SettleCapture(tx *gorm.DB, hold *models.Hold) error {
+	return settleHold(tx, hold, models.HoldStatusCaptured)
+}
+
+// FinishCapture records what was captured and the entry it was posted as.
+func FinishCapture(tx *gorm.DB, hold *models.Hold, amount int64, entry *models.JournalEntry) error {
+	hold.CapturedAmount = amount
+	hold.CaptureEntryID = &entry.ID
+
+	return tx.Model(hold).Updates(map[string]interface{}{
+		"captured_amount":  hold.CapturedAmount,
+		"capture_entry_id": hold.CaptureEntryID,
+	}).Error
+}
+
+// ReleaseHold gives the held amount back to the available balance.
+func ReleaseHold(tx *gorm.DB, holdID uint) (*models.Hold, error) {
+	hold, err := lockActiveHold(tx, holdID)
+	if err != nil {
+		return nil, err
+	}
+
+	return hold, settleHold(tx, hold, models.HoldStatusReleased)
+}
+
+// ExpireHolds releases every active hold whose TTL has passed and returns
+// how many were expired.
+func ExpireHolds(db *gorm.DB, now time.Time) (int, error) {
+	var ids []uint
+	if err := db.Model(&models.Hold{}).
+		Where("status = ? AND expires_at <= ?", models.HoldStatusActive, now).
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	expired := 0
+	for _, id := range ids {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			hold, err := lockActiveHold(tx, id)
+			if err != nil {
+				return err
+			}
+
+			return settleHold(tx, hold, models.HoldStatusExpired)
+		})
+		if errors.Is(err, ErrHoldNotActive) {
+			continue
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+	}
+
+	return expired, nil
+}
+
+func lockActiveHold(tx *gorm.DB, holdID uint) (*models.Hold, error) {
+	var hold models.Hold
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&hold, holdID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrHoldNotFound
+		}
+		return nil, err
+	}
+
+	if hold.Status != models.HoldStatusActive {
+		return nil, ErrHoldNotActive
+	}
+
+	return &hold, nil
+}
+
+// settleHold closes an active hold and removes its amount from the
+// account's held balance.
+func settleHold(tx *gorm.DB, hold *models.Hold, status string) error {
+	accounts, err := lockAccounts(tx, map[uint]int64{hold.AccountID: 0})
+	if err != nil {
+		return err
+	}
+
+	if err := updateAccount(tx, &accounts[0], 0, -hold.Amount); err != nil {
+		return err
+	}
+
+	hold.Status = status
+	return tx.Model(hold).Update("status", status).Error
+}
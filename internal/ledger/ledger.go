@@ -0,0 +1,358 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/internal/money"
+	"ledger-app/models"
+	"sort"
+	"time"
+)
+
+var (
+	ErrUnbalancedEntry = errors.New("journal entry is not balanced")
+	ErrTooFewPostings  = errors.New("journal entry needs at least two postings")
+	ErrZeroPosting     = errors.New("journal entry contains a zero amount posting")
+
+	ErrAccountNotFound     = errors.New("account not found")
+	ErrCurrencyMismatch    = errors.New("account currency does not match the ledger currency")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrStaleAccount        = errors.New("account was modified concurrently")
+	ErrAccountClosed       = errors.New("account is closed")
+)
+
+// PostOptions relaxes the checks Post applies to an entry.
+type PostOptions struct {
+	// AllowNegative lets user accounts drop below their overdraft limit.
+	// Every account that does is recorded as a BalanceException carrying
+	// ExceptionReason.
+	AllowNegative   bool
+	ExceptionReason string
+}
+
+// Post writes a journal entry together with its postings. Debits and
+// credits are signed amounts on the postings and must sum to zero, so
+// every entry leaves the books balanced. The accounts involved are locked
+// for the rest of tx, so the balance check and the write are atomic; tx
+// must therefore be a database transaction.
+func Post(tx *gorm.DB, entry *models.JournalEntry) error {
+	return PostWithOptions(tx, entry, PostOptions{})
+}
+
+func PostWithOptions(tx *gorm.DB, entry *models.JournalEntry, opts PostOptions) error {
+	if len(entry.Postings) < 2 {
+		return ErrTooFewPostings
+	}
+
+	var total int64
+	for _, posting := range entry.Postings {
+		if posting.Amount == 0 {
+			return ErrZeroPosting
+		}
+
+		var err error
+		if total, err = money.Add(total, posting.Amount); err != nil {
+			return err
+		}
+	}
+
+	if total != 0 {
+		return fmt.Errorf("%w: postings sum to %v", ErrUnbalancedEntry, total)
+	}
+
+	// Entries normally take the current time; a preset time means the
+	// entry is backdated and must not land in a closed period.
+	backdated := !entry.PostedAt.IsZero()
+	if backdated {
+		if err := checkOpenPeriod(tx, entry.PostedAt); err != nil {
+			return err
+		}
+	}
+
+	deltas := make(map[uint]int64)
+	for _, posting := range entry.Postings {
+		deltas[posting.AccountID] += posting.Amount
+	}
+
+	accounts, err := lockAccounts(tx, deltas)
+	if err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		if account.Type != models.AccountTypeUser || deltas[account.ID] >= 0 {
+			continue
+		}
+
+		if account.Headroom()+deltas[account.ID] < 0 && !opts.AllowNegative {
+			return fmt.Errorf("%w: account %s", ErrInsufficientBalance, account.Code)
+		}
+	}
+
+	if entry.PostedAt.IsZero() {
+		entry.PostedAt = time.Now().UTC()
+	}
+
+	// Timestamps are stored with whole seconds; truncating here keeps the
+	// hashed time equal to the stored one.
+	entry.PostedAt = entry.PostedAt.Truncate(time.Second)
+
+	for i := range entry.Postings {
+		entry.Postings[i].TransactionTime = entry.PostedAt
+	}
+
+	// The postings are hashed with the entry ID, so the entry is written
+	// first.
+	if err := tx.Omit("Postings").Create(entry).Error; err != nil {
+		return err
+	}
+
+	for i := range entry.Postings {
+		entry.Postings[i].JournalEntryID = entry.ID
+	}
+	chainPostings(entry, accounts)
+
+	if err := tx.Create(&entry.Postings).Error; err != nil {
+		return err
+	}
+	startChains(entry, accounts)
+
+	for _, account := range accounts {
+		if err := applyDelta(tx, &account, deltas[account.ID]); err != nil {
+			return err
+		}
+
+		if backdated {
+			if err := adjustSnapshots(tx, account.ID, entry.PostedAt, deltas[account.ID]); err != nil {
+				return err
+			}
+		}
+
+		if account.Type == models.AccountTypeUser && account.Headroom() < 0 && deltas[account.ID] < 0 {
+			exception := models.BalanceException{
+				AccountID:      account.ID,
+				JournalEntryID: entry.ID,
+				Balance:        account.Balance,
+				Reason:         opts.ExceptionReason,
+			}
+			if err := tx.Create(&exception).Error; err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// applyDelta moves the stored balance of a locked account.
+func applyDelta(tx *gorm.DB, account *models.Account, delta int64) error {
+	return updateAccount(tx, account, delta, 0)
+}
+
+// updateAccount moves the stored and held balances of a locked account.
+// The version check guards against writers that bypass the row lock.
+func updateAccount(tx *gorm.DB, account *models.Account, balanceDelta, heldDelta int64) error {
+	balance, err := money.Add(account.Balance, balanceDelta)
+	if err != nil {
+		return err
+	}
+
+	held, err := money.Add(account.Held, heldDelta)
+	if err != nil {
+		return err
+	}
+
+	result := tx.Model(&models.Account{}).
+		Where("id = ? AND version = ?", account.ID, account.Version).
+		Updates(map[string]interface{}{
+			"balance":        balance,
+			"held":           held,
+			"chain_hash":     account.ChainHash,
+			"chain_start_id": account.ChainStartID,
+			"version":        gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("%w: account %s", ErrStaleAccount, account.Code)
+	}
+
+	account.Balance = balance
+	account.Held = held
+	account.Version++
+	return nil
+}
+
+// lockAccounts takes row locks on every account in ascending ID order so
+// that concurrent entries touching the same accounts cannot deadlock.
+func lockAccounts(tx *gorm.DB, deltas map[uint]int64) ([]models.Account, error) {
+	ids := make([]uint, 0, len(deltas))
+	for id := range deltas {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var accounts []models.Account
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("id IN ?", ids).Order("id").Find(&accounts).Error; err != nil {
+		return nil, err
+	}
+
+	if len(accounts) != len(ids) {
+		return nil, ErrAccountNotFound
+	}
+
+	for _, account := range accounts {
+		if account.Currency != money.Default.Code {
+			return nil, fmt.Errorf("%w: account %s is kept in %s", ErrCurrencyMismatch, account.Code, account.Currency)
+		}
+		if account.ClosedAt != nil {
+			return nil, fmt.Errorf("%w: %s", ErrAccountClosed, account.Code)
+		}
+	}
+
+	return accounts, nil
+}
+
+// LockUserAccounts locks the accounts of the users and of the named system
+// accounts together, in ascending ID order, and returns the user accounts
+// by user ID. Operations that post several entries lock everything up
+// front this way, so the entries take no locks out of order.
+func LockUserAccounts(tx *gorm.DB, userIDs []uint, systemCodes ...string) (map[uint]*models.Account, error) {
+	deltas := make(map[uint]int64)
+	for _, userID := range userIDs {
+		account, err := UserAccount(tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		deltas[account.ID] = 0
+	}
+
+	for _, code := range systemCodes {
+		account, err := SystemAccount(tx, code)
+		if err != nil {
+			return nil, err
+		}
+		deltas[account.ID] = 0
+	}
+
+	accounts, err := lockAccounts(tx, deltas)
+	if err != nil {
+		return nil, err
+	}
+
+	byUser := make(map[uint]*models.Account, len(userIDs))
+	for i := range accounts {
+		if accounts[i].UserID != nil {
+			byUser[*accounts[i].UserID] = &accounts[i]
+		}
+	}
+
+	return byUser, nil
+}
+
+func UserAccount(tx *gorm.DB, userID uint) (*models.Account, error) {
+	account := models.Account{
+		UserID:   &userID,
+		Code:     fmt.Sprintf("user:%d", userID),
+		Type:     models.AccountTypeUser,
+		Class:    models.AccountClassStandard,
+		Currency: money.Default.Code,
+	}
+
+	if err := tx.Where("user_id = ?", userID).FirstOrCreate(&account).Error; err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+func SystemAccount(tx *gorm.DB, code string) (*models.Account, error) {
+	account := models.Account{
+		Code:     code,
+		Type:     models.AccountTypeSystem,
+		Class:    models.AccountClassStandard,
+		Currency: money.Default.Code,
+	}
+
+	if err := tx.Where("code = ?", code).FirstOrCreate(&account).Error; err != nil {
+		return nil, err
+	}
+
+	return &account, nil
+}
+
+// Credit mints amount into the user's account against the issuance account.
+func Credit(tx *gorm.DB, userID uint, amount int64, meta models.EntryMetadata) (*models.JournalEntry, error) {
+	userAccount, err := UserAccount(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	issuance, err := SystemAccount(tx, models.SystemAccountIssuance)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.JournalEntry{
+		Type: models.EntryTypeCredit,
+		Postings: []models.Transaction{
+			{AccountID: issuance.ID, Amount: -amount},
+			{AccountID: userAccount.ID, UserID: &userID, Amount: amount},
+		},
+	}
+	entry.SetMetadata(meta)
+
+	return entry, Post(tx, entry)
+}
+
+// Transfer moves amount from the sender's account to the receiver's account.
+func Transfer(tx *gorm.DB, senderID, receiverID uint, amount int64, meta models.EntryMetadata) (*models.JournalEntry, error) {
+	senderAccount, err := UserAccount(tx, senderID)
+	if err != nil {
+		return nil, err
+	}
+
+	receiverAccount, err := UserAccount(tx, receiverID)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.JournalEntry{
+		Type: models.EntryTypeTransfer,
+		Postings: []models.Transaction{
+			{AccountID: senderAccount.ID, UserID: &senderID, Amount: -amount, SenderID: &senderID, ReceiverID: &receiverID},
+			{AccountID: receiverAccount.ID, UserID: &receiverID, Amount: amount, SenderID: &senderID, ReceiverID: &receiverID},
+		},
+	}
+	entry.SetMetadata(meta)
+
+	return entry, Post(tx, entry)
+}
+
+// Withdraw pays amount out of the user's account into the withdrawals account.
+func Withdraw(tx *gorm.DB, userID uint, amount int64, meta models.EntryMetadata) (*models.JournalEntry, error) {
+	userAccount, err := UserAccount(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	withdrawals, err := SystemAccount(tx, models.SystemAccountWithdrawals)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &models.JournalEntry{
+		Type: models.EntryTypeWithdrawal,
+		Postings: []models.Transaction{
+			{AccountID: userAccount.ID, UserID: &userID, Amount: -amount},
+			{AccountID: withdrawals.ID, Amount: amount},
+		},
+	}
+	entry.SetMetadata(meta)
+
+	return entry, Post(tx, entry)
+}
@@ -0,0 +1,90 @@
+package ledger_test
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/testdb"
+	"ledger-app/models"
+	"sync"
+	"testing"
+)
+
+func TestConcurrentWithdrawalsNeverOverdraw(t *testing.T) {
+	db := testdb.Open(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(50)
+
+	const (
+		workers = 300
+		amount  = 50
+		funded  = 200 * amount
+	)
+
+	user := testdb.NewUser(t, db, "alice", funded)
+	if err := db.Transaction(func(tx *gorm.DB) error {
+		_, err := ledger.SystemAccount(tx, models.SystemAccountWithdrawals)
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		refused   int
+		failures  []error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			err := db.Transaction(func(tx *gorm.DB) error {
+				_, err := ledger.Withdraw(tx, user.ID, amount, models.EntryMetadata{})
+				return err
+			})
+
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case errors.Is(err, ledger.ErrInsufficientBalance):
+				refused++
+			default:
+				failures = append(failures, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range failures {
+		t.Errorf("unexpected withdrawal error: %v", err)
+	}
+
+	if succeeded != funded/amount {
+		t.Errorf("succeeded = %d, want %d", succeeded, funded/amount)
+	}
+	if refused != workers-funded/amount {
+		t.Errorf("refused = %d, want %d", refused, workers-funded/amount)
+	}
+
+	account := testdb.Account(t, db, user.ID)
+	if account.Balance != 0 {
+		t.Errorf("balance = %d, want 0", account.Balance)
+	}
+
+	var posted int64
+	if err := db.Model(&models.Transaction{}).Where("account_id = ?", account.ID).
+		Select("COALESCE(SUM(amount), 0)").Scan(&posted).Error; err != nil {
+		t.Fatal(err)
+	}
+	if posted != account.Balance {
+		t.Errorf("postings sum to %d but stored balance is %d", posted, account.Balance)
+	}
+}
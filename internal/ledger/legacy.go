@@ -0,0 +1,151 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"ledger-app/models"
+	"time"
+)
+
+// LegacyTable holds the single-sided transactions written before the
+// ledger kept journal entries. The migration moves them here, out of the
+// way of the new transactions table, until PostLegacy has posted them.
+const LegacyTable = "legacy_transactions"
+
+// legacyReason marks the balance exceptions of legacy balances that were
+// already below zero.
+const legacyReason = "legacy balance"
+
+type legacyTransaction struct {
+	ID              uint
+	UserID          uint
+	Amount          int64
+	TransactionTime time.Time
+	SenderID        *uint
+	ReceiverID      *uint
+}
+
+// PostLegacy turns every legacy row into a balanced entry: a credit
+// against the issuance account, a withdrawal against the withdrawals
+// account and the two rows of a transfer into one transfer. A transfer row
+// whose other half is missing is posted against the migration account.
+// Entries keep the time of their rows and carry a legacy import key, so a
+// migration that was interrupted posts each row only once. Balances follow
+// from the entries; balances the legacy rows left below zero are kept and
+// recorded as balance exceptions.
+func PostLegacy(db *gorm.DB) (int, error) {
+	var rows []legacyTransaction
+	if err := db.Table(LegacyTable).Order("id").Find(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	posted := 0
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for i := 0; i < len(rows); i++ {
+			row := rows[i]
+			if row.Amount == 0 {
+				continue
+			}
+
+			// The two rows of a transfer were written one after the other.
+			var entry *models.JournalEntry
+			var err error
+			if i+1 < len(rows) && isTransferPair(row, rows[i+1]) {
+				entry, err = legacyTransfer(tx, row, rows[i+1])
+				i++
+			} else {
+				entry, err = legacyEntry(tx, row)
+			}
+			if err != nil {
+				return err
+			}
+
+			key := fmt.Sprintf("legacy:%d", row.ID)
+			var existing models.JournalEntry
+			err = tx.Where("import_key = ?", key).First(&existing).Error
+			if err == nil {
+				continue
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			entry.ImportKey = &key
+			entry.PostedAt = row.TransactionTime
+			if err := PostWithOptions(tx, entry, PostOptions{AllowNegative: true, ExceptionReason: legacyReason}); err != nil {
+				return fmt.Errorf("legacy transaction %d: %w", row.ID, err)
+			}
+			posted++
+		}
+
+		return nil
+	})
+
+	return posted, err
+}
+
+func isTransferPair(sent, received legacyTransaction) bool {
+	return sent.SenderID != nil && sent.ReceiverID != nil &&
+		sent.Amount < 0 &&
+		sent.UserID == *sent.SenderID &&
+		received.SenderID != nil && received.ReceiverID != nil &&
+		*received.SenderID == *sent.SenderID &&
+		*received.ReceiverID == *sent.ReceiverID &&
+		received.UserID == *sent.ReceiverID &&
+		received.Amount == -sent.Amount
+}
+
+// legacyEntry builds the entry of a single row.
+func legacyEntry(tx *gorm.DB, row legacyTransaction) (*models.JournalEntry, error) {
+	userAccount, err := UserAccount(tx, row.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	entryType, code := models.EntryTypeImport, models.SystemAccountMigration
+	switch {
+	case row.SenderID != nil && row.ReceiverID != nil:
+	case row.Amount > 0:
+		entryType, code = models.EntryTypeCredit, models.SystemAccountIssuance
+	default:
+		entryType, code = models.EntryTypeWithdrawal, models.SystemAccountWithdrawals
+	}
+
+	system, err := SystemAccount(tx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	userID := row.UserID
+	return &models.JournalEntry{
+		Type:        entryType,
+		Description: fmt.Sprintf("Legacy transaction %d", row.ID),
+		Postings: []models.Transaction{
+			{AccountID: system.ID, Amount: -row.Amount},
+			{AccountID: userAccount.ID, UserID: &userID, Amount: row.Amount, SenderID: row.SenderID, ReceiverID: row.ReceiverID},
+		},
+	}, nil
+}
+
+func legacyTransfer(tx *gorm.DB, sent, received legacyTransaction) (*models.JournalEntry, error) {
+	senderAccount, err := UserAccount(tx, sent.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	receiverAccount, err := UserAccount(tx, received.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	senderID, receiverID := sent.UserID, received.UserID
+	return &models.JournalEntry{
+		Type:        models.EntryTypeTransfer,
+		Description: fmt.Sprintf("Legacy transactions %d and %d", sent.ID, received.ID),
+		Postings: []models.Transaction{
+			{AccountID: senderAccount.ID, UserID: &senderID, Amount: sent.Amount, SenderID: &senderID, ReceiverID: &receiverID},
+			{AccountID: receiverAccount.ID, UserID: &receiverID, Amount: received.Amount, SenderID: &senderID, ReceiverID: &receiverID},
+		},
+	}, nil
+}
@@ -0,0 +1,46 @@
+package ledger
+
+import (
+	"gorm.io/gorm"
+	"ledger-app/models"
+)
+
+// SetOverdraftLimit changes how far the user's account may go below zero
+// and records who changed it and why.
+func SetOverdraftLimit(tx *gorm.DB, userID uint, limit int64, changedBy uint, reason string) (*models.OverdraftLimitChange, error) {
+	account, err := UserAccount(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := lockAccounts(tx, map[uint]int64{account.ID: 0})
+	if err != nil {
+		return nil, err
+	}
+	account = &accounts[0]
+
+	change := &models.OverdraftLimitChange{
+		UserID:    userID,
+		AccountID: account.ID,
+		OldLimit:  account.OverdraftLimit,
+		NewLimit:  limit,
+		ChangedBy: changedBy,
+		Reason:    reason,
+	}
+
+	result := tx.Model(&models.Account{}).
+		Where("id = ? AND version = ?", account.ID, account.Version).
+		Updates(map[string]interface{}{
+			"overdraft_limit": limit,
+			"version":         gorm.Expr("version + 1"),
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, ErrStaleAccount
+	}
+
+	return change, tx.Create(change).Error
+}
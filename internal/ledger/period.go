@@ -0,0 +1,95 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/models"
+	"time"
+)
+
+var (
+	ErrPeriodClosed        = errors.New("posting date falls in a closed period")
+	ErrPeriodNotEnded      = errors.New("a period can only be closed once it has ended")
+	ErrPeriodAlreadyClosed = errors.New("period end must be after the end of the last closed period")
+)
+
+// ClosedThrough returns the end of the last closed period, or the zero
+// time when no period has been closed. The row read is locked in share
+// mode, which keeps a period from being closed while a backdated entry
+// that passed the check is still being written.
+func ClosedThrough(tx *gorm.DB) (time.Time, error) {
+	var last models.PeriodClose
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+		Order("closed_through DESC").
+		Limit(1).
+		Find(&last).Error; err != nil {
+		return time.Time{}, err
+	}
+
+	return last.ClosedThrough, nil
+}
+
+func checkOpenPeriod(tx *gorm.DB, postedAt time.Time) error {
+	closedThrough, err := ClosedThrough(tx)
+	if err != nil {
+		return err
+	}
+
+	if postedAt.Before(closedThrough) {
+		return fmt.Errorf("%w: books are closed through %s", ErrPeriodClosed, closedThrough.UTC().Format(time.RFC3339))
+	}
+
+	return nil
+}
+
+// ClosePeriod closes the books for everything before through and snapshots
+// every account's balance at that point. Periods close in order, so
+// through must be later than the previous close and not in the future.
+func ClosePeriod(tx *gorm.DB, through time.Time, closedBy uint, reason string, now time.Time) (*models.PeriodClose, int, error) {
+	through = through.UTC()
+
+	if through.After(now) {
+		return nil, 0, ErrPeriodNotEnded
+	}
+
+	var last models.PeriodClose
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Order("closed_through DESC").
+		Limit(1).
+		Find(&last).Error; err != nil {
+		return nil, 0, err
+	}
+
+	if !through.After(last.ClosedThrough) {
+		return nil, 0, ErrPeriodAlreadyClosed
+	}
+
+	period := &models.PeriodClose{ClosedThrough: through, ClosedBy: closedBy, Reason: reason}
+	if err := tx.Create(period).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var accountIDs []uint
+	if err := tx.Model(&models.Account{}).Order("id").Pluck("id", &accountIDs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	for _, accountID := range accountIDs {
+		balance, err := BalanceAt(tx, accountID, through)
+		if err != nil {
+			return nil, 0, err
+		}
+
+		snapshot := models.BalanceSnapshot{AccountID: accountID, At: through, Balance: balance}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "account_id"}, {Name: "at"}},
+			DoUpdates: clause.AssignmentColumns([]string{"balance"}),
+		}).Create(&snapshot).Error; err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return period, len(accountIDs), nil
+}
@@ -0,0 +1,68 @@
+package ledger
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/models"
+)
+
+// BalanceCorrection records an account whose stored balance did not match
+// the sum of its postings.
+type BalanceCorrection struct {
+	AccountID uint
+	Code      string
+	Stored    int64
+	Computed  int64
+}
+
+// RebuildBalances recomputes every stored account balance from the
+// transaction history. All accounts are locked while the rebuild runs so
+// no posting can slip in between the sum and the update.
+func RebuildBalances(db *gorm.DB) ([]BalanceCorrection, error) {
+	var corrections []BalanceCorrection
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var accounts []models.Account
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Order("id").Find(&accounts).Error; err != nil {
+			return err
+		}
+
+		var sums []struct {
+			AccountID uint
+			Total     int64
+		}
+		if err := tx.Model(&models.Transaction{}).
+			Select("account_id, COALESCE(SUM(amount), 0) AS total").
+			Group("account_id").
+			Scan(&sums).Error; err != nil {
+			return err
+		}
+
+		totals := make(map[uint]int64, len(sums))
+		for _, sum := range sums {
+			totals[sum.AccountID] = sum.Total
+		}
+
+		for _, account := range accounts {
+			stored, computed := account.Balance, totals[account.ID]
+			if computed == stored {
+				continue
+			}
+
+			if err := applyDelta(tx, &account, computed-stored); err != nil {
+				return err
+			}
+
+			corrections = append(corrections, BalanceCorrection{
+				AccountID: account.ID,
+				Code:      account.Code,
+				Stored:    stored,
+				Computed:  computed,
+			})
+		}
+
+		return nil
+	})
+
+	return corrections, err
+}
@@ -0,0 +1,182 @@
+package ledger
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/models"
+)
+
+var (
+	ErrEntryNotFound          = errors.New("journal entry not found")
+	ErrReversalOfReversal     = errors.New("a reversal cannot itself be reversed")
+	ErrAlreadyReversed        = errors.New("journal entry has already been fully reversed")
+	ErrReversalExceedsEntry   = errors.New("reversal amount exceeds the amount left to reverse")
+	ErrPartialReversalUnsound = errors.New("only two-legged entries can be partially reversed")
+)
+
+// Reverse posts a compensating entry for the journal entry that contains
+// the given transaction. An amount of zero reverses whatever is left of
+// the original; a smaller amount makes a partial refund. The original
+// entry row is locked while the reversed total is checked and bumped, so
+// the same money cannot be refunded twice. A fee charged for the original
+// is refunded in the same proportion with a reversal of its own, which is
+// returned as the second entry (nil when there is nothing to refund).
+func Reverse(tx *gorm.DB, transactionID uint, amount int64, opts PostOptions) (*models.JournalEntry, *models.JournalEntry, *models.JournalEntry, error) {
+	var posting models.Transaction
+	if err := tx.First(&posting, transactionID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil, ErrEntryNotFound
+		}
+		return nil, nil, nil, err
+	}
+
+	var original models.JournalEntry
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Postings").
+		First(&original, posting.JournalEntryID).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, nil, ErrEntryNotFound
+		}
+		return nil, nil, nil, err
+	}
+
+	if original.Type == models.EntryTypeReversal {
+		return nil, nil, nil, ErrReversalOfReversal
+	}
+
+	gross := original.Amount()
+	remaining := gross - original.ReversedAmount
+	if remaining <= 0 {
+		return nil, nil, nil, ErrAlreadyReversed
+	}
+
+	if amount == 0 {
+		amount = remaining
+	}
+
+	if amount > remaining {
+		return nil, nil, nil, fmt.Errorf("%w: %d left", ErrReversalExceedsEntry, remaining)
+	}
+
+	if amount != gross && len(original.Postings) != 2 {
+		return nil, nil, nil, ErrPartialReversalUnsound
+	}
+
+	fee, err := lockFee(tx, &original)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// Both reversals lock their accounts once more while posting; taking
+	// every lock here first keeps them in ascending ID order, as
+	// transfers take theirs.
+	deltas := make(map[uint]int64)
+	for _, entry := range []*models.JournalEntry{&original, fee} {
+		if entry == nil {
+			continue
+		}
+		for _, leg := range entry.Postings {
+			deltas[leg.AccountID] = 0
+		}
+	}
+	if _, err := lockAccounts(tx, deltas); err != nil {
+		return nil, nil, nil, err
+	}
+
+	reversal, err := reverseEntry(tx, &original, amount, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	feeReversal, err := reverseFee(tx, &original, fee, amount, amount == remaining, opts)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return reversal, feeReversal, &original, nil
+}
+
+// lockFee locks the fee entry charged for original, if there is one.
+func lockFee(tx *gorm.DB, original *models.JournalEntry) (*models.JournalEntry, error) {
+	var fee models.JournalEntry
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Preload("Postings").
+		Where("fee_for_id = ? AND type = ?", original.ID, models.EntryTypeFee).
+		First(&fee).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &fee, nil
+}
+
+// reverseFee refunds the share of the locked fee that matches the amount
+// of original being reversed. The last reversal of original refunds
+// whatever is left of the fee so rounding never keeps a remainder.
+func reverseFee(tx *gorm.DB, original, fee *models.JournalEntry, amount int64, final bool, opts PostOptions) (*models.JournalEntry, error) {
+	if fee == nil {
+		return nil, nil
+	}
+
+	left := fee.Amount() - fee.ReversedAmount
+	refund := left
+	if !final {
+		refund = fee.Amount() * amount / original.Amount()
+		if refund > left {
+			refund = left
+		}
+	}
+
+	if refund <= 0 {
+		return nil, nil
+	}
+
+	return reverseEntry(tx, fee, refund, opts)
+}
+
+// reverseEntry posts the reversal of amount of the locked entry and bumps
+// its reversed total.
+func reverseEntry(tx *gorm.DB, original *models.JournalEntry, amount int64, opts PostOptions) (*models.JournalEntry, error) {
+	gross := original.Amount()
+
+	reversal := &models.JournalEntry{
+		Type:         models.EntryTypeReversal,
+		ReversalOfID: &original.ID,
+		Description:  fmt.Sprintf("Reversal of entry %d", original.ID),
+		Category:     original.Category,
+	}
+
+	for _, leg := range original.Postings {
+		reversedAmount := -leg.Amount
+		if amount != gross {
+			reversedAmount = amount
+			if leg.Amount > 0 {
+				reversedAmount = -amount
+			}
+		}
+
+		reversal.Postings = append(reversal.Postings, models.Transaction{
+			AccountID:  leg.AccountID,
+			UserID:     leg.UserID,
+			Amount:     reversedAmount,
+			SenderID:   leg.ReceiverID,
+			ReceiverID: leg.SenderID,
+		})
+	}
+
+	if err := PostWithOptions(tx, reversal, opts); err != nil {
+		return nil, err
+	}
+
+	original.ReversedAmount += amount
+	if err := tx.Model(original).Update("reversed_amount", original.ReversedAmount).Error; err != nil {
+		return nil, err
+	}
+
+	return reversal, nil
+}
@@ -0,0 +1,97 @@
+package ledger
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/models"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// TakeSnapshots records the closing balance of every account at each UTC
+// midnight up to now that has no snapshot yet. An account's first snapshot
+// is taken at the latest midnight only; older days are not backfilled.
+func TakeSnapshots(db *gorm.DB, now time.Time) (int, error) {
+	boundary := now.UTC().Truncate(day)
+
+	var accountIDs []uint
+	if err := db.Model(&models.Account{}).Order("id").Pluck("id", &accountIDs).Error; err != nil {
+		return 0, err
+	}
+
+	taken := 0
+	for _, accountID := range accountIDs {
+		count, err := snapshotAccount(db, accountID, boundary)
+		taken += count
+		if err != nil {
+			return taken, err
+		}
+	}
+
+	return taken, nil
+}
+
+// snapshotAccount locks the account first so that the sums below see every
+// posting committed before it, including backdated ones.
+func snapshotAccount(db *gorm.DB, accountID uint, boundary time.Time) (int, error) {
+	taken := 0
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var account models.Account
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&account, accountID).Error; err != nil {
+			return err
+		}
+
+		var last models.BalanceSnapshot
+		if err := tx.Where("account_id = ?", accountID).Order("at DESC").Limit(1).Find(&last).Error; err != nil {
+			return err
+		}
+
+		if last.ID == 0 {
+			balance, err := BalanceAt(tx, accountID, boundary)
+			if err != nil {
+				return err
+			}
+
+			taken++
+			return tx.Create(&models.BalanceSnapshot{AccountID: accountID, At: boundary, Balance: balance}).Error
+		}
+
+		balance := last.Balance
+		from := last.At
+		for at := last.At.UTC().Truncate(day).Add(day); !at.After(boundary); at = at.Add(day) {
+			var sum int64
+			if err := tx.Model(&models.Transaction{}).
+				Where("account_id = ? AND transaction_time >= ? AND transaction_time < ?", accountID, from, at).
+				Select("COALESCE(SUM(amount), 0)").
+				Scan(&sum).Error; err != nil {
+				return err
+			}
+
+			balance += sum
+			if err := tx.Create(&models.BalanceSnapshot{AccountID: accountID, At: at, Balance: balance}).Error; err != nil {
+				return err
+			}
+
+			from = at
+			taken++
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return taken, nil
+}
+
+// adjustSnapshots carries a backdated posting into the snapshots taken
+// after it.
+func adjustSnapshots(tx *gorm.DB, accountID uint, postedAt time.Time, delta int64) error {
+	return tx.Model(&models.BalanceSnapshot{}).
+		Where("account_id = ? AND at > ?", accountID, postedAt).
+		Update("balance", gorm.Expr("balance + ?", delta)).Error
+}
@@ -0,0 +1,58 @@
+package ledger
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/models"
+	"sort"
+)
+
+// CheckStatus returns models.ErrUserInactive when the sender may not send
+// or the receiver may not receive money. A zero ID is not checked. The
+// users are read with shared locks, in ascending ID order, so a status
+// change cannot commit between the check and the posting that follows in
+// tx. Everything that moves money on a user's behalf calls it inside the
+// transaction that posts.
+func CheckStatus(tx *gorm.DB, senderID, receiverID uint) error {
+	var ids []uint
+	for _, id := range []uint{senderID, receiverID} {
+		if id != 0 {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	var users []models.User
+	if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).
+		Select("id", "status").
+		Where("id IN ?", ids).
+		Order("id").
+		Find(&users).Error; err != nil {
+		return err
+	}
+
+	byID := make(map[uint]*models.User, len(users))
+	for i := range users {
+		byID[users[i].ID] = &users[i]
+	}
+
+	for _, id := range ids {
+		if byID[id] == nil {
+			return gorm.ErrRecordNotFound
+		}
+	}
+
+	if sender := byID[senderID]; senderID != 0 && !sender.CanSend() {
+		return fmt.Errorf("%w: sender %d is %s", models.ErrUserInactive, sender.ID, sender.Status)
+	}
+
+	if receiver := byID[receiverID]; receiverID != 0 && !receiver.CanReceive() {
+		return fmt.Errorf("%w: receiver %d is %s", models.ErrUserInactive, receiver.ID, receiver.Status)
+	}
+
+	return nil
+}
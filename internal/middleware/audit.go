@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"errors"
+	"github.com/labstack/echo/v4"
+	"ledger-app/internal/audit"
+	"ledger-app/internal/connections/database"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+)
+
+// Audit records every admin request that changes state. An event with
+// status 0 is written before the handler runs, and the request is refused
+// if it cannot be, so no change goes unaudited. Handlers record their
+// outcome inside their own transaction and store the event as
+// "auditEvent"; for any other outcome the middleware records it, with the
+// *audit.Change a handler stored as "auditChange" or else the route
+// parameters.
+func Audit(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		method := c.Request().Method
+		if method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions {
+			return next(c)
+		}
+
+		intent := audit.NewEvent(c, 0)
+		if change := audit.RouteParams(c); change != nil {
+			_ = audit.Apply(&intent, change)
+		}
+		if err := audit.Record(database.Db, &intent); err != nil {
+			logger.Logger.Errorf("Failed to record audit event %s: %v", intent.Action, err)
+			return c.JSON(http.StatusServiceUnavailable, map[string]string{"error": "Audit log unavailable"})
+		}
+
+		err := next(c)
+
+		event := audit.NewEvent(c, c.Response().Status)
+
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			event.Status = httpErr.Code
+		}
+
+		// A handler whose transaction failed to commit answers with another
+		// status than the one it recorded, and that outcome is recorded here.
+		if recorded, ok := c.Get("auditEvent").(*models.AuditEvent); ok && recorded.Status == event.Status {
+			return err
+		}
+
+		change, _ := c.Get("auditChange").(*audit.Change)
+		if change == nil {
+			change = audit.RouteParams(c)
+		}
+		if change != nil {
+			if applyErr := audit.Apply(&event, change); applyErr != nil {
+				logger.Logger.Error("Failed to encode audit change: ", applyErr.Error())
+			}
+		}
+
+		if recordErr := audit.Record(database.Db, &event); recordErr != nil {
+			logger.Logger.Errorf("Failed to record audit event %s: %v", event.Action, recordErr)
+		}
+
+		return err
+	}
+}
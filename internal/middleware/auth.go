@@ -4,7 +4,9 @@ import (
 	"github.com/golang-jwt/jwt"
 	"github.com/labstack/echo/v4"
 	"ledger-app/internal/auth"
+	"ledger-app/internal/connections/database"
 	"ledger-app/logger"
+	"ledger-app/models"
 	"net/http"
 	"strings"
 )
@@ -30,6 +32,32 @@ func JWTMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token claims"})
 		}
 
+		userID, ok := claims["userID"].(float64)
+		if !ok {
+			logger.Logger.Error("Invalid token claims")
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token claims"})
+		}
+
+		// Status is read on every request so that a freeze or suspension
+		// takes effect on tokens already issued.
+		var user models.User
+		if err := database.Db.Select("id", "status").First(&user, uint(userID)).Error; err != nil {
+			logger.Logger.Error("Token user not found: ", err)
+			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid token"})
+		}
+
+		switch user.Status {
+		case models.UserStatusSuspended:
+			logger.Logger.Warnf("Suspended User ID %d attempted a request", user.ID)
+			return c.JSON(http.StatusForbidden, map[string]string{"error": "Account suspended"})
+		case models.UserStatusFrozen, models.UserStatusClosed:
+			method := c.Request().Method
+			if method != http.MethodGet && method != http.MethodHead {
+				logger.Logger.Warnf("User ID %d is %s and attempted %s %s", user.ID, user.Status, method, c.Path())
+				return c.JSON(http.StatusForbidden, map[string]string{"error": "Account is " + user.Status + " and read-only"})
+			}
+		}
+
 		c.Set("userID", claims["userID"])
 		c.Set("role", claims["role"])
 
@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/labstack/echo/v4"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"io"
+	"ledger-app/internal/connections/database"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"net/http"
+	"time"
+)
+
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+type responseRecorder struct {
+	http.ResponseWriter
+	body bytes.Buffer
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
+
+// Idempotency stores the first response for each Idempotency-Key sent by a
+// user and replays it for retries of the same request. Reusing a key with a
+// different request is rejected with 409. Keys expire after ttl. It must run
+// after JWTMiddleware so keys are scoped to the caller.
+func Idempotency(ttl time.Duration) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			key := c.Request().Header.Get(IdempotencyKeyHeader)
+			if key == "" {
+				return next(c)
+			}
+
+			if len(key) > 255 {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Idempotency key is too long"})
+			}
+
+			tokenUserID, ok := c.Get("userID").(float64)
+			if !ok {
+				logger.Logger.Error("Failed to retrieve user ID from token")
+				return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
+			}
+
+			body, err := io.ReadAll(c.Request().Body)
+			if err != nil {
+				logger.Logger.Error("Failed to read request body: ", err.Error())
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request payload"})
+			}
+			c.Request().Body = io.NopCloser(bytes.NewReader(body))
+
+			now := time.Now().UTC()
+			record := models.IdempotencyKey{
+				Key:         key,
+				UserID:      uint(tokenUserID),
+				Method:      c.Request().Method,
+				Path:        c.Request().URL.Path,
+				Fingerprint: fingerprint(c.Request().Method, c.Request().URL.Path, body),
+				ExpiresAt:   now.Add(ttl),
+			}
+
+			result := database.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+			if result.Error == nil && result.RowsAffected == 0 {
+				// The key may have expired without being purged yet; release it
+				// and claim it again for this request.
+				expired := database.Db.Where("user_id = ? AND `key` = ? AND expires_at < ?", record.UserID, record.Key, now).
+					Delete(&models.IdempotencyKey{})
+				if expired.Error == nil && expired.RowsAffected > 0 {
+					result = database.Db.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+				} else if expired.Error != nil {
+					result = expired
+				}
+			}
+			if result.Error != nil {
+				logger.Logger.Error("Failed to store idempotency key: ", result.Error.Error())
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+			}
+
+			if result.RowsAffected == 0 {
+				return replay(c, record)
+			}
+
+			recorder := &responseRecorder{ResponseWriter: c.Response().Writer}
+			c.Response().Writer = recorder
+
+			if err := next(c); err != nil {
+				c.Error(err)
+			}
+
+			// Server errors are not cached so the client can retry with the same key.
+			if c.Response().Status >= http.StatusInternalServerError {
+				if err := database.Db.Delete(&record).Error; err != nil {
+					logger.Logger.Error("Failed to release idempotency key: ", err.Error())
+				}
+				return nil
+			}
+
+			if err := database.Db.Model(&record).Updates(map[string]interface{}{
+				"completed":     true,
+				"status_code":   c.Response().Status,
+				"response_body": recorder.body.String(),
+			}).Error; err != nil {
+				logger.Logger.Error("Failed to store idempotent response: ", err.Error())
+			}
+
+			return nil
+		}
+	}
+}
+
+// PurgeIdempotencyKeys deletes the keys that expired before now and returns
+// how many were removed.
+func PurgeIdempotencyKeys(db *gorm.DB, now time.Time) (int64, error) {
+	result := db.Where("expires_at < ?", now).Delete(&models.IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
+
+func replay(c echo.Context, attempt models.IdempotencyKey) error {
+	var stored models.IdempotencyKey
+	if err := database.Db.Where("user_id = ? AND `key` = ?", attempt.UserID, attempt.Key).First(&stored).Error; err != nil {
+		logger.Logger.Error("Failed to load idempotency key: ", err.Error())
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	if stored.Fingerprint != attempt.Fingerprint {
+		logger.Logger.Warnf("Idempotency key %s reused by User ID %d with a different request", attempt.Key, attempt.UserID)
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Idempotency key was already used for a different request"})
+	}
+
+	if !stored.Completed {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "A request with this idempotency key is still in progress"})
+	}
+
+	logger.Logger.Infof("Replaying response for idempotency key %s of User ID %d", attempt.Key, attempt.UserID)
+	c.Response().Header().Set("Idempotent-Replayed", "true")
+	return c.Blob(stored.StatusCode, echo.MIMEApplicationJSONCharsetUTF8, []byte(stored.ResponseBody))
+}
+
+func fingerprint(method, path string, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(method + " " + path + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
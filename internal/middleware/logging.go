@@ -9,8 +9,9 @@ import (
 func LogRequest(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		logger.Logger.WithFields(logrus.Fields{
-			"Method": c.Request().Method,
-			"Url":    c.Request().URL.String(),
+			"Method":    c.Request().Method,
+			"Url":       c.Request().URL.String(),
+			"RequestID": c.Get("requestID"),
 		}).Info("Incoming request")
 
 		return next(c)
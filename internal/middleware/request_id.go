@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/labstack/echo/v4"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID keeps the caller's request ID when one is sent and makes one
+// up otherwise. It is echoed back in the response and stored as
+// "requestID" in the context.
+func RequestID(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		requestID := c.Request().Header.Get(RequestIDHeader)
+		if requestID == "" || len(requestID) > 64 {
+			buf := make([]byte, 16)
+			_, _ = rand.Read(buf)
+			requestID = hex.EncodeToString(buf)
+		}
+
+		c.Set("requestID", requestID)
+		c.Response().Header().Set(RequestIDHeader, requestID)
+
+		return next(c)
+	}
+}
@@ -0,0 +1,35 @@
+package money
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Decimal holds an amount exactly as the client sent it. It accepts both
+// JSON numbers and JSON strings so that values never pass through float64.
+type Decimal string
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '"' {
+		var value string
+		if err := json.Unmarshal(data, &value); err != nil {
+			return err
+		}
+
+		*d = Decimal(value)
+		return nil
+	}
+
+	if bytes.Equal(data, []byte("null")) {
+		*d = ""
+		return nil
+	}
+
+	*d = Decimal(data)
+	return nil
+}
+
+func (d Decimal) MinorUnits(currency Currency) (int64, error) {
+	return Parse(string(d), currency)
+}
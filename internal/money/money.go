@@ -0,0 +1,122 @@
+package money
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrInvalidAmount       = errors.New("amount must be a plain decimal number")
+	ErrTooManyDecimals     = errors.New("amount has more decimal places than the currency allows")
+	ErrAmountOutOfRange    = errors.New("amount is out of range")
+	ErrUnsupportedCurrency = errors.New("currency is not supported")
+)
+
+var decimalPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?$`)
+
+// Currency describes how many minor units make up one major unit,
+// e.g. a precision of 2 means amounts are stored in cents.
+type Currency struct {
+	Code      string
+	Precision int
+}
+
+var currencies = map[string]Currency{
+	"USD": {Code: "USD", Precision: 2},
+	"EUR": {Code: "EUR", Precision: 2},
+	"GBP": {Code: "GBP", Precision: 2},
+	"TRY": {Code: "TRY", Precision: 2},
+	"JPY": {Code: "JPY", Precision: 0},
+	"KWD": {Code: "KWD", Precision: 3},
+	"BHD": {Code: "BHD", Precision: 3},
+}
+
+// Default is the currency every account of this ledger is kept in.
+var Default = currencies["USD"]
+
+func Lookup(code string) (Currency, error) {
+	currency, ok := currencies[strings.ToUpper(code)]
+	if !ok {
+		return Currency{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, code)
+	}
+
+	return currency, nil
+}
+
+func SetDefault(code string) error {
+	currency, err := Lookup(code)
+	if err != nil {
+		return err
+	}
+
+	Default = currency
+	return nil
+}
+
+// Parse converts a decimal string such as "12.34" into minor units.
+// Inputs with more fractional digits than the currency precision are
+// rejected instead of being rounded.
+func Parse(value string, currency Currency) (int64, error) {
+	value = strings.TrimSpace(value)
+	if !decimalPattern.MatchString(value) {
+		return 0, ErrInvalidAmount
+	}
+
+	// The sign is parsed along with the digits, so the most negative
+	// amount is in range too.
+	sign := ""
+	if strings.HasPrefix(value, "-") {
+		sign, value = "-", value[1:]
+	}
+
+	whole, fraction, _ := strings.Cut(value, ".")
+	fraction = strings.TrimRight(fraction, "0")
+	if len(fraction) > currency.Precision {
+		return 0, fmt.Errorf("%w: %s allows %d", ErrTooManyDecimals, currency.Code, currency.Precision)
+	}
+
+	fraction += strings.Repeat("0", currency.Precision-len(fraction))
+
+	minor, err := strconv.ParseInt(sign+whole+fraction, 10, 64)
+	if err != nil {
+		return 0, ErrAmountOutOfRange
+	}
+
+	return minor, nil
+}
+
+// Format renders minor units as a decimal string with exactly the
+// currency precision, e.g. 1234 USD becomes "12.34".
+func Format(amount int64, currency Currency) string {
+	if currency.Precision == 0 {
+		return strconv.FormatInt(amount, 10)
+	}
+
+	sign := ""
+	magnitude := uint64(amount)
+	if amount < 0 {
+		sign = "-"
+		magnitude = uint64(-(amount + 1)) + 1
+	}
+
+	digits := strconv.FormatUint(magnitude, 10)
+	if len(digits) <= currency.Precision {
+		digits = strings.Repeat("0", currency.Precision-len(digits)+1) + digits
+	}
+
+	split := len(digits) - currency.Precision
+	return sign + digits[:split] + "." + digits[split:]
+}
+
+// Add returns a+b and reports overflow instead of wrapping around.
+func Add(a, b int64) (int64, error) {
+	if (b > 0 && a > math.MaxInt64-b) || (b < 0 && a < math.MinInt64-b) {
+		return 0, ErrAmountOutOfRange
+	}
+
+	return a + b, nil
+}
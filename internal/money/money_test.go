@@ -0,0 +1,176 @@
+package money_test
+
+import (
+	"errors"
+	"ledger-app/internal/money"
+	"math"
+	"testing"
+)
+
+var (
+	usd = money.Currency{Code: "USD", Precision: 2}
+	jpy = money.Currency{Code: "JPY", Precision: 0}
+	kwd = money.Currency{Code: "KWD", Precision: 3}
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		value    string
+		currency money.Currency
+		want     int64
+		err      error
+	}{
+		{"12.34", usd, 1234, nil},
+		{"12.3", usd, 1230, nil},
+		{"12", usd, 1200, nil},
+		{" 12.50 ", usd, 1250, nil},
+		{"12.340", usd, 1234, nil},
+		{"0.01", usd, 1, nil},
+		{"-0.01", usd, -1, nil},
+		{"-0", usd, 0, nil},
+		{"100", jpy, 100, nil},
+		{"1.234", kwd, 1234, nil},
+		{"92233720368547758.07", usd, math.MaxInt64, nil},
+		{"-92233720368547758.08", usd, math.MinInt64, nil},
+
+		{"12.345", usd, 0, money.ErrTooManyDecimals},
+		{"-0.001", usd, 0, money.ErrTooManyDecimals},
+		{"100.5", jpy, 0, money.ErrTooManyDecimals},
+		{"1.2345", kwd, 0, money.ErrTooManyDecimals},
+
+		{"92233720368547758.08", usd, 0, money.ErrAmountOutOfRange},
+		{"-92233720368547758.09", usd, 0, money.ErrAmountOutOfRange},
+		{"99999999999999999999", jpy, 0, money.ErrAmountOutOfRange},
+
+		{"", usd, 0, money.ErrInvalidAmount},
+		{"abc", usd, 0, money.ErrInvalidAmount},
+		{"1e5", usd, 0, money.ErrInvalidAmount},
+		{"+1", usd, 0, money.ErrInvalidAmount},
+		{"1.", usd, 0, money.ErrInvalidAmount},
+		{".5", usd, 0, money.ErrInvalidAmount},
+		{"1,000", usd, 0, money.ErrInvalidAmount},
+		{"--1", usd, 0, money.ErrInvalidAmount},
+	}
+
+	for _, tt := range tests {
+		got, err := money.Parse(tt.value, tt.currency)
+		if !errors.Is(err, tt.err) {
+			t.Errorf("Parse(%q, %s) error = %v, want %v", tt.value, tt.currency.Code, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q, %s) = %d, want %d", tt.value, tt.currency.Code, got, tt.want)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		amount   int64
+		currency money.Currency
+		want     string
+	}{
+		{1234, usd, "12.34"},
+		{5, usd, "0.05"},
+		{-5, usd, "-0.05"},
+		{0, usd, "0.00"},
+		{-1200, usd, "-12.00"},
+		{100, jpy, "100"},
+		{-100, jpy, "-100"},
+		{1, kwd, "0.001"},
+		{math.MaxInt64, usd, "92233720368547758.07"},
+		{math.MinInt64, usd, "-92233720368547758.08"},
+	}
+
+	for _, tt := range tests {
+		if got := money.Format(tt.amount, tt.currency); got != tt.want {
+			t.Errorf("Format(%d, %s) = %q, want %q", tt.amount, tt.currency.Code, got, tt.want)
+		}
+	}
+}
+
+func TestFormatParsesBack(t *testing.T) {
+	for _, amount := range []int64{0, 1, -1, 99, -101, 123456789, math.MaxInt64, math.MinInt64} {
+		for _, currency := range []money.Currency{usd, jpy, kwd} {
+			got, err := money.Parse(money.Format(amount, currency), currency)
+			if err != nil || got != amount {
+				t.Errorf("Parse(Format(%d, %s)) = %d, %v", amount, currency.Code, got, err)
+			}
+		}
+	}
+}
+
+func TestAdd(t *testing.T) {
+	tests := []struct {
+		a, b int64
+		want int64
+		err  error
+	}{
+		{1, 2, 3, nil},
+		{-1, -2, -3, nil},
+		{math.MaxInt64, 0, math.MaxInt64, nil},
+		{math.MaxInt64, math.MinInt64, -1, nil},
+		{math.MaxInt64, 1, 0, money.ErrAmountOutOfRange},
+		{math.MinInt64, -1, 0, money.ErrAmountOutOfRange},
+	}
+
+	for _, tt := range tests {
+		got, err := money.Add(tt.a, tt.b)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("Add(%d, %d) = %d, %v, want %d, %v", tt.a, tt.b, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestApplyPercent(t *testing.T) {
+	tests := []struct {
+		amount, ppm int64
+		want        int64
+		err         error
+	}{
+		{10000, 15000, 150, nil},
+		{10000, 0, 0, nil},
+		{1, 500_000, 1, nil},
+		{-1, 500_000, -1, nil},
+		{3, 500_000, 2, nil},
+		{-3, 500_000, -2, nil},
+		{1, 499_999, 0, nil},
+		{-1, 499_999, 0, nil},
+		{1, 500_001, 1, nil},
+		{-1, 500_001, -1, nil},
+		{3, -500_000, -2, nil},
+		{333, 10_000, 3, nil},
+		{-333, 10_000, -3, nil},
+		{math.MaxInt64, 1_000_000, math.MaxInt64, nil},
+		{math.MinInt64, 1_000_000, math.MinInt64, nil},
+		{math.MaxInt64, 2_000_000, 0, money.ErrAmountOutOfRange},
+		{math.MinInt64, -1_000_000, 0, money.ErrAmountOutOfRange},
+	}
+
+	for _, tt := range tests {
+		got, err := money.ApplyPercent(tt.amount, tt.ppm)
+		if !errors.Is(err, tt.err) || got != tt.want {
+			t.Errorf("ApplyPercent(%d, %d) = %d, %v, want %d, %v", tt.amount, tt.ppm, got, err, tt.want, tt.err)
+		}
+	}
+}
+
+func TestDecimalUnmarshal(t *testing.T) {
+	tests := map[string]string{
+		`"12.34"`: "12.34",
+		`12.34`:   "12.34",
+		` 5 `:     "5",
+		`null`:    "",
+	}
+
+	for input, want := range tests {
+		var d money.Decimal
+		if err := d.UnmarshalJSON([]byte(input)); err != nil {
+			t.Errorf("UnmarshalJSON(%s) error = %v", input, err)
+			continue
+		}
+		if string(d) != want {
+			t.Errorf("UnmarshalJSON(%s) = %q, want %q", input, d, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package money
+
+import (
+	"math/big"
+)
+
+// percent parses percentages with four decimals, which makes the parsed
+// value the rate in parts per million.
+var percent = Currency{Code: "%", Precision: 4}
+
+// ParsePercent converts a percentage such as "4.25" into parts per million.
+func ParsePercent(value Decimal) (int64, error) {
+	return value.MinorUnits(percent)
+}
+
+func FormatPercent(ppm int64) string {
+	return Format(ppm, percent)
+}
+
+// ApplyPercent returns ppm parts per million of amount, rounded half away
+// from zero, so a negative amount rounds the same as its positive
+// counterpart.
+func ApplyPercent(amount, ppm int64) (int64, error) {
+	product := new(big.Int).Mul(big.NewInt(amount), big.NewInt(ppm))
+	negative := product.Sign() < 0
+
+	product.Abs(product)
+	product.Add(product, big.NewInt(500_000))
+	product.Quo(product, big.NewInt(1_000_000))
+	if negative {
+		product.Neg(product)
+	}
+
+	if !product.IsInt64() {
+		return 0, ErrAmountOutOfRange
+	}
+
+	return product.Int64(), nil
+}
@@ -4,22 +4,119 @@ import (
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
 	"ledger-app/config"
+	"ledger-app/internal/approvals"
+	"ledger-app/internal/audit"
+	"ledger-app/internal/commands"
 	"ledger-app/internal/connections/database"
+	"ledger-app/internal/interest"
+	"ledger-app/internal/jobs"
+	"ledger-app/internal/ledger"
 	"ledger-app/internal/middleware"
+	"ledger-app/internal/money"
+	"ledger-app/internal/reconcile"
+	"ledger-app/internal/risk"
+	"ledger-app/internal/scheduler"
 	"ledger-app/logger"
 	"ledger-app/models"
 	"ledger-app/routes"
+	"net"
+	"strings"
+	"time"
 )
 
 func InitLogger() {
 	logger.InitLogger()
 }
 
+func InitCurrency(cfg *config.Config) {
+	if err := money.SetDefault(cfg.Currency); err != nil {
+		logger.Logger.Fatalf("Failed to set ledger currency: %v", err)
+	}
+
+	logger.Logger.Infof("Ledger currency set to %s", money.Default.Code)
+}
+
+func InitChainKey(cfg *config.Config) {
+	if cfg.ChainKey == "" {
+		logger.Logger.Warn("LEDGER_CHAIN_KEY is not set, transaction hashes are unkeyed")
+	}
+
+	ledger.SetChainKey(cfg.ChainKey)
+	audit.SetChainKey(cfg.ChainKey)
+}
+
+// InitApprovals sets the amounts above which a second admin must approve:
+// the credit threshold for credits, overdraft limits and single import
+// rows, the import threshold for the volume of a whole import. An empty
+// threshold leaves those operations to a single admin.
+func InitApprovals(cfg *config.Config) {
+	approvals.Configure(
+		approvalThreshold("CREDIT_APPROVAL_THRESHOLD", cfg.CreditApprovalLimit),
+		approvalThreshold("IMPORT_APPROVAL_THRESHOLD", cfg.ImportApprovalLimit),
+		cfg.ApprovalTTL)
+}
+
+func approvalThreshold(name, value string) int64 {
+	if value == "" {
+		return 0
+	}
+
+	threshold, err := money.Parse(value, money.Default)
+	if err != nil || threshold <= 0 {
+		logger.Logger.Fatalf("Invalid %s %q", name, value)
+	}
+
+	return threshold
+}
+
+// InitRiskRules registers the built-in risk rules on the default engine.
+// Further rules implementing risk.Rule can be registered the same way.
+func InitRiskRules(cfg *config.Config) {
+	newAccountLimit, err := money.Parse(cfg.RiskNewAccountLimit, money.Default)
+	if err != nil || newAccountLimit < 0 {
+		logger.Logger.Fatalf("Invalid RISK_NEW_ACCOUNT_LIMIT %q", cfg.RiskNewAccountLimit)
+	}
+
+	risk.Default.Register(risk.AccountAge{MinAge: cfg.RiskNewAccountAge, MaxAmount: newAccountLimit})
+	risk.Default.Register(risk.UnusualAmount{Lookback: 90 * 24 * time.Hour, MinHistory: 5, Multiplier: 10})
+	risk.Default.Register(risk.FanOut{Window: time.Hour, Review: 10, Deny: 25})
+	risk.Default.Register(risk.FailedAttempts{Window: time.Hour, Review: 5, Deny: 10})
+}
+
 func InitDatabase() {
 	database.Connect()
 }
 
+// InitIPExtractor decides where client addresses come from. Only the
+// proxies listed in TRUSTED_PROXIES, as comma separated CIDRs, may name
+// the client in X-Forwarded-For; without any the connection's own address
+// is used, so a client cannot put another address in the audit log.
+func InitIPExtractor(e *echo.Echo, cfg *config.Config) {
+	var options []echo.TrustOption
+	for _, cidr := range strings.Split(cfg.TrustedProxies, ",") {
+		cidr = strings.TrimSpace(cidr)
+		if cidr == "" {
+			continue
+		}
+
+		_, ipRange, err := net.ParseCIDR(cidr)
+		if err != nil {
+			logger.Logger.Fatalf("Invalid TRUSTED_PROXIES entry %q", cidr)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+
+	if len(options) == 0 {
+		e.IPExtractor = echo.ExtractIPDirect()
+		return
+	}
+
+	options = append(options, echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false))
+	e.IPExtractor = echo.ExtractIPFromXFFHeader(options...)
+}
+
 func RegisterMiddlewares(e *echo.Echo) {
+	e.Use(middleware.RequestID)
 	e.Use(middleware.LogRequest)
 	routes.RegisterRoutes(e)
 }
@@ -55,6 +152,87 @@ func InitDefaultAdmin() {
 	logger.Logger.Infof("Default admin created with username: %s and password: %s\n", adminConfig.DefaultAdminUserName, adminConfig.DefaultAdminPassword)
 }
 
+func RunCommand(args []string) {
+	if err := commands.Run(args); err != nil {
+		logger.Logger.Fatalf("Command %s failed: %v", args[0], err)
+	}
+}
+
+func StartJobs(cfg *config.Config) {
+	jobs.Every("expire-holds", cfg.HoldExpiryInterval, func() error {
+		expired, err := ledger.ExpireHolds(database.Db, time.Now().UTC())
+		if expired > 0 {
+			logger.Logger.Infof("Expired %d hold(s)", expired)
+		}
+		return err
+	})
+
+	jobs.Every("idempotency-keys", cfg.IdempotencyPurge, func() error {
+		purged, err := middleware.PurgeIdempotencyKeys(database.Db, time.Now().UTC())
+		if purged > 0 {
+			logger.Logger.Infof("Purged %d expired idempotency key(s)", purged)
+		}
+		return err
+	})
+
+	jobs.Every("scheduled-transfers", cfg.SchedulerInterval, func() error {
+		attempts, err := scheduler.RunDue(database.Db, time.Now().UTC())
+		if attempts > 0 {
+			logger.Logger.Infof("Ran %d scheduled transfer(s)", attempts)
+		}
+		return err
+	})
+
+	jobs.Every("balance-snapshots", cfg.SnapshotInterval, func() error {
+		taken, err := ledger.TakeSnapshots(database.Db, time.Now().UTC())
+		if taken > 0 {
+			logger.Logger.Infof("Took %d balance snapshot(s)", taken)
+		}
+		return err
+	})
+
+	jobs.Every("interest", cfg.InterestInterval, func() error {
+		paid, err := interest.Run(database.Db, time.Now().UTC())
+		if paid > 0 {
+			logger.Logger.Infof("Paid interest to %d account(s)", paid)
+		}
+		return err
+	})
+
+	jobs.Every("reconcile", cfg.ReconcileInterval, func() error {
+		report, err := reconcile.Run(database.Db, reconcile.TriggerSchedule, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if len(report.Discrepancies) > 0 {
+			logger.Logger.Errorf("Reconciliation found %d discrepancies", len(report.Discrepancies))
+		}
+		return nil
+	})
+
+	jobs.Every("expire-approvals", cfg.ApprovalExpiry, func() error {
+		expired, err := approvals.Expire(database.Db, time.Now().UTC())
+		if expired > 0 {
+			logger.Logger.Infof("Expired %d approval request(s)", expired)
+		}
+		return err
+	})
+
+	// A retention of zero keeps the audit log forever.
+	pruneInterval := cfg.AuditPruneInterval
+	if cfg.AuditRetention <= 0 {
+		pruneInterval = 0
+	}
+
+	jobs.Every("audit-retention", pruneInterval, func() error {
+		pruned, err := audit.Prune(database.Db, time.Now().UTC().Add(-cfg.AuditRetention))
+		if pruned > 0 {
+			logger.Logger.Infof("Pruned %d audit event(s)", pruned)
+		}
+		return err
+	})
+}
+
 func StartServer(e *echo.Echo, cfg *config.Config) {
 	logger.Logger.Infof("Starting server at port %s", cfg.Port)
 
@@ -0,0 +1,308 @@
+package reconcile
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"ledger-app/internal/money"
+	"ledger-app/models"
+	"sort"
+)
+
+// checkUnbalancedEntries finds journal entries whose postings do not sum
+// to zero.
+func checkUnbalancedEntries(db *gorm.DB, report *Report) error {
+	var entries []struct {
+		JournalEntryID uint
+		Total          int64
+	}
+	if err := db.Model(&models.Transaction{}).
+		Select("journal_entry_id, SUM(amount) AS total").
+		Group("journal_entry_id").
+		Having("SUM(amount) <> 0").
+		Order("journal_entry_id").
+		Limit(maxDiscrepancies).
+		Scan(&entries).Error; err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		transactionIDs, err := entryTransactionIDs(db, entry.JournalEntryID)
+		if err != nil {
+			return err
+		}
+
+		if !report.add(Discrepancy{
+			Check:          CheckUnbalancedEntry,
+			Message:        fmt.Sprintf("postings sum to %s instead of zero", money.Format(entry.Total, money.Default)),
+			JournalEntryID: entry.JournalEntryID,
+			TransactionIDs: transactionIDs,
+		}) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// checkTransferPairing checks that every transfer entry is a pair of
+// postings, and that on every posting naming a sender and receiver the
+// debited user is the sender and the credited user the receiver.
+func checkTransferPairing(db *gorm.DB, report *Report) error {
+	var unpaired []struct {
+		JournalEntryID uint
+		Postings       int
+	}
+	if err := db.Model(&models.Transaction{}).
+		Select("transactions.journal_entry_id, COUNT(*) AS postings").
+		Joins("JOIN journal_entries ON journal_entries.id = transactions.journal_entry_id").
+		Where("journal_entries.type = ?", models.EntryTypeTransfer).
+		Group("transactions.journal_entry_id").
+		Having("COUNT(*) <> 2").
+		Order("transactions.journal_entry_id").
+		Limit(maxDiscrepancies).
+		Scan(&unpaired).Error; err != nil {
+		return err
+	}
+
+	for _, entry := range unpaired {
+		transactionIDs, err := entryTransactionIDs(db, entry.JournalEntryID)
+		if err != nil {
+			return err
+		}
+
+		if !report.add(Discrepancy{
+			Check:          CheckTransferPairing,
+			Message:        fmt.Sprintf("transfer has %d postings instead of 2", entry.Postings),
+			JournalEntryID: entry.JournalEntryID,
+			TransactionIDs: transactionIDs,
+		}) {
+			return nil
+		}
+	}
+
+	var mismatched []models.Transaction
+	if err := db.Where("sender_id IS NOT NULL OR receiver_id IS NOT NULL").
+		Where("(amount < 0 AND (user_id IS NULL OR sender_id IS NULL OR user_id <> sender_id)) OR " +
+			"(amount > 0 AND (user_id IS NULL OR receiver_id IS NULL OR user_id <> receiver_id))").
+		Order("id").
+		Limit(maxDiscrepancies).
+		Find(&mismatched).Error; err != nil {
+		return err
+	}
+
+	for _, posting := range mismatched {
+		if !report.add(Discrepancy{
+			Check:          CheckTransferPairing,
+			Message:        "posting user does not match the sender or receiver",
+			JournalEntryID: posting.JournalEntryID,
+			TransactionIDs: []uint{posting.ID},
+			AccountID:      posting.AccountID,
+		}) {
+			break
+		}
+	}
+
+	return nil
+}
+
+// checkBalances compares every stored balance with the sum of its
+// postings and flags user accounts below their overdraft limit.
+func checkBalances(db *gorm.DB, report *Report) error {
+	var accounts []struct {
+		models.Account
+		Total int64
+	}
+	if err := db.Table("accounts").
+		Select("accounts.*, COALESCE((SELECT SUM(amount) FROM transactions WHERE transactions.account_id = accounts.id), 0) AS total").
+		Order("accounts.id").
+		Scan(&accounts).Error; err != nil {
+		return err
+	}
+
+	for _, account := range accounts {
+		if account.Balance != account.Total {
+			if !report.add(Discrepancy{
+				Check: CheckBalanceDrift,
+				Message: fmt.Sprintf("account %s stores %s but its postings sum to %s", account.Code,
+					money.Format(account.Balance, money.Default), money.Format(account.Total, money.Default)),
+				AccountID: account.ID,
+			}) {
+				return nil
+			}
+		}
+
+		if account.Type == models.AccountTypeUser && account.Headroom() < 0 {
+			var lastPosting uint
+			if err := db.Model(&models.Transaction{}).
+				Where("account_id = ? AND amount < 0", account.ID).
+				Select("COALESCE(MAX(id), 0)").
+				Scan(&lastPosting).Error; err != nil {
+				return err
+			}
+
+			discrepancy := Discrepancy{
+				Check: CheckBelowLimit,
+				Message: fmt.Sprintf("account %s is %s below its limit", account.Code,
+					money.Format(-account.Headroom(), money.Default)),
+				AccountID: account.ID,
+			}
+			if lastPosting != 0 {
+				discrepancy.TransactionIDs = []uint{lastPosting}
+			}
+
+			if !report.add(discrepancy) {
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// flowAccounts names the system account on the other side of user
+// postings for each entry type. Transfers move money between users and
+// must net to zero.
+var flowAccounts = map[string]string{
+	models.EntryTypeCredit:     models.SystemAccountIssuance,
+	models.EntryTypeWithdrawal: models.SystemAccountWithdrawals,
+	models.EntryTypeImport:     models.SystemAccountMigration,
+	models.EntryTypeInterest:   models.SystemAccountInterest,
+	models.EntryTypeFee:        models.SystemAccountFeeRevenue,
+	models.EntryTypeClosure:    models.SystemAccountClosed,
+}
+
+// checkSupply checks that the money held by users equals admin credits
+// minus withdrawals, plus whatever the other system accounts (imports,
+// interest, fees) moved in or out. It then checks each flow on its own:
+// the user postings of every entry type, with reversals counted under the
+// type they reverse, must match the balance of the system account that
+// type posts against, so money routed through the wrong account or a
+// system balance edited by hand does not hide behind a matching total.
+func checkSupply(db *gorm.DB, report *Report) error {
+	var balances []struct {
+		Type    string
+		Code    string
+		Balance int64
+	}
+	if err := db.Model(&models.Account{}).
+		Select("type, code, balance").
+		Scan(&balances).Error; err != nil {
+		return err
+	}
+
+	var flows []struct {
+		Type  string
+		Total int64
+	}
+	if err := db.Raw(`SELECT COALESCE(originals.type, journal_entries.type) AS type, SUM(transactions.amount) AS total
+		FROM transactions
+		JOIN accounts ON accounts.id = transactions.account_id
+		JOIN journal_entries ON journal_entries.id = transactions.journal_entry_id
+		LEFT JOIN journal_entries originals ON originals.id = journal_entries.reversal_of_id
+		WHERE accounts.type = ?
+		GROUP BY COALESCE(originals.type, journal_entries.type)`, models.AccountTypeUser).
+		Scan(&flows).Error; err != nil {
+		return err
+	}
+
+	supply := Supply{Other: make(map[string]int64), Flows: make(map[string]int64)}
+	systemBalances := make(map[string]int64)
+	for _, account := range balances {
+		switch {
+		case account.Type == models.AccountTypeUser:
+			supply.UserBalances += account.Balance
+			continue
+		case account.Code == models.SystemAccountIssuance:
+			supply.Credits = -account.Balance
+		case account.Code == models.SystemAccountWithdrawals:
+			supply.Withdrawals = account.Balance
+		default:
+			supply.Other[account.Code] = -account.Balance
+		}
+		systemBalances[account.Code] = account.Balance
+	}
+
+	supply.Expected = supply.Credits - supply.Withdrawals
+	for _, amount := range supply.Other {
+		supply.Expected += amount
+	}
+
+	for _, flow := range flows {
+		supply.Flows[flow.Type] = flow.Total
+	}
+
+	report.Supply = supply
+
+	if supply.UserBalances != supply.Expected {
+		report.add(Discrepancy{
+			Check: CheckTotalSupply,
+			Message: fmt.Sprintf("users hold %s but credits, withdrawals and other system flows account for %s",
+				money.Format(supply.UserBalances, money.Default), money.Format(supply.Expected, money.Default)),
+		})
+	}
+
+	// Walking the types in order keeps reports of the same ledger identical.
+	entryTypes := make([]string, 0, len(supply.Flows)+len(flowAccounts))
+	for entryType := range supply.Flows {
+		entryTypes = append(entryTypes, entryType)
+	}
+	for entryType := range flowAccounts {
+		if _, ok := supply.Flows[entryType]; !ok {
+			entryTypes = append(entryTypes, entryType)
+		}
+	}
+	sort.Strings(entryTypes)
+
+	for _, entryType := range entryTypes {
+		total := supply.Flows[entryType]
+		code, ok := flowAccounts[entryType]
+
+		var message string
+		switch {
+		case ok && total != -systemBalances[code]:
+			message = fmt.Sprintf("%s entries moved %s into user accounts but %s stands at %s",
+				entryType, money.Format(total, money.Default), code, money.Format(systemBalances[code], money.Default))
+		case ok || total == 0:
+			continue
+		case entryType == models.EntryTypeTransfer:
+			message = fmt.Sprintf("transfers moved %s into user accounts instead of netting to zero", money.Format(total, money.Default))
+		default:
+			message = fmt.Sprintf("%s entries moved %s into user accounts that no system account accounts for", entryType, money.Format(total, money.Default))
+		}
+
+		report.add(Discrepancy{Check: CheckTotalSupply, Message: message})
+	}
+
+	codes := make([]string, 0, len(systemBalances))
+	for code := range systemBalances {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	for _, code := range codes {
+		if !isFlowAccount(code) && systemBalances[code] != 0 {
+			report.add(Discrepancy{
+				Check:   CheckTotalSupply,
+				Message: fmt.Sprintf("system account %s holds %s that no entry type accounts for", code, money.Format(systemBalances[code], money.Default)),
+			})
+		}
+	}
+
+	return nil
+}
+
+func isFlowAccount(code string) bool {
+	for _, flowCode := range flowAccounts {
+		if flowCode == code {
+			return true
+		}
+	}
+
+	return false
+}
+
+func entryTransactionIDs(db *gorm.DB, entryID uint) ([]uint, error) {
+	var ids []uint
+	err := db.Model(&models.Transaction{}).Where("journal_entry_id = ?", entryID).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
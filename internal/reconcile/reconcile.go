@@ -0,0 +1,94 @@
+package reconcile
+
+import (
+	"encoding/json"
+	"gorm.io/gorm"
+	"ledger-app/models"
+	"time"
+)
+
+const (
+	TriggerManual   = "manual"
+	TriggerSchedule = "schedule"
+)
+
+const (
+	CheckUnbalancedEntry = "unbalanced_entry"
+	CheckTransferPairing = "transfer_pairing"
+	CheckBalanceDrift    = "balance_drift"
+	CheckBelowLimit      = "below_limit"
+	CheckTotalSupply     = "total_supply"
+
+	// maxDiscrepancies caps the size of a report; a ledger this broken
+	// needs a person, not a longer list.
+	maxDiscrepancies = 1000
+)
+
+type Discrepancy struct {
+	Check          string `json:"check"`
+	Message        string `json:"message"`
+	JournalEntryID uint   `json:"journal_entry_id,omitempty"`
+	TransactionIDs []uint `json:"transaction_ids,omitempty"`
+	AccountID      uint   `json:"account_id,omitempty"`
+}
+
+// Supply compares the money held by users with what the system accounts
+// have put into and taken out of circulation. Flows holds what the user
+// accounts gained through each entry type.
+type Supply struct {
+	UserBalances int64            `json:"user_balances"`
+	Credits      int64            `json:"credits"`
+	Withdrawals  int64            `json:"withdrawals"`
+	Other        map[string]int64 `json:"other"`
+	Expected     int64            `json:"expected"`
+	Flows        map[string]int64 `json:"flows"`
+}
+
+type Report struct {
+	RanAt         time.Time     `json:"ran_at"`
+	Supply        Supply        `json:"supply"`
+	Discrepancies []Discrepancy `json:"discrepancies"`
+	Truncated     bool          `json:"truncated"`
+}
+
+func (r *Report) add(d Discrepancy) bool {
+	if len(r.Discrepancies) >= maxDiscrepancies {
+		r.Truncated = true
+		return false
+	}
+
+	r.Discrepancies = append(r.Discrepancies, d)
+	return true
+}
+
+// Run checks the ledger invariants and stores the report as a
+// ReconciliationRun. It only reads the ledger; nothing is corrected.
+func Run(db *gorm.DB, trigger string, now time.Time) (*Report, error) {
+	report := &Report{RanAt: now, Discrepancies: []Discrepancy{}}
+
+	checks := []func(*gorm.DB, *Report) error{
+		checkUnbalancedEntries,
+		checkTransferPairing,
+		checkBalances,
+		checkSupply,
+	}
+
+	for _, check := range checks {
+		if err := check(db, report); err != nil {
+			return nil, err
+		}
+	}
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return nil, err
+	}
+
+	run := models.ReconciliationRun{
+		Trigger:       trigger,
+		Discrepancies: len(report.Discrepancies),
+		Report:        string(data),
+	}
+
+	return report, db.Create(&run).Error
+}
@@ -0,0 +1,75 @@
+package risk
+
+import (
+	"encoding/json"
+	"errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/models"
+	"time"
+)
+
+var (
+	ErrReviewNotFound   = errors.New("review not found")
+	ErrReviewNotPending = errors.New("review has already been decided")
+)
+
+// Park queues a transfer the rules want reviewed.
+func Park(db *gorm.DB, transfer *Transfer, meta models.EntryMetadata, assessment *Assessment) (*models.RiskReview, error) {
+	metadata, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+
+	findings, err := json.Marshal(assessment.Findings)
+	if err != nil {
+		return nil, err
+	}
+
+	review := &models.RiskReview{
+		SenderID:   transfer.SenderID,
+		ReceiverID: transfer.ReceiverID,
+		Amount:     transfer.Amount,
+		Metadata:   string(metadata),
+		Findings:   string(findings),
+		Status:     models.RiskReviewPending,
+	}
+
+	return review, db.Create(review).Error
+}
+
+func Reject(tx *gorm.DB, id, adminID uint, reason string, now time.Time) (*models.RiskReview, error) {
+	review, err := LockPending(tx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return review, Decide(tx, review, models.RiskReviewRejected, adminID, reason, now)
+}
+
+// LockPending locks a review that has not been decided yet.
+func LockPending(tx *gorm.DB, id uint) (*models.RiskReview, error) {
+	var review models.RiskReview
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&review, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrReviewNotFound
+		}
+		return nil, err
+	}
+
+	if review.Status != models.RiskReviewPending {
+		return nil, ErrReviewNotPending
+	}
+
+	return &review, nil
+}
+
+// Decide records the admin's decision on a locked review.
+func Decide(tx *gorm.DB, review *models.RiskReview, status string, adminID uint, reason string, now time.Time) error {
+	review.Status = status
+	review.DecidedBy = &adminID
+	review.DecisionReason = reason
+	review.DecidedAt = &now
+
+	return tx.Save(review).Error
+}
@@ -0,0 +1,105 @@
+package risk
+
+import (
+	"gorm.io/gorm"
+	"time"
+)
+
+// Decision is a rule's verdict on a transfer. Decisions are ordered, so
+// the strictest one of a chain is the largest.
+type Decision int
+
+const (
+	Allow Decision = iota
+	Review
+	Deny
+)
+
+func (d Decision) String() string {
+	switch d {
+	case Review:
+		return "review"
+	case Deny:
+		return "deny"
+	default:
+		return "allow"
+	}
+}
+
+func (d Decision) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// Transfer is what the rules are shown before a transfer is posted.
+type Transfer struct {
+	SenderID   uint
+	ReceiverID uint
+	Amount     int64
+	At         time.Time
+
+	// Pending are the sender's other transfers that are about to be posted
+	// together with this one, such as the rest of a batch. They are not
+	// in the database yet, so rules judging the sender's recent activity
+	// must count them.
+	Pending []Transfer
+}
+
+// Rule inspects a transfer. Rules read the database but must not write
+// to it. A rule that has nothing to say returns Allow with no reason.
+type Rule interface {
+	Name() string
+	Evaluate(db *gorm.DB, transfer *Transfer) (Decision, string, error)
+}
+
+// Finding is one rule that did not allow the transfer.
+type Finding struct {
+	Rule     string   `json:"rule"`
+	Decision Decision `json:"decision"`
+	Reason   string   `json:"reason"`
+}
+
+// Assessment is the combined verdict of a chain of rules.
+type Assessment struct {
+	Decision Decision
+	Findings []Finding
+}
+
+// Engine runs its rules in order. Every rule runs even after one denies,
+// so the findings are complete.
+type Engine struct {
+	rules []Rule
+}
+
+func NewEngine(rules ...Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+func (e *Engine) Register(rule Rule) {
+	e.rules = append(e.rules, rule)
+}
+
+func (e *Engine) Evaluate(db *gorm.DB, transfer *Transfer) (*Assessment, error) {
+	assessment := &Assessment{Decision: Allow, Findings: []Finding{}}
+
+	for _, rule := range e.rules {
+		decision, reason, err := rule.Evaluate(db, transfer)
+		if err != nil {
+			return nil, err
+		}
+
+		if decision == Allow {
+			continue
+		}
+
+		assessment.Findings = append(assessment.Findings, Finding{Rule: rule.Name(), Decision: decision, Reason: reason})
+		if decision > assessment.Decision {
+			assessment.Decision = decision
+		}
+	}
+
+	return assessment, nil
+}
+
+// Default is the engine package transfers consults. It has no rules
+// until the application registers them at startup.
+var Default = NewEngine()
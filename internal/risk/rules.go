@@ -0,0 +1,142 @@
+package risk
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"ledger-app/internal/money"
+	"ledger-app/models"
+	"time"
+)
+
+// AccountAge reviews transfers above MaxAmount from accounts opened less
+// than MinAge ago. A MaxAmount of zero reviews all of them.
+type AccountAge struct {
+	MinAge    time.Duration
+	MaxAmount int64
+}
+
+func (r AccountAge) Name() string { return "account_age" }
+
+func (r AccountAge) Evaluate(db *gorm.DB, transfer *Transfer) (Decision, string, error) {
+	var account models.Account
+	err := db.Where("user_id = ?", transfer.SenderID).Limit(1).Find(&account).Error
+	if err != nil || account.ID == 0 {
+		return Allow, "", err
+	}
+
+	age := transfer.At.Sub(account.CreatedAt)
+	if age >= r.MinAge || transfer.Amount <= r.MaxAmount {
+		return Allow, "", nil
+	}
+
+	return Review, fmt.Sprintf("account is %s old, younger than %s", age.Truncate(time.Minute), r.MinAge), nil
+}
+
+// UnusualAmount reviews transfers more than Multiplier times the
+// sender's average transfer over Lookback. Senders with fewer than
+// MinHistory transfers are not judged.
+type UnusualAmount struct {
+	Lookback   time.Duration
+	MinHistory int
+	Multiplier int64
+}
+
+func (r UnusualAmount) Name() string { return "unusual_amount" }
+
+func (r UnusualAmount) Evaluate(db *gorm.DB, transfer *Transfer) (Decision, string, error) {
+	var history struct {
+		Total int64
+		Count int64
+	}
+	if err := db.Table("transactions").
+		Select("COALESCE(SUM(-transactions.amount), 0) AS total, COUNT(*) AS count").
+		Joins("JOIN journal_entries ON journal_entries.id = transactions.journal_entry_id").
+		Where("transactions.user_id = ? AND transactions.amount < 0", transfer.SenderID).
+		Where("journal_entries.type = ? AND journal_entries.posted_at >= ?", models.EntryTypeTransfer, transfer.At.Add(-r.Lookback)).
+		Scan(&history).Error; err != nil {
+		return Allow, "", err
+	}
+
+	if history.Count < int64(r.MinHistory) {
+		return Allow, "", nil
+	}
+
+	average := history.Total / history.Count
+	if transfer.Amount <= average*r.Multiplier {
+		return Allow, "", nil
+	}
+
+	return Review, fmt.Sprintf("amount is more than %d times the average transfer of %s",
+		r.Multiplier, money.Format(average, money.Default)), nil
+}
+
+// FanOut flags senders paying many different receivers within Window,
+// counting the receivers of this transfer and of its pending ones. Zero
+// thresholds are off.
+type FanOut struct {
+	Window time.Duration
+	Review int
+	Deny   int
+}
+
+func (r FanOut) Name() string { return "fan_out" }
+
+func (r FanOut) Evaluate(db *gorm.DB, transfer *Transfer) (Decision, string, error) {
+	var receivers []uint
+	if err := db.Model(&models.Transaction{}).
+		Distinct("receiver_id").
+		Where("sender_id = ? AND user_id = ? AND amount < 0 AND transaction_time >= ?",
+			transfer.SenderID, transfer.SenderID, transfer.At.Add(-r.Window)).
+		Pluck("receiver_id", &receivers).Error; err != nil {
+		return Allow, "", err
+	}
+
+	seen := make(map[uint]bool, len(receivers)+len(transfer.Pending)+1)
+	for _, receiverID := range receivers {
+		seen[receiverID] = true
+	}
+	for _, pending := range transfer.Pending {
+		seen[pending.ReceiverID] = true
+	}
+	seen[transfer.ReceiverID] = true
+	count := len(seen)
+
+	return threshold(count, r.Review, r.Deny, fmt.Sprintf("%d different receivers within %s", count, r.Window))
+}
+
+// FailedAttempts flags senders with many refused operations within
+// Window. Zero thresholds are off.
+type FailedAttempts struct {
+	Window time.Duration
+	Review int
+	Deny   int
+}
+
+func (r FailedAttempts) Name() string { return "failed_attempts" }
+
+func (r FailedAttempts) Evaluate(db *gorm.DB, transfer *Transfer) (Decision, string, error) {
+	var count int64
+	if err := db.Model(&models.FailedAttempt{}).
+		Where("user_id = ? AND created_at >= ?", transfer.SenderID, transfer.At.Add(-r.Window)).
+		Count(&count).Error; err != nil {
+		return Allow, "", err
+	}
+
+	return threshold(int(count), r.Review, r.Deny, fmt.Sprintf("%d failed attempts within %s", count, r.Window))
+}
+
+func threshold(count, review, deny int, reason string) (Decision, string, error) {
+	switch {
+	case deny > 0 && count >= deny:
+		return Deny, reason, nil
+	case review > 0 && count >= review:
+		return Review, reason, nil
+	}
+
+	return Allow, "", nil
+}
+
+// RecordFailure remembers a refused operation for the FailedAttempts rule.
+func RecordFailure(db *gorm.DB, userID uint, operation, reason string) error {
+	return db.Create(&models.FailedAttempt{UserID: userID, Operation: operation, Reason: reason}).Error
+}
@@ -0,0 +1,161 @@
+package scheduler
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/risk"
+	"ledger-app/internal/transfers"
+	"ledger-app/internal/velocity"
+	"ledger-app/logger"
+	"ledger-app/models"
+	"time"
+)
+
+// RunDue executes every active scheduled transfer whose next run is at or
+// before now and returns how many attempts were made. Each attempt is
+// recorded, whether the transfer went through or was refused. A schedule
+// that fails for any other reason, such as a deadlock or a lost
+// connection, is left due so the next run retries it; the others still
+// run and the errors are returned together.
+func RunDue(db *gorm.DB, now time.Time) (int, error) {
+	var ids []uint
+	if err := db.Model(&models.ScheduledTransfer{}).
+		Where("status = ? AND next_run_at <= ?", models.ScheduleStatusActive, now).
+		Order("next_run_at").
+		Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	attempts := 0
+	var errs []error
+	for _, id := range ids {
+		ran, err := runOne(db, id, now)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("scheduled transfer %d: %w", id, err))
+			continue
+		}
+		if ran {
+			attempts++
+		}
+	}
+
+	return attempts, errors.Join(errs...)
+}
+
+// refused reports whether the transfer was turned down for good on this
+// run: the money, the users, the limits or the risk rules do not allow
+// it. Those runs are recorded as failed and the schedule moves on.
+func refused(err error) bool {
+	var riskErr *transfers.RiskError
+	var limitErr *velocity.LimitError
+
+	return errors.Is(err, ledger.ErrInsufficientBalance) ||
+		errors.Is(err, models.ErrUserInactive) ||
+		errors.Is(err, ledger.ErrAccountClosed) ||
+		errors.Is(err, transfers.ErrSelfTransfer) ||
+		errors.As(err, &limitErr) ||
+		errors.As(err, &riskErr)
+}
+
+func runOne(db *gorm.DB, scheduleID uint, now time.Time) (bool, error) {
+	ran := false
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var schedule models.ScheduledTransfer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&schedule, scheduleID).Error; err != nil {
+			return err
+		}
+
+		// Another instance may have run or paused it since it was selected.
+		if schedule.Status != models.ScheduleStatusActive || schedule.NextRunAt.After(now) {
+			return nil
+		}
+
+		run := models.ScheduleRun{
+			ScheduledTransferID: schedule.ID,
+			RunAt:               now,
+			Status:              models.ScheduleRunSucceeded,
+		}
+
+		request := &transfers.Request{
+			SenderID:   schedule.SenderID,
+			ReceiverID: schedule.ReceiverID,
+			Amount:     schedule.Amount,
+			Meta: models.EntryMetadata{
+				Description: fmt.Sprintf("Scheduled transfer %d", schedule.ID),
+				Reference:   fmt.Sprintf("schedule:%d:%d", schedule.ID, schedule.RunCount+1),
+				Category:    "scheduled",
+			},
+		}
+
+		err := tx.Transaction(func(inner *gorm.DB) error {
+			result, err := transfers.Execute(inner, request, now)
+			if err != nil {
+				return err
+			}
+
+			run.JournalEntryID = &result.Entry.ID
+			return nil
+		})
+
+		// A run the risk rules want reviewed is parked like any other
+		// transfer; releasing the review posts it.
+		var riskErr *transfers.RiskError
+		if errors.As(err, &riskErr) && riskErr.Assessment.Decision == risk.Review {
+			transfer := &risk.Transfer{SenderID: request.SenderID, ReceiverID: request.ReceiverID, Amount: request.Amount}
+			review, parkErr := risk.Park(tx, transfer, request.Meta, riskErr.Assessment)
+			if parkErr != nil {
+				return parkErr
+			}
+			err = fmt.Errorf("%w: held as review %d", err, review.ID)
+		}
+
+		if err != nil {
+			// Anything but a refusal rolls the run back and leaves the
+			// schedule due, so the next run retries it.
+			if !refused(err) {
+				return err
+			}
+
+			run.Status = models.ScheduleRunFailed
+			run.FailureReason = truncate(err.Error(), 255)
+			logger.Logger.Warnf("Scheduled transfer %d failed: %v", schedule.ID, err)
+		}
+
+		if err := tx.Create(&run).Error; err != nil {
+			return err
+		}
+
+		schedule.RunCount++
+		advance(&schedule)
+		ran = true
+
+		return tx.Save(&schedule).Error
+	})
+
+	return ran, err
+}
+
+// advance moves the schedule to its next occurrence or marks it completed
+// once it has used up its count, passed its end date or was a one-off.
+func advance(schedule *models.ScheduledTransfer) {
+	schedule.NextRunAt = schedule.RunAt(schedule.RunCount)
+
+	switch {
+	case schedule.Frequency == models.ScheduleFrequencyOnce,
+		schedule.MaxRuns != nil && schedule.RunCount >= *schedule.MaxRuns,
+		schedule.EndAt != nil && schedule.NextRunAt.After(*schedule.EndAt):
+		schedule.Status = models.ScheduleStatusCompleted
+	}
+}
+
+func truncate(value string, length int) string {
+	if len(value) <= length {
+		return value
+	}
+
+	return value[:length]
+}
@@ -0,0 +1,63 @@
+package statement
+
+import (
+	"encoding/csv"
+	"io"
+	"ledger-app/internal/history"
+	"ledger-app/internal/money"
+	"strconv"
+	"time"
+)
+
+type csvWriter struct {
+	csv      *csv.Writer
+	currency money.Currency
+}
+
+func newCSVWriter(w io.Writer) Writer {
+	return &csvWriter{csv: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) Begin(h *Header) error {
+	w.currency = h.Currency
+
+	if err := w.csv.Write([]string{"date", "transaction_id", "journal_entry_id", "type", "counterparty", "description", "reference", "category", "amount", "balance", "currency"}); err != nil {
+		return err
+	}
+
+	return w.csv.Write([]string{h.From.UTC().Format(time.RFC3339), "", "", "opening_balance", "", "Opening balance", "", "", "", money.Format(h.Opening, w.currency), w.currency.Code})
+}
+
+func (w *csvWriter) Row(row *history.Row, balance int64) error {
+	counterparty := ""
+	if id := row.CounterpartyID(); id != nil {
+		counterparty = uintString(*id)
+	}
+
+	return w.csv.Write([]string{
+		row.TransactionTime.UTC().Format(time.RFC3339),
+		uintString(row.ID),
+		uintString(row.JournalEntryID),
+		row.Type,
+		counterparty,
+		row.Description,
+		row.Reference,
+		row.Category,
+		money.Format(row.Amount, w.currency),
+		money.Format(balance, w.currency),
+		w.currency.Code,
+	})
+}
+
+func (w *csvWriter) End(h *Header, closing int64) error {
+	if err := w.csv.Write([]string{h.To.UTC().Format(time.RFC3339), "", "", "closing_balance", "", "Closing balance", "", "", "", money.Format(closing, w.currency), w.currency.Code}); err != nil {
+		return err
+	}
+
+	w.csv.Flush()
+	return w.csv.Error()
+}
+
+func uintString(value uint) string {
+	return strconv.FormatUint(uint64(value), 10)
+}
@@ -0,0 +1,82 @@
+package statement
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"ledger-app/internal/history"
+	"ledger-app/internal/money"
+	"strings"
+	"time"
+)
+
+const ofxDate = "20060102150405"
+
+type ofxWriter struct {
+	out      *bufio.Writer
+	currency money.Currency
+}
+
+func newOFXWriter(w io.Writer) Writer {
+	return &ofxWriter{out: bufio.NewWriter(w)}
+}
+
+// Begin writes an OFX 2.2 bank statement header. The transaction list is
+// left open so rows can be streamed into it.
+func (w *ofxWriter) Begin(h *Header) error {
+	w.currency = h.Currency
+	now := time.Now().UTC().Format(ofxDate)
+
+	fmt.Fprintln(w.out, `<?xml version="1.0" encoding="UTF-8" standalone="no"?>`)
+	fmt.Fprintln(w.out, `<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>`)
+	fmt.Fprintln(w.out, "<OFX>")
+	fmt.Fprintf(w.out, "<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><DTSERVER>%s</DTSERVER><LANGUAGE>ENG</LANGUAGE></SONRS></SIGNONMSGSRSV1>\n", now)
+	fmt.Fprintln(w.out, "<BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID><STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS><STMTRS>")
+	fmt.Fprintf(w.out, "<CURDEF>%s</CURDEF>\n", w.currency.Code)
+	fmt.Fprintf(w.out, "<BANKACCTFROM><BANKID>LEDGER</BANKID><ACCTID>%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n", ofxText(h.Account, 22))
+	_, err := fmt.Fprintf(w.out, "<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n", h.From.UTC().Format(ofxDate), h.To.UTC().Format(ofxDate))
+	return err
+}
+
+func (w *ofxWriter) Row(row *history.Row, _ int64) error {
+	transactionType := "CREDIT"
+	if row.Amount < 0 {
+		transactionType = "DEBIT"
+	}
+
+	fmt.Fprint(w.out, "<STMTTRN>")
+	fmt.Fprintf(w.out, "<TRNTYPE>%s</TRNTYPE>", transactionType)
+	fmt.Fprintf(w.out, "<DTPOSTED>%s</DTPOSTED>", row.TransactionTime.UTC().Format(ofxDate))
+	fmt.Fprintf(w.out, "<TRNAMT>%s</TRNAMT>", money.Format(row.Amount, w.currency))
+	fmt.Fprintf(w.out, "<FITID>%d</FITID>", row.ID)
+	if row.Reference != "" {
+		fmt.Fprintf(w.out, "<REFNUM>%s</REFNUM>", ofxText(row.Reference, 32))
+	}
+	fmt.Fprintf(w.out, "<NAME>%s</NAME>", ofxText(counterpartyLabel(row), 32))
+	if row.Description != "" {
+		fmt.Fprintf(w.out, "<MEMO>%s</MEMO>", ofxText(row.Description, 255))
+	}
+	_, err := fmt.Fprintln(w.out, "</STMTTRN>")
+	return err
+}
+
+func (w *ofxWriter) End(h *Header, closing int64) error {
+	fmt.Fprintln(w.out, "</BANKTRANLIST>")
+	fmt.Fprintf(w.out, "<LEDGERBAL><BALAMT>%s</BALAMT><DTASOF>%s</DTASOF></LEDGERBAL>\n", money.Format(closing, w.currency), h.To.UTC().Format(ofxDate))
+	fmt.Fprintln(w.out, "</STMTRS></STMTTRNRS></BANKMSGSRSV1>")
+	fmt.Fprintln(w.out, "</OFX>")
+	return w.out.Flush()
+}
+
+// ofxText escapes value for XML and cuts it to the field's maximum length.
+func ofxText(value string, length int) string {
+	runes := []rune(value)
+	if len(runes) > length {
+		runes = runes[:length]
+	}
+
+	var escaped strings.Builder
+	_ = xml.EscapeText(&escaped, []byte(string(runes)))
+	return escaped.String()
+}
@@ -0,0 +1,61 @@
+package statement
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"ledger-app/internal/history"
+	"ledger-app/internal/money"
+	"strings"
+	"time"
+)
+
+const qifDate = "01/02/2006"
+
+type qifWriter struct {
+	out      *bufio.Writer
+	currency money.Currency
+}
+
+func newQIFWriter(w io.Writer) Writer {
+	return &qifWriter{out: bufio.NewWriter(w)}
+}
+
+// Begin writes the opening balance as the first record, the way
+// accounting tools expect a QIF bank statement to start.
+func (w *qifWriter) Begin(h *Header) error {
+	w.currency = h.Currency
+
+	fmt.Fprintln(w.out, "!Type:Bank")
+	return w.record(h.From, h.Opening, "Opening Balance", "", "["+h.Account+"]", "")
+}
+
+func (w *qifWriter) Row(row *history.Row, _ int64) error {
+	return w.record(row.TransactionTime, row.Amount, counterpartyLabel(row), row.Description, row.Category, uintString(row.ID))
+}
+
+func (w *qifWriter) End(_ *Header, _ int64) error {
+	return w.out.Flush()
+}
+
+func (w *qifWriter) record(date time.Time, amount int64, payee, memo, category, number string) error {
+	fmt.Fprintf(w.out, "D%s\n", date.UTC().Format(qifDate))
+	fmt.Fprintf(w.out, "T%s\n", money.Format(amount, w.currency))
+	if number != "" {
+		fmt.Fprintf(w.out, "N%s\n", number)
+	}
+	fmt.Fprintf(w.out, "P%s\n", qifText(payee))
+	if memo != "" {
+		fmt.Fprintf(w.out, "M%s\n", qifText(memo))
+	}
+	if category != "" {
+		fmt.Fprintf(w.out, "L%s\n", qifText(category))
+	}
+	_, err := fmt.Fprintln(w.out, "^")
+	return err
+}
+
+// qifText keeps free text on a single line, as every QIF field is one line.
+func qifText(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
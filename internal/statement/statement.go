@@ -0,0 +1,111 @@
+package statement
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"io"
+	"ledger-app/internal/history"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/money"
+	"time"
+)
+
+var ErrUnknownFormat = errors.New("unknown statement format, use csv, ofx or qif")
+
+// Header describes the account and period a statement covers.
+type Header struct {
+	UserID   uint
+	UserName string
+	Account  string
+	From     time.Time
+	To       time.Time
+	Opening  int64
+	Currency money.Currency
+}
+
+// Writer renders a statement one posting at a time so it never has to
+// hold the whole history in memory.
+type Writer interface {
+	Begin(h *Header) error
+	Row(row *history.Row, balance int64) error
+	End(h *Header, closing int64) error
+}
+
+type format struct {
+	contentType string
+	extension   string
+	newWriter   func(w io.Writer) Writer
+}
+
+var formats = map[string]format{
+	"csv": {contentType: "text/csv; charset=utf-8", extension: "csv", newWriter: newCSVWriter},
+	"ofx": {contentType: "application/x-ofx", extension: "ofx", newWriter: newOFXWriter},
+	"qif": {contentType: "application/qif", extension: "qif", newWriter: newQIFWriter},
+}
+
+// ContentType returns the MIME type and file extension of a format.
+func ContentType(name string) (string, string, error) {
+	f, ok := formats[name]
+	if !ok {
+		return "", "", ErrUnknownFormat
+	}
+
+	return f.contentType, f.extension, nil
+}
+
+// Write streams the statement for accountID in the named format. The
+// opening balance is the balance just before h.From and the closing
+// balance the one just before h.To, as GetUserBalanceAtTime reports them.
+func Write(db *gorm.DB, w io.Writer, name string, accountID uint, h *Header) (int64, error) {
+	f, ok := formats[name]
+	if !ok {
+		return 0, ErrUnknownFormat
+	}
+
+	opening, err := ledger.BalanceAt(db, accountID, h.From)
+	if err != nil {
+		return 0, err
+	}
+	h.Opening = opening
+
+	rows, err := history.Apply(db, accountID, history.Filter{From: &h.From, To: &h.To}).
+		Select(history.Columns).
+		Order("transactions.transaction_time, transactions.id").
+		Rows()
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	writer := f.newWriter(w)
+	if err := writer.Begin(h); err != nil {
+		return 0, err
+	}
+
+	balance := opening
+	for rows.Next() {
+		var row history.Row
+		if err := db.ScanRows(rows, &row); err != nil {
+			return 0, err
+		}
+
+		balance += row.Amount
+		if err := writer.Row(&row, balance); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	return balance, writer.End(h, balance)
+}
+
+func counterpartyLabel(row *history.Row) string {
+	if id := row.CounterpartyID(); id != nil {
+		return "User " + uintString(*id)
+	}
+
+	return row.Type
+}
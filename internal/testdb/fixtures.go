@@ -0,0 +1,56 @@
+package testdb
+
+import (
+	"gorm.io/gorm"
+	"ledger-app/internal/ledger"
+	"ledger-app/models"
+	"testing"
+)
+
+// NewUser creates an active user, opens their account and funds it with
+// balance credited from the issuance account.
+func NewUser(t testing.TB, db *gorm.DB, name string, balance int64) *models.User {
+	t.Helper()
+
+	user := &models.User{Name: name, PasswordHash: "x"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if _, err := ledger.UserAccount(tx, user.ID); err != nil {
+			return err
+		}
+		if balance == 0 {
+			return nil
+		}
+		_, err := ledger.Credit(tx, user.ID, balance, models.EntryMetadata{})
+		return err
+	})
+	if err != nil {
+		t.Fatalf("fund user: %v", err)
+	}
+
+	return user
+}
+
+// SetStatus changes the status of the user.
+func SetStatus(t testing.TB, db *gorm.DB, user *models.User, status string) {
+	t.Helper()
+
+	if err := db.Model(user).Update("status", status).Error; err != nil {
+		t.Fatalf("set user status: %v", err)
+	}
+}
+
+// Account returns the account of the user as it is stored.
+func Account(t testing.TB, db *gorm.DB, userID uint) models.Account {
+	t.Helper()
+
+	var account models.Account
+	if err := db.Where("user_id = ?", userID).First(&account).Error; err != nil {
+		t.Fatalf("load account: %v", err)
+	}
+
+	return account
+}
@@ -0,0 +1,52 @@
+// Package testdb connects tests to a throwaway MySQL database. Tests that
+// need one are skipped unless LEDGER_TEST_DB_URL is set, e.g.
+//
+//	LEDGER_TEST_DB_URL='root:12345@tcp(127.0.0.1:3306)/ledger_test?parseTime=true' go test -race ./...
+//
+// Every call drops and recreates all tables, so never point it at a
+// database whose contents matter.
+package testdb
+
+import (
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"ledger-app/internal/connections/database"
+	"os"
+	"testing"
+)
+
+const EnvURL = "LEDGER_TEST_DB_URL"
+
+// Open returns a connection to an empty, migrated test database and makes
+// it the global database.Db for handler tests.
+func Open(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	url := os.Getenv(EnvURL)
+	if url == "" {
+		t.Skipf("%s is not set", EnvURL)
+	}
+
+	db, err := gorm.Open(mysql.Open(url), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+
+	if err := db.Migrator().DropTable(database.Tables()...); err != nil {
+		t.Fatalf("drop tables: %v", err)
+	}
+
+	if err := database.Migrate(db); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+
+	database.Db = db
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+
+	return db
+}
@@ -0,0 +1,170 @@
+package transfers
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"ledger-app/internal/fees"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/risk"
+	"ledger-app/internal/velocity"
+	"ledger-app/models"
+	"time"
+)
+
+var ErrBatchRejected = errors.New("batch transfer rejected")
+
+// Leg is one payment of a batch. Fee is filled in by Batch and charged to
+// the sender on top of Amount.
+type Leg struct {
+	SenderID   uint
+	ReceiverID uint
+	Amount     int64
+	Fee        int64
+	Meta       models.EntryMetadata
+}
+
+type LegResult struct {
+	Entry    *models.JournalEntry
+	FeeEntry *models.JournalEntry
+	Err      error
+}
+
+// Batch posts every leg or none, each through Execute. Every leg is
+// checked for status, fees and risk, with the sender's other legs shown to
+// the risk rules as pending, and all accounts the batch touches are locked
+// up front in ascending ID order before anything is posted. Senders are
+// then checked against everything they pay across the batch, fees
+// included, ignoring what they receive in it. On ErrBatchRejected the
+// results say which legs failed; tx must then be rolled back.
+func Batch(tx *gorm.DB, legs []Leg, now time.Time) ([]LegResult, error) {
+	results := make([]LegResult, len(legs))
+
+	userIDs := make([]uint, 0, 2*len(legs))
+	for _, leg := range legs {
+		userIDs = append(userIDs, leg.SenderID, leg.ReceiverID)
+	}
+
+	var found []models.User
+	if err := tx.Where("id IN ?", userIDs).Find(&found).Error; err != nil {
+		return nil, err
+	}
+
+	users := make(map[uint]*models.User, len(found))
+	for i := range found {
+		users[found[i].ID] = &found[i]
+	}
+
+	rejected := false
+	withFee := false
+	for i := range legs {
+		leg := &legs[i]
+
+		var err error
+		if leg.SenderID == leg.ReceiverID {
+			err = ErrSelfTransfer
+		}
+		if err == nil {
+			err = ledger.CheckStatus(tx, leg.SenderID, leg.ReceiverID)
+		}
+		if err == nil {
+			leg.Fee, err = fees.Quote(tx, models.FeeOperationTransfer, users[leg.SenderID].Role(), leg.Amount)
+		}
+		if err == nil {
+			// Each leg is screened alongside the sender's other legs, so a
+			// batch cannot split a fan-out into legs that pass one by one.
+			err = screen(tx, &risk.Transfer{
+				SenderID:   leg.SenderID,
+				ReceiverID: leg.ReceiverID,
+				Amount:     leg.Amount,
+				At:         now,
+				Pending:    otherLegs(legs, i, now),
+			})
+		}
+
+		var riskErr *RiskError
+		switch {
+		case err == nil:
+			withFee = withFee || leg.Fee > 0
+		case errors.Is(err, ErrSelfTransfer), errors.Is(err, models.ErrUserInactive), errors.As(err, &riskErr):
+			results[i].Err = err
+			rejected = true
+		default:
+			return nil, err
+		}
+	}
+
+	if rejected {
+		return results, ErrBatchRejected
+	}
+
+	var systemCodes []string
+	if withFee {
+		systemCodes = append(systemCodes, models.SystemAccountFeeRevenue)
+	}
+
+	accounts, err := ledger.LockUserAccounts(tx, userIDs, systemCodes...)
+	if err != nil {
+		return nil, err
+	}
+
+	// Legs are posted one at a time, so money a sender receives in the
+	// batch may only arrive after their own legs. Checking the gross debits
+	// keeps the outcome independent of the order of the legs.
+	debits := make(map[uint]int64)
+	for _, leg := range legs {
+		debits[leg.SenderID] += leg.Amount + leg.Fee
+	}
+
+	for i, leg := range legs {
+		if accounts[leg.SenderID].Headroom() < debits[leg.SenderID] {
+			results[i].Err = fmt.Errorf("%w: user %d cannot cover the batch", ledger.ErrInsufficientBalance, leg.SenderID)
+			rejected = true
+		}
+	}
+
+	if rejected {
+		return results, ErrBatchRejected
+	}
+
+	for i, leg := range legs {
+		result, err := Execute(tx, &Request{
+			SenderID:   leg.SenderID,
+			ReceiverID: leg.ReceiverID,
+			Amount:     leg.Amount,
+			Meta:       leg.Meta,
+			SkipRisk:   true,
+		}, now)
+
+		var limitErr *velocity.LimitError
+		if errors.Is(err, ledger.ErrInsufficientBalance) || errors.As(err, &limitErr) {
+			// The entries posted so far are rolled back with tx.
+			for j := range results[:i] {
+				results[j] = LegResult{}
+			}
+			results[i] = LegResult{Err: err}
+			return results, ErrBatchRejected
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		results[i] = LegResult{Entry: result.Entry, FeeEntry: result.FeeEntry}
+	}
+
+	return results, nil
+}
+
+// otherLegs returns the legs of the batch sent by the sender of leg i,
+// other than leg i itself.
+func otherLegs(legs []Leg, i int, now time.Time) []risk.Transfer {
+	var others []risk.Transfer
+	for j, leg := range legs {
+		if j == i || leg.SenderID != legs[i].SenderID {
+			continue
+		}
+		others = append(others, risk.Transfer{SenderID: leg.SenderID, ReceiverID: leg.ReceiverID, Amount: leg.Amount, At: now})
+	}
+
+	return others
+}
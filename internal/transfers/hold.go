@@ -0,0 +1,38 @@
+package transfers
+
+import (
+	"fmt"
+	"gorm.io/gorm"
+	"ledger-app/internal/ledger"
+	"ledger-app/models"
+	"time"
+)
+
+// CaptureHold settles up to the held amount as a transfer to receiverID and
+// releases whatever was not captured. An amount of zero captures the hold
+// in full. A capture the risk rules want reviewed is refused: the hold
+// cannot be parked half captured.
+func CaptureHold(tx *gorm.DB, holdID, receiverID uint, amount int64, now time.Time) (*models.Hold, *Result, error) {
+	hold, amount, err := ledger.StartCapture(tx, holdID, amount, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	result, err := Execute(tx, &Request{
+		SenderID:   hold.UserID,
+		ReceiverID: receiverID,
+		Amount:     amount,
+		Meta: models.EntryMetadata{
+			Description: fmt.Sprintf("Capture of hold %d", hold.ID),
+			Reference:   fmt.Sprintf("hold:%d", hold.ID),
+		},
+		BeforePost: func(tx *gorm.DB) error {
+			return ledger.SettleCapture(tx, hold)
+		},
+	}, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return hold, result, ledger.FinishCapture(tx, hold, amount, result.Entry)
+}
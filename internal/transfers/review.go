@@ -0,0 +1,38 @@
+package transfers
+
+import (
+	"encoding/json"
+	"gorm.io/gorm"
+	"ledger-app/internal/risk"
+	"ledger-app/models"
+	"time"
+)
+
+// ReleaseReview posts a transfer the risk rules parked and marks its review
+// released. The rules are not run again; status checks, fees and velocity
+// limits are, as of now.
+func ReleaseReview(tx *gorm.DB, id, adminID uint, reason string, now time.Time) (*models.RiskReview, *Result, error) {
+	review, err := risk.LockPending(tx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var meta models.EntryMetadata
+	if err := json.Unmarshal([]byte(review.Metadata), &meta); err != nil {
+		return nil, nil, err
+	}
+
+	result, err := Execute(tx, &Request{
+		SenderID:   review.SenderID,
+		ReceiverID: review.ReceiverID,
+		Amount:     review.Amount,
+		Meta:       meta,
+		SkipRisk:   true,
+	}, now)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	review.EntryID = &result.Entry.ID
+	return review, result, risk.Decide(tx, review, models.RiskReviewReleased, adminID, reason, now)
+}
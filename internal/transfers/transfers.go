@@ -0,0 +1,135 @@
+// Package transfers is the single path every transfer between users takes,
+// whether a user sends it, a schedule runs it, a hold is captured, it is
+// one leg of a batch or an admin releases it from review. Status checks,
+// risk rules, fees and velocity limits are applied here around
+// ledger.Transfer, so no caller can skip one of them.
+package transfers
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"ledger-app/internal/fees"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/risk"
+	"ledger-app/internal/velocity"
+	"ledger-app/models"
+	"time"
+)
+
+var ErrSelfTransfer = errors.New("sender and receiver must be different users")
+
+// RiskError is returned when the risk rules deny a transfer or want it
+// reviewed. Nothing has been posted; the caller decides whether to park
+// the transfer with risk.Park.
+type RiskError struct {
+	Assessment *risk.Assessment
+}
+
+func (e *RiskError) Error() string {
+	if e.Assessment.Decision == risk.Deny {
+		return "transfer denied by risk rules"
+	}
+
+	return "transfer requires risk review"
+}
+
+type Request struct {
+	SenderID   uint
+	ReceiverID uint
+	Amount     int64
+	Meta       models.EntryMetadata
+
+	// SkipRisk is set for transfers the risk rules have already passed,
+	// or that an admin released from review.
+	SkipRisk bool
+
+	// BeforePost runs once the accounts are locked, just before the
+	// transfer is posted.
+	BeforePost func(tx *gorm.DB) error
+}
+
+type Result struct {
+	Entry    *models.JournalEntry
+	FeeEntry *models.JournalEntry
+	Fee      int64
+}
+
+// Execute posts the transfer and its fee in tx. The sender and receiver
+// must be allowed to move money, the risk rules must allow it, and the
+// sender's velocity limits are checked against the posted transfer. All
+// accounts involved are locked together before anything is posted.
+func Execute(tx *gorm.DB, req *Request, now time.Time) (*Result, error) {
+	if req.SenderID == req.ReceiverID {
+		return nil, ErrSelfTransfer
+	}
+
+	if err := ledger.CheckStatus(tx, req.SenderID, req.ReceiverID); err != nil {
+		return nil, err
+	}
+
+	var sender models.User
+	if err := tx.First(&sender, req.SenderID).Error; err != nil {
+		return nil, err
+	}
+
+	if !req.SkipRisk {
+		if err := screen(tx, &risk.Transfer{SenderID: req.SenderID, ReceiverID: req.ReceiverID, Amount: req.Amount, At: now}); err != nil {
+			return nil, err
+		}
+	}
+
+	fee, err := fees.Quote(tx, models.FeeOperationTransfer, sender.Role(), req.Amount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lock(tx, []uint{req.SenderID, req.ReceiverID}, fee > 0); err != nil {
+		return nil, err
+	}
+
+	if req.BeforePost != nil {
+		if err := req.BeforePost(tx); err != nil {
+			return nil, err
+		}
+	}
+
+	entry, err := ledger.Transfer(tx, req.SenderID, req.ReceiverID, req.Amount, req.Meta)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := velocity.Check(tx, req.SenderID, sender.Role(), models.EntryTypeTransfer, []int64{req.Amount}, now); err != nil {
+		return nil, err
+	}
+
+	feeEntry, err := ledger.ChargeFee(tx, req.SenderID, fee, entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Result{Entry: entry, FeeEntry: feeEntry, Fee: fee}, nil
+}
+
+// screen runs the risk rules and turns anything but Allow into a RiskError.
+func screen(tx *gorm.DB, transfer *risk.Transfer) error {
+	assessment, err := risk.Default.Evaluate(tx, transfer)
+	if err != nil {
+		return err
+	}
+
+	if assessment.Decision != risk.Allow {
+		return &RiskError{Assessment: assessment}
+	}
+
+	return nil
+}
+
+func lock(tx *gorm.DB, userIDs []uint, withFee bool) error {
+	var systemCodes []string
+	if withFee {
+		systemCodes = append(systemCodes, models.SystemAccountFeeRevenue)
+	}
+
+	_, err := ledger.LockUserAccounts(tx, userIDs, systemCodes...)
+	return err
+}
@@ -0,0 +1,83 @@
+package transfers
+
+import (
+	"gorm.io/gorm"
+	"ledger-app/internal/ledger"
+	"ledger-app/internal/testdb"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Captures from the holder to the receiver run against transfers going the
+// other way. Both must lock the two accounts in the same order, otherwise
+// MySQL aborts some of them as deadlocked.
+func TestCaptureAndTransferDoNotDeadlock(t *testing.T) {
+	db := testdb.Open(t)
+
+	const (
+		rounds = 50
+		amount = 10
+	)
+
+	// The receiver is created first so it has the lower account ID.
+	receiver := testdb.NewUser(t, db, "bob", rounds*amount)
+	holder := testdb.NewUser(t, db, "carol", rounds*amount)
+
+	holds := make([]uint, 0, rounds)
+	for i := 0; i < rounds; i++ {
+		err := db.Transaction(func(tx *gorm.DB) error {
+			hold, err := ledger.PlaceHold(tx, holder.ID, amount, time.Hour)
+			if err == nil {
+				holds = append(holds, hold.ID)
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatalf("place hold: %v", err)
+		}
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		failures []error
+	)
+	record := func(err error) {
+		if err != nil {
+			mu.Lock()
+			failures = append(failures, err)
+			mu.Unlock()
+		}
+	}
+
+	for _, holdID := range holds {
+		wg.Add(2)
+		go func(holdID uint) {
+			defer wg.Done()
+			record(db.Transaction(func(tx *gorm.DB) error {
+				_, _, err := CaptureHold(tx, holdID, receiver.ID, 0, time.Now().UTC())
+				return err
+			}))
+		}(holdID)
+		go func() {
+			defer wg.Done()
+			record(db.Transaction(func(tx *gorm.DB) error {
+				_, err := Execute(tx, &Request{SenderID: receiver.ID, ReceiverID: holder.ID, Amount: amount}, time.Now().UTC())
+				return err
+			}))
+		}()
+	}
+	wg.Wait()
+
+	for _, err := range failures {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	if got := testdb.Account(t, db, holder.ID); got.Balance != rounds*amount || got.Held != 0 {
+		t.Errorf("holder balance = %d held %d, want %d held 0", got.Balance, got.Held, rounds*amount)
+	}
+	if got := testdb.Account(t, db, receiver.ID); got.Balance != rounds*amount {
+		t.Errorf("receiver balance = %d, want %d", got.Balance, rounds*amount)
+	}
+}
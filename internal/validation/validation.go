@@ -2,18 +2,66 @@ package validation
 
 import (
 	"github.com/go-playground/validator/v10"
+	"regexp"
 	"sync"
+	"unicode"
+	"unicode/utf8"
 )
 
 var (
 	validate *validator.Validate
 	once     sync.Once
+
+	positiveDecimalPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?$`)
+	zeroDecimalPattern     = regexp.MustCompile(`^0+(\.0+)?$`)
+	referencePattern       = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._:/-]*$`)
+	slugPattern            = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
 )
 
 func ValidateStruct() *validator.Validate {
 	once.Do(func() {
 		validate = validator.New()
+		_ = validate.RegisterValidation("positive_decimal", positiveDecimal)
+		_ = validate.RegisterValidation("decimal", nonNegativeDecimal)
+		_ = validate.RegisterValidation("safe_text", safeText)
+		_ = validate.RegisterValidation("reference", matches(referencePattern))
+		_ = validate.RegisterValidation("slug", matches(slugPattern))
 	})
 
 	return validate
 }
+
+// positiveDecimal accepts plain decimal strings greater than zero. Exponents,
+// signs and thousands separators are rejected.
+func positiveDecimal(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	return positiveDecimalPattern.MatchString(value) && !zeroDecimalPattern.MatchString(value)
+}
+
+// nonNegativeDecimal is positiveDecimal that also accepts zero.
+func nonNegativeDecimal(fl validator.FieldLevel) bool {
+	return positiveDecimalPattern.MatchString(fl.Field().String())
+}
+
+// safeText accepts valid UTF-8 without control characters, so free text
+// can be shown in statements and exports as is.
+func safeText(fl validator.FieldLevel) bool {
+	value := fl.Field().String()
+	if !utf8.ValidString(value) {
+		return false
+	}
+
+	for _, r := range value {
+		if unicode.IsControl(r) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func matches(pattern *regexp.Regexp) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return pattern.MatchString(fl.Field().String())
+	}
+}
@@ -0,0 +1,236 @@
+package velocity
+
+import (
+	"errors"
+	"fmt"
+	"gorm.io/gorm"
+	"ledger-app/models"
+	"time"
+)
+
+const (
+	CodeTransactionAmount = "transaction_amount_exceeded"
+	CodeDailyAmount       = "daily_amount_exceeded"
+	CodeDailyCount        = "daily_count_exceeded"
+	CodeMonthlyAmount     = "monthly_amount_exceeded"
+	CodeMonthlyCount      = "monthly_count_exceeded"
+)
+
+var ErrDuplicateWindow = errors.New("each window may only be given once")
+
+// Windows lists the rolling windows in the order they are checked, with
+// how far back each one reaches.
+var Windows = []struct {
+	Name       string
+	Length     time.Duration
+	AmountCode string
+	CountCode  string
+}{
+	{models.VelocityWindowTransaction, 0, CodeTransactionAmount, ""},
+	{models.VelocityWindowDaily, 24 * time.Hour, CodeDailyAmount, CodeDailyCount},
+	{models.VelocityWindowMonthly, 30 * 24 * time.Hour, CodeMonthlyAmount, CodeMonthlyCount},
+}
+
+// Allowance is a user's limit for one window and what is left of it.
+// Remaining values are nil when the window has no cap.
+type Allowance struct {
+	Window          string
+	MaxAmount       int64
+	MaxCount        int
+	UsedAmount      int64
+	UsedCount       int
+	RemainingAmount *int64
+	RemainingCount  *int
+}
+
+// LimitError rejects an operation. Allowance is what remained before it.
+type LimitError struct {
+	Code      string
+	Allowance Allowance
+}
+
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("velocity limit exceeded: %s", e.Code)
+}
+
+// Effective resolves the limit of every window: the user's override
+// first, then the role, then any role. Windows without a limit are left
+// out.
+func Effective(db *gorm.DB, userID uint, role, operation string) (map[string]models.VelocityLimit, error) {
+	var limits []models.VelocityLimit
+	if err := db.Where("operation = ? AND ((user_id = ? AND role = '') OR (user_id = 0 AND role IN ?))",
+		operation, userID, []string{role, models.VelocityRoleAny}).
+		Find(&limits).Error; err != nil {
+		return nil, err
+	}
+
+	rank := func(limit *models.VelocityLimit) int {
+		switch {
+		case limit.UserID != 0:
+			return 3
+		case limit.Role == role:
+			return 2
+		default:
+			return 1
+		}
+	}
+
+	effective := make(map[string]models.VelocityLimit)
+	for i := range limits {
+		current, ok := effective[limits[i].Window]
+		if !ok || rank(&limits[i]) > rank(&current) {
+			effective[limits[i].Window] = limits[i]
+		}
+	}
+
+	return effective, nil
+}
+
+// Allowances reports every limited window of the operation for the user.
+func Allowances(db *gorm.DB, userID uint, role, operation string, now time.Time) ([]Allowance, error) {
+	limits, err := Effective(db, userID, role, operation)
+	if err != nil {
+		return nil, err
+	}
+
+	allowances := []Allowance{}
+	for _, window := range Windows {
+		limit, ok := limits[window.Name]
+		if !ok {
+			continue
+		}
+
+		allowance := Allowance{Window: window.Name, MaxAmount: limit.MaxAmount, MaxCount: limit.MaxCount}
+		if window.Length > 0 {
+			if allowance.UsedAmount, allowance.UsedCount, err = usage(db, userID, operation, now.Add(-window.Length)); err != nil {
+				return nil, err
+			}
+		}
+
+		allowance.remaining()
+		allowances = append(allowances, allowance)
+	}
+
+	return allowances, nil
+}
+
+// Check enforces the limits on amounts the user has just posted in tx.
+// Calling it after posting means the user's account is already locked,
+// so concurrent operations cannot both slip under a limit.
+func Check(tx *gorm.DB, userID uint, role, operation string, amounts []int64, now time.Time) error {
+	limits, err := Effective(tx, userID, role, operation)
+	if err != nil || len(limits) == 0 {
+		return err
+	}
+
+	var total int64
+	for _, amount := range amounts {
+		total += amount
+	}
+
+	for _, window := range Windows {
+		limit, ok := limits[window.Name]
+		if !ok {
+			continue
+		}
+
+		allowance := Allowance{Window: window.Name, MaxAmount: limit.MaxAmount, MaxCount: limit.MaxCount}
+
+		if window.Length == 0 {
+			for _, amount := range amounts {
+				if limit.MaxAmount > 0 && amount > limit.MaxAmount {
+					allowance.remaining()
+					return &LimitError{Code: window.AmountCode, Allowance: allowance}
+				}
+			}
+			continue
+		}
+
+		usedAmount, usedCount, err := usage(tx, userID, operation, now.Add(-window.Length))
+		if err != nil {
+			return err
+		}
+
+		// Report what was left before this operation.
+		allowance.UsedAmount = usedAmount - total
+		allowance.UsedCount = usedCount - len(amounts)
+		allowance.remaining()
+
+		switch {
+		case limit.MaxAmount > 0 && usedAmount > limit.MaxAmount:
+			return &LimitError{Code: window.AmountCode, Allowance: allowance}
+		case limit.MaxCount > 0 && usedCount > limit.MaxCount:
+			return &LimitError{Code: window.CountCode, Allowance: allowance}
+		}
+	}
+
+	return nil
+}
+
+// usage sums the user's outgoing postings of the operation since the
+// given time. Fees are separate entries and do not count.
+func usage(db *gorm.DB, userID uint, operation string, since time.Time) (int64, int, error) {
+	var result struct {
+		Amount int64
+		Count  int
+	}
+
+	err := db.Table("transactions").
+		Select("COALESCE(SUM(-transactions.amount), 0) AS amount, COUNT(DISTINCT transactions.journal_entry_id) AS count").
+		Joins("JOIN journal_entries ON journal_entries.id = transactions.journal_entry_id").
+		Where("transactions.user_id = ? AND transactions.amount < 0", userID).
+		Where("journal_entries.type = ? AND journal_entries.posted_at >= ?", operation, since).
+		Scan(&result).Error
+
+	return result.Amount, result.Count, err
+}
+
+func (a *Allowance) remaining() {
+	if a.MaxAmount > 0 {
+		remaining := a.MaxAmount - a.UsedAmount
+		if a.Window == models.VelocityWindowTransaction {
+			remaining = a.MaxAmount
+		}
+		if remaining < 0 {
+			remaining = 0
+		}
+		a.RemainingAmount = &remaining
+	}
+
+	if a.MaxCount > 0 && a.Window != models.VelocityWindowTransaction {
+		remaining := a.MaxCount - a.UsedCount
+		if remaining < 0 {
+			remaining = 0
+		}
+		a.RemainingCount = &remaining
+	}
+}
+
+// SetLimits replaces the limits of an operation for a role, or for a
+// user when userID is not zero.
+func SetLimits(tx *gorm.DB, operation, role string, userID uint, limits []models.VelocityLimit) error {
+	seen := make(map[string]bool, len(limits))
+	for _, limit := range limits {
+		if seen[limit.Window] {
+			return ErrDuplicateWindow
+		}
+		seen[limit.Window] = true
+	}
+
+	if err := DeleteLimits(tx, operation, role, userID); err != nil {
+		return err
+	}
+
+	for i := range limits {
+		limits[i].Operation = operation
+		limits[i].Role = role
+		limits[i].UserID = userID
+	}
+
+	return tx.Create(&limits).Error
+}
+
+func DeleteLimits(db *gorm.DB, operation, role string, userID uint) error {
+	return db.Where("operation = ? AND role = ? AND user_id = ?", operation, role, userID).
+		Delete(&models.VelocityLimit{}).Error
+}
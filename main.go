@@ -4,6 +4,7 @@ import (
 	"ledger-app/config"
 	"ledger-app/internal/connections/echoserver"
 	"ledger-app/internal/providers"
+	"os"
 )
 
 func main() {
@@ -11,8 +12,20 @@ func main() {
 	cfg := config.LoadEnvironment()
 
 	providers.InitLogger()
+	providers.InitCurrency(cfg)
+	providers.InitChainKey(cfg)
+	providers.InitApprovals(cfg)
+	providers.InitRiskRules(cfg)
 	providers.InitDatabase()
+
+	if len(os.Args) > 1 {
+		providers.RunCommand(os.Args[1:])
+		return
+	}
+
+	providers.InitIPExtractor(e, cfg)
 	providers.RegisterMiddlewares(e)
 	providers.InitDefaultAdmin()
+	providers.StartJobs(cfg)
 	providers.StartServer(e, cfg)
 }
@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+const (
+	AccountTypeUser   = "user"
+	AccountTypeSystem = "system"
+
+	SystemAccountIssuance    = "system:issuance"
+	SystemAccountWithdrawals = "system:withdrawals"
+	SystemAccountMigration   = "system:migration"
+	SystemAccountInterest    = "system:interest"
+	SystemAccountFeeRevenue  = "system:fee-revenue"
+	SystemAccountClosed      = "system:closed-accounts"
+
+	// AccountClassStandard is the class of new accounts. Interest rates
+	// are configured per class.
+	AccountClassStandard = "standard"
+)
+
+type Account struct {
+	ID             uint       `gorm:"primaryKey"`
+	UserID         *uint      `gorm:"uniqueIndex"`
+	Code           string     `gorm:"size:64;uniqueIndex;not null"`
+	Type           string     `gorm:"size:16;not null"`
+	Class          string     `gorm:"size:32;not null;default:standard"`
+	Currency       string     `gorm:"size:3;not null"`
+	Balance        int64      `gorm:"not null;default:0"`
+	Held           int64      `gorm:"not null;default:0"`
+	OverdraftLimit int64      `gorm:"not null;default:0"`
+	Version        uint       `gorm:"not null;default:0"`
+	ChainHash      string     `gorm:"size:64;not null;default:''"`
+	ChainStartID   *uint      `gorm:"default:null"`
+	ClosedAt       *time.Time `gorm:"type:timestamp"`
+	CreatedAt      time.Time  `gorm:"type:timestamp"`
+}
+
+// Available is the part of the balance not reserved by active holds.
+func (a *Account) Available() int64 {
+	return a.Balance - a.Held
+}
+
+// Headroom is how much can still be debited before the overdraft limit
+// is reached.
+func (a *Account) Headroom() int64 {
+	return a.Available() + a.OverdraftLimit
+}
@@ -0,0 +1,43 @@
+package models
+
+import "time"
+
+const (
+	ApprovalOperationCredit     = "credit"
+	ApprovalOperationRoleChange = "role_change"
+	ApprovalOperationImport     = "import"
+	ApprovalOperationImportFile = "import_file"
+	ApprovalOperationJournal    = "journal_import"
+	ApprovalOperationOverdraft  = "overdraft_limit"
+
+	ApprovalStatusPending  = "pending"
+	ApprovalStatusApproved = "approved"
+	ApprovalStatusRejected = "rejected"
+	ApprovalStatusExpired  = "expired"
+)
+
+// ApprovalRequest is an admin operation held back until a second admin
+// approves it. Payload holds the operation's input as JSON: the entry
+// metadata of a credit, the new role of a role change, the row or rows of
+// an import, the file of a journal import or the reason for an overdraft
+// limit. Amount is the credit, the overdraft limit or the volume an
+// import moves. Requests covering many users have no TargetUserID.
+type ApprovalRequest struct {
+	ID             uint   `gorm:"primaryKey"`
+	Operation      string `gorm:"size:32;not null"`
+	TargetUserID   uint   `gorm:"not null;index"`
+	Amount         int64  `gorm:"not null;default:0"`
+	Payload        string `gorm:"type:mediumtext"`
+	Status         string `gorm:"size:16;not null;index:idx_approval_requests_status_expires_at"`
+	RequestedBy    uint   `gorm:"not null"`
+	DecidedBy      *uint
+	DecisionReason string `gorm:"size:255"`
+	ResultEntryID  *uint
+	ExpiresAt      time.Time  `gorm:"type:timestamp;not null;index:idx_approval_requests_status_expires_at"`
+	DecidedAt      *time.Time `gorm:"type:timestamp"`
+	CreatedAt      time.Time  `gorm:"type:timestamp"`
+}
+
+type ApprovalDecisionRequest struct {
+	Reason string `json:"Reason" validate:"max=255,safe_text"`
+}
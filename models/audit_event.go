@@ -0,0 +1,57 @@
+package models
+
+import (
+	"errors"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	AuditActionLogin       = "login"
+	AuditActionLoginFailed = "login_failed"
+)
+
+var ErrAuditAppendOnly = errors.New("audit events are append-only")
+
+// AuditEvent records one admin operation or login. Events are numbered
+// and chained by hash like postings, so editing or removing one from the
+// middle of the log is detectable. Before and After hold JSON. Status 0
+// marks the event written as an admin request starts, before it has an
+// outcome.
+type AuditEvent struct {
+	ID         uint      `gorm:"primaryKey"`
+	Seq        uint64    `gorm:"not null;default:0;index"`
+	ActorID    *uint     `gorm:"index"`
+	Action     string    `gorm:"size:128;not null;index"`
+	TargetType string    `gorm:"size:32;index:idx_audit_target"`
+	TargetID   *uint     `gorm:"index:idx_audit_target"`
+	Before     string    `gorm:"type:text"`
+	After      string    `gorm:"type:text"`
+	Status     int       `gorm:"not null"`
+	RequestID  string    `gorm:"size:64;index"`
+	SourceIP   string    `gorm:"size:45"`
+	PrevHash   string    `gorm:"size:64"`
+	Hash       string    `gorm:"size:64"`
+	CreatedAt  time.Time `gorm:"type:timestamp;index"`
+}
+
+func (e *AuditEvent) BeforeUpdate(*gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+func (e *AuditEvent) BeforeDelete(*gorm.DB) error {
+	return ErrAuditAppendOnly
+}
+
+// AuditHeadID is the ID of the single AuditHead row.
+const AuditHeadID = 1
+
+// AuditHead is the latest position of the audit log: the sequence and
+// hash of the newest event, and the last sequence retention removed. It
+// is what reveals events deleted from either end of the log.
+type AuditHead struct {
+	ID            uint   `gorm:"primaryKey"`
+	Seq           uint64 `gorm:"not null;default:0"`
+	Hash          string `gorm:"size:64;not null;default:''"`
+	PrunedThrough uint64 `gorm:"not null;default:0"`
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// BalanceException marks a posting that was deliberately allowed to take a
+// user account below zero.
+type BalanceException struct {
+	ID             uint      `gorm:"primaryKey"`
+	AccountID      uint      `gorm:"not null;index"`
+	JournalEntryID uint      `gorm:"not null;index"`
+	Balance        int64     `gorm:"not null"`
+	Reason         string    `gorm:"size:255"`
+	CreatedAt      time.Time `gorm:"type:timestamp"`
+}
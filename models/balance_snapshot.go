@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// BalanceSnapshot is the balance of an account made up of every posting
+// before At. Point-in-time queries start from the latest snapshot instead
+// of summing the whole history.
+type BalanceSnapshot struct {
+	ID        uint      `gorm:"primaryKey"`
+	AccountID uint      `gorm:"not null;uniqueIndex:idx_balance_snapshots_account_at"`
+	At        time.Time `gorm:"type:timestamp;not null;uniqueIndex:idx_balance_snapshots_account_at"`
+	Balance   int64     `gorm:"not null"`
+	CreatedAt time.Time `gorm:"type:timestamp"`
+}
+
+// PeriodClose closes the books for everything before ClosedThrough. No
+// posting may be dated inside a closed period.
+type PeriodClose struct {
+	ID            uint      `gorm:"primaryKey"`
+	ClosedThrough time.Time `gorm:"type:timestamp;not null;uniqueIndex"`
+	ClosedBy      uint      `gorm:"not null"`
+	Reason        string    `gorm:"size:255;not null"`
+	CreatedAt     time.Time `gorm:"type:timestamp"`
+}
+
+type PeriodCloseRequest struct {
+	Through time.Time `json:"Through" validate:"required"`
+	Reason  string    `json:"Reason" validate:"required,max=255"`
+}
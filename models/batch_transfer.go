@@ -0,0 +1,11 @@
+package models
+
+type BatchTransferRequest struct {
+	Legs []BatchTransferLeg `json:"Legs" validate:"required,min=1,max=500,dive"`
+}
+
+type BatchTransferLeg struct {
+	SenderID   uint `json:"SenderID" validate:"required"`
+	ReceiverID uint `json:"ReceiverID" validate:"required,nefield=SenderID"`
+	CreditRequest
+}
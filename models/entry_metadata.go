@@ -0,0 +1,16 @@
+package models
+
+// EntryMetadata is the descriptive information a client may attach to a
+// credit, debit or transfer. It is stored on the journal entry.
+type EntryMetadata struct {
+	Description string   `json:"Description" validate:"max=255,safe_text"`
+	Reference   string   `json:"Reference" validate:"omitempty,max=64,reference"`
+	Category    string   `json:"Category" validate:"omitempty,max=32,slug"`
+	Tags        []string `json:"Tags" validate:"max=10,dive,min=1,max=32,slug"`
+}
+
+type EntryTag struct {
+	ID             uint   `gorm:"primaryKey"`
+	JournalEntryID uint   `gorm:"not null;uniqueIndex:idx_entry_tags_entry_tag"`
+	Tag            string `gorm:"size:32;not null;uniqueIndex:idx_entry_tags_entry_tag;index"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"ledger-app/internal/money"
+	"time"
+)
+
+const (
+	FeeOperationTransfer   = "transfer"
+	FeeOperationWithdrawal = "withdrawal"
+
+	// FeeRoleAny is the schedule used for roles without their own.
+	FeeRoleAny = "any"
+)
+
+// FeeSchedule is the fee charged for one operation to users of one role.
+// The tier with the highest AmountFrom not above the amount applies, and
+// the result is kept between Min and Max; a Max of zero means no cap.
+type FeeSchedule struct {
+	ID        uint      `gorm:"primaryKey"`
+	Operation string    `gorm:"size:16;not null;uniqueIndex:idx_fee_schedules_operation_role"`
+	Role      string    `gorm:"size:16;not null;uniqueIndex:idx_fee_schedules_operation_role"`
+	Min       int64     `gorm:"not null;default:0"`
+	Max       int64     `gorm:"not null;default:0"`
+	Tiers     []FeeTier `gorm:"foreignKey:ScheduleID;constraint:OnDelete:CASCADE"`
+	UpdatedBy uint      `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"type:timestamp"`
+}
+
+// FeeTier charges Flat plus RatePPM parts per million of the amount.
+type FeeTier struct {
+	ID         uint  `gorm:"primaryKey"`
+	ScheduleID uint  `gorm:"not null;index"`
+	AmountFrom int64 `gorm:"not null"`
+	Flat       int64 `gorm:"not null;default:0"`
+	RatePPM    int64 `gorm:"not null;default:0"`
+}
+
+type FeeScheduleRequest struct {
+	Min   money.Decimal    `json:"Min" validate:"omitempty,decimal"`
+	Max   money.Decimal    `json:"Max" validate:"omitempty,decimal"`
+	Tiers []FeeTierRequest `json:"Tiers" validate:"required,min=1,max=20,dive"`
+}
+
+type FeeTierRequest struct {
+	From money.Decimal `json:"From" validate:"omitempty,decimal"`
+	Flat money.Decimal `json:"Flat" validate:"omitempty,decimal"`
+	Rate money.Decimal `json:"Rate" validate:"omitempty,decimal"`
+}
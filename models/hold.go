@@ -0,0 +1,31 @@
+package models
+
+import (
+	"ledger-app/internal/money"
+	"time"
+)
+
+const (
+	HoldStatusActive   = "active"
+	HoldStatusCaptured = "captured"
+	HoldStatusReleased = "released"
+	HoldStatusExpired  = "expired"
+)
+
+type Hold struct {
+	ID             uint      `gorm:"primaryKey"`
+	UserID         uint      `gorm:"not null;index"`
+	AccountID      uint      `gorm:"not null;index"`
+	Amount         int64     `gorm:"not null"`
+	CapturedAmount int64     `gorm:"not null;default:0"`
+	Status         string    `gorm:"size:16;not null;index:idx_holds_status_expires_at"`
+	ExpiresAt      time.Time `gorm:"type:timestamp;not null;index:idx_holds_status_expires_at"`
+	CaptureEntryID *uint
+	CreatedAt      time.Time `gorm:"type:timestamp"`
+	UpdatedAt      time.Time `gorm:"type:timestamp"`
+}
+
+type CaptureRequest struct {
+	ReceiverID uint          `json:"ReceiverID" validate:"required"`
+	Amount     money.Decimal `json:"Amount" validate:"omitempty,positive_decimal"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+type IdempotencyKey struct {
+	ID           uint      `gorm:"primaryKey"`
+	Key          string    `gorm:"size:255;not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_idempotency_keys_user_key"`
+	Method       string    `gorm:"size:10;not null"`
+	Path         string    `gorm:"size:255;not null"`
+	Fingerprint  string    `gorm:"size:64;not null"`
+	Completed    bool      `gorm:"not null;default:false"`
+	StatusCode   int       `gorm:"not null;default:0"`
+	ResponseBody string    `gorm:"type:mediumtext"`
+	CreatedAt    time.Time `gorm:"type:timestamp"`
+	ExpiresAt    time.Time `gorm:"type:timestamp;not null;index"`
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"ledger-app/internal/money"
+	"time"
+)
+
+const (
+	InterestSimple   = "simple"
+	InterestCompound = "compound"
+
+	DayCountActual365 = "act/365"
+	DayCountActual360 = "act/360"
+	DayCount30360     = "30/360"
+)
+
+// InterestRate is the annual rate paid on accounts of one class. RatePPM
+// is the rate in parts per million, so 4.25% is 42500.
+type InterestRate struct {
+	ID        uint      `gorm:"primaryKey"`
+	Class     string    `gorm:"size:32;not null;uniqueIndex"`
+	RatePPM   int64     `gorm:"not null"`
+	Method    string    `gorm:"size:16;not null"`
+	DayCount  string    `gorm:"size:16;not null"`
+	UpdatedBy uint      `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"type:timestamp"`
+}
+
+// InterestAccrual is the interest an account has earned but not yet been
+// paid. Accrued is kept in millionths of a minor unit so that small daily
+// amounts are not lost to rounding; days before AccruedThrough are done.
+type InterestAccrual struct {
+	ID             uint      `gorm:"primaryKey"`
+	AccountID      uint      `gorm:"not null;uniqueIndex"`
+	AccruedThrough time.Time `gorm:"type:timestamp;not null"`
+	Accrued        int64     `gorm:"not null;default:0"`
+	UpdatedAt      time.Time `gorm:"type:timestamp"`
+}
+
+type InterestRateRequest struct {
+	Rate     money.Decimal `json:"Rate" validate:"required,decimal"`
+	Method   string        `json:"Method" validate:"required,oneof=simple compound"`
+	DayCount string        `json:"DayCount" validate:"required,oneof=act/365 act/360 30/360"`
+}
+
+type AccountClassRequest struct {
+	Class string `json:"Class" validate:"required,max=32,slug"`
+}
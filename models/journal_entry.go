@@ -0,0 +1,80 @@
+package models
+
+import (
+	"ledger-app/internal/money"
+	"time"
+)
+
+const (
+	EntryTypeCredit     = "credit"
+	EntryTypeTransfer   = "transfer"
+	EntryTypeWithdrawal = "withdrawal"
+	EntryTypeReversal   = "reversal"
+	EntryTypeImport     = "import"
+	EntryTypeInterest   = "interest"
+	EntryTypeFee        = "fee"
+	EntryTypeClosure    = "closure"
+)
+
+// EntryTypes lists every type an entry can have.
+var EntryTypes = []string{
+	EntryTypeCredit,
+	EntryTypeTransfer,
+	EntryTypeWithdrawal,
+	EntryTypeReversal,
+	EntryTypeImport,
+	EntryTypeInterest,
+	EntryTypeFee,
+	EntryTypeClosure,
+}
+
+type JournalEntry struct {
+	ID             uint          `gorm:"primaryKey"`
+	Type           string        `gorm:"size:32;not null;index"`
+	PostedAt       time.Time     `gorm:"type:timestamp;not null;index"`
+	ReversalOfID   *uint         `gorm:"index"`
+	FeeForID       *uint         `gorm:"index"`
+	ReversedAmount int64         `gorm:"not null;default:0"`
+	Description    string        `gorm:"size:255"`
+	Reference      string        `gorm:"size:64;index"`
+	Category       string        `gorm:"size:32;index"`
+	ImportKey      *string       `gorm:"size:64;uniqueIndex"`
+	Tags           []EntryTag    `gorm:"foreignKey:JournalEntryID"`
+	Postings       []Transaction `gorm:"foreignKey:JournalEntryID"`
+}
+
+// SetMetadata copies client supplied metadata onto the entry. Duplicate
+// tags are stored once.
+func (e *JournalEntry) SetMetadata(meta EntryMetadata) {
+	e.Description = meta.Description
+	e.Reference = meta.Reference
+	e.Category = meta.Category
+
+	seen := make(map[string]bool, len(meta.Tags))
+	for _, tag := range meta.Tags {
+		if seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		e.Tags = append(e.Tags, EntryTag{Tag: tag})
+	}
+}
+
+// Amount is the gross value moved by the entry, i.e. the sum of its
+// positive postings.
+func (e *JournalEntry) Amount() int64 {
+	var amount int64
+	for _, posting := range e.Postings {
+		if posting.Amount > 0 {
+			amount += posting.Amount
+		}
+	}
+
+	return amount
+}
+
+type ReversalRequest struct {
+	Amount        money.Decimal `json:"Amount" validate:"omitempty,positive_decimal"`
+	Reason        string        `json:"Reason" validate:"required,max=255"`
+	AllowNegative bool          `json:"AllowNegative"`
+}
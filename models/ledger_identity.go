@@ -0,0 +1,12 @@
+package models
+
+// LedgerIdentityID is the ID of the single LedgerIdentity row.
+const LedgerIdentityID = 1
+
+// LedgerIdentity names this ledger in the journals it exports, so an
+// import can tell its own entries from those of another ledger that
+// happen to carry the same IDs.
+type LedgerIdentity struct {
+	ID  uint   `gorm:"primaryKey"`
+	Key string `gorm:"size:32;not null"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"ledger-app/internal/money"
+	"time"
+)
+
+// OverdraftLimitChange is the audit trail of every overdraft limit an
+// admin has set.
+type OverdraftLimitChange struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	AccountID uint      `gorm:"not null"`
+	OldLimit  int64     `gorm:"not null"`
+	NewLimit  int64     `gorm:"not null"`
+	ChangedBy uint      `gorm:"not null"`
+	Reason    string    `gorm:"size:255;not null"`
+	CreatedAt time.Time `gorm:"type:timestamp"`
+}
+
+type OverdraftLimitRequest struct {
+	Limit  money.Decimal `json:"Limit" validate:"required,decimal"`
+	Reason string        `json:"Reason" validate:"required,max=255"`
+}
@@ -0,0 +1,13 @@
+package models
+
+import "time"
+
+// ReconciliationRun stores the outcome of one invariant check of the
+// ledger. Report holds the full result as JSON.
+type ReconciliationRun struct {
+	ID            uint      `gorm:"primaryKey"`
+	Trigger       string    `gorm:"size:16;not null"`
+	Discrepancies int       `gorm:"not null"`
+	Report        string    `gorm:"type:mediumtext"`
+	CreatedAt     time.Time `gorm:"type:timestamp;index"`
+}
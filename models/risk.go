@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+const (
+	RiskReviewPending  = "pending"
+	RiskReviewReleased = "released"
+	RiskReviewRejected = "rejected"
+)
+
+// RiskReview is a transfer the risk rules parked for an admin to release
+// or reject. Metadata and Findings hold JSON.
+type RiskReview struct {
+	ID             uint   `gorm:"primaryKey"`
+	SenderID       uint   `gorm:"not null;index"`
+	ReceiverID     uint   `gorm:"not null"`
+	Amount         int64  `gorm:"not null"`
+	Metadata       string `gorm:"type:text"`
+	Findings       string `gorm:"type:text"`
+	Status         string `gorm:"size:16;not null;index"`
+	DecidedBy      *uint
+	DecisionReason string `gorm:"size:255"`
+	EntryID        *uint
+	DecidedAt      *time.Time `gorm:"type:timestamp"`
+	CreatedAt      time.Time  `gorm:"type:timestamp"`
+}
+
+// FailedAttempt is a transfer or withdrawal a user tried that was
+// refused. The risk rules count them.
+type FailedAttempt struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index:idx_failed_attempts_user_created"`
+	Operation string    `gorm:"size:16;not null"`
+	Reason    string    `gorm:"size:64;not null"`
+	CreatedAt time.Time `gorm:"type:timestamp;index:idx_failed_attempts_user_created"`
+}
+
+type RiskDecisionRequest struct {
+	Reason string `json:"Reason" validate:"max=255,safe_text"`
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"ledger-app/internal/money"
+	"time"
+)
+
+const (
+	ScheduleFrequencyOnce    = "once"
+	ScheduleFrequencyDaily   = "daily"
+	ScheduleFrequencyWeekly  = "weekly"
+	ScheduleFrequencyMonthly = "monthly"
+
+	ScheduleStatusActive    = "active"
+	ScheduleStatusPaused    = "paused"
+	ScheduleStatusCancelled = "cancelled"
+	ScheduleStatusCompleted = "completed"
+
+	ScheduleRunSucceeded = "succeeded"
+	ScheduleRunFailed    = "failed"
+)
+
+type ScheduledTransfer struct {
+	ID         uint       `gorm:"primaryKey"`
+	SenderID   uint       `gorm:"not null;index"`
+	ReceiverID uint       `gorm:"not null"`
+	Amount     int64      `gorm:"not null"`
+	Frequency  string     `gorm:"size:16;not null"`
+	StartAt    time.Time  `gorm:"type:timestamp;not null"`
+	NextRunAt  time.Time  `gorm:"type:timestamp;not null;index:idx_scheduled_transfers_due"`
+	EndAt      *time.Time `gorm:"type:timestamp NULL"`
+	MaxRuns    *uint
+	RunCount   uint      `gorm:"not null;default:0"`
+	Status     string    `gorm:"size:16;not null;index:idx_scheduled_transfers_due"`
+	CreatedAt  time.Time `gorm:"type:timestamp"`
+	UpdatedAt  time.Time `gorm:"type:timestamp"`
+}
+
+// ScheduleRun records one attempt to execute a scheduled transfer.
+type ScheduleRun struct {
+	ID                  uint      `gorm:"primaryKey"`
+	ScheduledTransferID uint      `gorm:"not null;index"`
+	RunAt               time.Time `gorm:"type:timestamp;not null"`
+	Status              string    `gorm:"size:16;not null"`
+	FailureReason       string    `gorm:"size:255"`
+	JournalEntryID      *uint
+}
+
+type ScheduleRequest struct {
+	ReceiverID uint          `json:"ReceiverID" validate:"required"`
+	Amount     money.Decimal `json:"Amount" validate:"required,positive_decimal"`
+	Currency   string        `json:"Currency" validate:"omitempty,iso4217"`
+	Frequency  string        `json:"Frequency" validate:"required,oneof=once daily weekly monthly"`
+	StartAt    time.Time     `json:"StartAt" validate:"required"`
+	EndAt      *time.Time    `json:"EndAt" validate:"omitempty,gtfield=StartAt"`
+	MaxRuns    *uint         `json:"MaxRuns" validate:"omitempty,gt=0"`
+}
+
+// MinorUnits applies the same currency rules as CreditRequest.
+func (r *ScheduleRequest) MinorUnits() (int64, error) {
+	creditReq := CreditRequest{Amount: r.Amount, Currency: r.Currency}
+	return creditReq.MinorUnits()
+}
+
+// RunAt returns the time of the n-th occurrence, counting from zero.
+// Monthly schedules keep the day of StartAt and fall back to the last day
+// of shorter months.
+func (s *ScheduledTransfer) RunAt(n uint) time.Time {
+	switch s.Frequency {
+	case ScheduleFrequencyDaily:
+		return s.StartAt.AddDate(0, 0, int(n))
+	case ScheduleFrequencyWeekly:
+		return s.StartAt.AddDate(0, 0, 7*int(n))
+	case ScheduleFrequencyMonthly:
+		year, month, day := s.StartAt.Date()
+		hour, minute, second := s.StartAt.Clock()
+		month += time.Month(n)
+
+		lastDay := time.Date(year, month+1, 0, 0, 0, 0, 0, s.StartAt.Location()).Day()
+		if day > lastDay {
+			day = lastDay
+		}
+		return time.Date(year, month, day, hour, minute, second, s.StartAt.Nanosecond(), s.StartAt.Location())
+	}
+
+	return s.StartAt
+}
@@ -1,23 +1,51 @@
 package models
 
 import (
+	"fmt"
+	"ledger-app/internal/money"
 	"ledger-app/internal/validation"
 	"time"
 )
 
+// Transaction is one posting of a journal entry. Hash covers the posting
+// and PrevHash, the hash of the account's previous posting, so the
+// postings of every account form a tamper-evident chain.
 type Transaction struct {
 	ID              uint      `gorm:"primaryKey"`
-	UserID          uint      `gorm:"not null"`
-	Amount          float64   `gorm:"not null"`
-	TransactionTime time.Time `gorm:"type:timestamp;not null"`
+	JournalEntryID  uint      `gorm:"not null;index"`
+	AccountID       uint      `gorm:"not null;index"`
+	UserID          *uint     `gorm:"index"`
+	Amount          int64     `gorm:"not null"`
+	TransactionTime time.Time `gorm:"type:timestamp;not null;index"`
 	SenderID        *uint     `gorm:"index"`
 	ReceiverID      *uint     `gorm:"index"`
+	PrevHash        string    `gorm:"size:64;not null;default:''"`
+	Hash            string    `gorm:"size:64;not null;default:''"`
 }
 
 type CreditRequest struct {
-	Amount float64 `json:"Amount" validate:"required,gt=0"`
+	Amount   money.Decimal `json:"Amount" validate:"required,positive_decimal"`
+	Currency string        `json:"Currency" validate:"omitempty,iso4217"`
+	EntryMetadata
 }
 
 func (t *Transaction) Validator() error {
 	return validation.ValidateStruct().Struct(t)
 }
+
+// MinorUnits returns the requested amount in minor units of the ledger
+// currency, rejecting other currencies and excess decimal places.
+func (r *CreditRequest) MinorUnits() (int64, error) {
+	if r.Currency != "" {
+		currency, err := money.Lookup(r.Currency)
+		if err != nil {
+			return 0, err
+		}
+
+		if currency.Code != money.Default.Code {
+			return 0, fmt.Errorf("%w: ledger is kept in %s", money.ErrUnsupportedCurrency, money.Default.Code)
+		}
+	}
+
+	return r.Amount.MinorUnits(money.Default)
+}
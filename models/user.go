@@ -1,15 +1,68 @@
 package models
 
 import (
+	"errors"
 	"ledger-app/internal/validation"
+	"time"
 )
 
+// A frozen user can sign in and read but not move money out; incoming
+// money is still accepted. A suspended user cannot sign in and nothing
+// moves in or out. A closed user has had their balance swept away and
+// can only read their history; closing is final.
+const (
+	UserStatusActive    = "active"
+	UserStatusFrozen    = "frozen"
+	UserStatusSuspended = "suspended"
+	UserStatusClosed    = "closed"
+)
+
+var ErrUserInactive = errors.New("user status does not allow this operation")
+
 type User struct {
-	ID           uint          `gorm:"primaryKey"`
-	Name         string        `gorm:"not null" validate:"required,min=1,max=10"`
-	PasswordHash string        `gorm:"not null"`
-	IsAdmin      bool          `gorm:"default:false"`
-	Credits      []Transaction `gorm:"foreignKey:UserID"`
+	ID           uint   `gorm:"primaryKey"`
+	Name         string `gorm:"not null" validate:"required,min=1,max=10"`
+	PasswordHash string `gorm:"not null"`
+	IsAdmin      bool   `gorm:"default:false"`
+	Status       string `gorm:"size:16;not null;default:active"`
+}
+
+// Role is the role tokens are issued for and fee schedules, velocity
+// limits and risk reviews are looked up by.
+func (u *User) Role() string {
+	if u.IsAdmin {
+		return "admin"
+	}
+
+	return "user"
+}
+
+// CanSend reports whether money may leave the user's account.
+func (u *User) CanSend() bool {
+	return u.Status == "" || u.Status == UserStatusActive
+}
+
+// CanReceive reports whether money may be paid into the user's account.
+func (u *User) CanReceive() bool {
+	return u.CanSend() || u.Status == UserStatusFrozen
+}
+
+// UserStatusChange is the audit trail of every status an admin has set.
+type UserStatusChange struct {
+	ID           uint   `gorm:"primaryKey"`
+	UserID       uint   `gorm:"not null;index"`
+	OldStatus    string `gorm:"size:16;not null"`
+	NewStatus    string `gorm:"size:16;not null"`
+	Reason       string `gorm:"size:255;not null"`
+	ChangedBy    uint   `gorm:"not null"`
+	SweepEntryID *uint
+	CreatedAt    time.Time `gorm:"type:timestamp"`
+}
+
+type UserStatusRequest struct {
+	Status        string `json:"Status" validate:"required,oneof=active frozen suspended closed"`
+	Reason        string `json:"Reason" validate:"required,max=255,safe_text"`
+	SweepToUserID uint   `json:"SweepToUserID"`
 }
 
 func (u *User) Validate() error {
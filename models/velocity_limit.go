@@ -0,0 +1,41 @@
+package models
+
+import (
+	"ledger-app/internal/money"
+	"time"
+)
+
+const (
+	VelocityWindowTransaction = "transaction"
+	VelocityWindowDaily       = "daily"
+	VelocityWindowMonthly     = "monthly"
+
+	// VelocityRoleAny holds the limits for roles without their own.
+	VelocityRoleAny = "any"
+)
+
+// VelocityLimit caps the amount and number of transfers or withdrawals a
+// user makes in one window. A row with a UserID overrides the limits of
+// the user's role for that window; role rows have a UserID of zero and
+// override rows an empty Role. Zero means no cap.
+type VelocityLimit struct {
+	ID        uint      `gorm:"primaryKey"`
+	Operation string    `gorm:"size:16;not null;uniqueIndex:idx_velocity_limits_scope"`
+	Role      string    `gorm:"size:16;not null;uniqueIndex:idx_velocity_limits_scope"`
+	UserID    uint      `gorm:"not null;default:0;uniqueIndex:idx_velocity_limits_scope"`
+	Window    string    `gorm:"column:time_window;size:16;not null;uniqueIndex:idx_velocity_limits_scope"`
+	MaxAmount int64     `gorm:"not null;default:0"`
+	MaxCount  int       `gorm:"not null;default:0"`
+	UpdatedBy uint      `gorm:"not null"`
+	UpdatedAt time.Time `gorm:"type:timestamp"`
+}
+
+type VelocityLimitRequest struct {
+	Windows []VelocityWindowRequest `json:"Windows" validate:"required,min=1,max=3,dive"`
+}
+
+type VelocityWindowRequest struct {
+	Window    string        `json:"Window" validate:"required,oneof=transaction daily monthly"`
+	MaxAmount money.Decimal `json:"MaxAmount" validate:"omitempty,decimal"`
+	MaxCount  int           `json:"MaxCount" validate:"min=0"`
+}
@@ -2,23 +2,75 @@ package routes
 
 import (
 	"github.com/labstack/echo/v4"
+	"ledger-app/config"
 	"ledger-app/handlers"
 	"ledger-app/internal/middleware"
 )
 
 func RegisterUsersRoutes(e *echo.Echo) {
+	cfg := config.LoadEnvironment()
+	idempotency := middleware.Idempotency(cfg.IdempotencyKeyTTL)
+
 	e.POST("/register", handlers.RegisterUser)
 	e.POST("/login", handlers.LoginUser)
 
-	adminGroup := e.Group("/admin", middleware.JWTMiddleware, middleware.AdminMiddleware)
+	adminGroup := e.Group("/admin", middleware.JWTMiddleware, middleware.AdminMiddleware, middleware.Audit)
 	adminGroup.GET("/users", handlers.GetAllUser)
 	adminGroup.GET("/balances", handlers.GetAllUsersTotalBalance)
-	adminGroup.POST("/users/:id/credit", handlers.AddCreditToUser)
+	adminGroup.POST("/users/:id/credit", handlers.AddCreditToUser, idempotency)
 	adminGroup.PUT("/users/:userID/role", handlers.UpdateUserRole)
+	adminGroup.POST("/transactions/:id/reverse", handlers.ReverseTransaction, idempotency)
+	adminGroup.PUT("/users/:id/overdraft", handlers.SetOverdraftLimit)
+	adminGroup.GET("/users/:id/overdraft/history", handlers.GetOverdraftLimitHistory)
+	adminGroup.PUT("/users/:id/status", handlers.SetUserStatus)
+	adminGroup.GET("/users/:id/status/history", handlers.GetUserStatusHistory)
+	adminGroup.POST("/import", handlers.ImportTransactions)
+	adminGroup.GET("/journal", handlers.ExportJournal)
+	adminGroup.POST("/journal", handlers.ImportJournal)
+	adminGroup.GET("/periods", handlers.GetClosedPeriods)
+	adminGroup.POST("/periods/close", handlers.ClosePeriod)
+	adminGroup.GET("/interest-rates", handlers.GetInterestRates)
+	adminGroup.PUT("/interest-rates/:class", handlers.SetInterestRate)
+	adminGroup.PUT("/users/:id/class", handlers.SetAccountClass)
+	adminGroup.GET("/fees", handlers.GetFeeSchedules)
+	adminGroup.PUT("/fees/:operation/:role", handlers.SetFeeSchedule)
+	adminGroup.DELETE("/fees/:operation/:role", handlers.DeleteFeeSchedule)
+	adminGroup.GET("/transactions/verify", handlers.VerifyTransactionChain)
+	adminGroup.POST("/reconcile", handlers.RunReconciliation)
+	adminGroup.GET("/reconcile/runs", handlers.GetReconciliationRuns)
+	adminGroup.GET("/reconcile/runs/:id", handlers.GetReconciliationRun)
+	adminGroup.GET("/audit", handlers.GetAuditEvents)
+	adminGroup.GET("/audit/verify", handlers.VerifyAuditLog)
+	adminGroup.GET("/approvals", handlers.GetApprovalRequests)
+	adminGroup.POST("/approvals/:id/approve", handlers.ApproveRequest, idempotency)
+	adminGroup.POST("/approvals/:id/reject", handlers.RejectRequest)
+	adminGroup.GET("/velocity-limits", handlers.GetVelocityLimits)
+	adminGroup.PUT("/velocity-limits/:operation/:role", handlers.SetVelocityLimits)
+	adminGroup.DELETE("/velocity-limits/:operation/:role", handlers.DeleteVelocityLimits)
+	adminGroup.PUT("/users/:id/velocity-limits/:operation", handlers.SetUserVelocityLimits)
+	adminGroup.DELETE("/users/:id/velocity-limits/:operation", handlers.DeleteUserVelocityLimits)
+	adminGroup.GET("/risk/reviews", handlers.GetRiskReviews)
+	adminGroup.POST("/risk/reviews/:id/release", handlers.ReleaseRiskReview, idempotency)
+	adminGroup.POST("/risk/reviews/:id/reject", handlers.RejectRiskReview)
 	
 	userGroup := e.Group("/users", middleware.JWTMiddleware)
 	userGroup.GET("/:id/balance", handlers.GetUserBalance)
 	userGroup.GET("/:id/time/balance", handlers.GetUserBalanceAtTime)
-	userGroup.POST("/:sender_id/transfer/:receiver_id", handlers.TransferCredit)
-	userGroup.POST("/:id/debit", handlers.UserWithdrawsCredit)
+	userGroup.GET("/:id/transactions", handlers.GetUserTransactions)
+	userGroup.GET("/:id/statement", handlers.GetUserStatement)
+	userGroup.GET("/:id/interest", handlers.GetUserInterest)
+	userGroup.GET("/:id/velocity-limits", handlers.GetUserVelocityLimits)
+	userGroup.POST("/:sender_id/transfer/:receiver_id", handlers.TransferCredit, idempotency)
+	userGroup.POST("/transfers/batch", handlers.BatchTransfer, idempotency)
+	userGroup.POST("/:id/debit", handlers.UserWithdrawsCredit, idempotency)
+	userGroup.POST("/:id/holds", handlers.PlaceHold(cfg.HoldTTL), idempotency)
+	userGroup.GET("/:id/holds", handlers.GetUserHolds)
+	userGroup.POST("/:id/holds/:hold_id/capture", handlers.CaptureHold, idempotency)
+	userGroup.POST("/:id/holds/:hold_id/release", handlers.ReleaseHold)
+	userGroup.POST("/:id/schedules", handlers.CreateSchedule, idempotency)
+	userGroup.GET("/:id/schedules", handlers.GetUserSchedules)
+	userGroup.GET("/:id/schedules/:schedule_id/runs", handlers.GetScheduleRuns)
+	userGroup.POST("/:id/schedules/:schedule_id/cancel", handlers.CancelSchedule)
+	userGroup.POST("/:id/schedules/:schedule_id/pause", handlers.PauseSchedule)
+	userGroup.POST("/:id/schedules/:schedule_id/resume", handlers.ResumeSchedule)
 }